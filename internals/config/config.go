@@ -5,6 +5,7 @@ import (
 
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -13,12 +14,128 @@ type HTTPServer struct {
 	Addr string `yaml:"address" env:"HTTP_SERVER_ADDRESS" env-default:"localhost:5000"`
 }
 
+type QuizGeneration struct {
+	// QuizGenerator selects the primary backend: "python", "gemini[:model]"
+	// or "fake"
+	QuizGenerator  string `yaml:"generator" env:"QUIZ_GENERATOR" env-default:"python"`
+	GeminiModel    string `yaml:"gemini_model" env:"GEMINI_MODEL" env-default:"gemini-2.5-flash"`
+	PythonPath     string `yaml:"python_path" env:"QUIZ_PYTHON_PATH"`
+	QuizScriptPath string `yaml:"script_path" env:"QUIZ_SCRIPT_PATH"`
+	// MaxRounds bounds how often the model is asked again for questions
+	// that were dropped by validation
+	MaxRounds int `yaml:"max_rounds" env:"QUIZ_MAX_ROUNDS" env-default:"3"`
+	// Python worker pool, used when QuizGenerator is "python"
+	PythonWorkers int           `yaml:"python_workers" env:"QUIZ_PYTHON_WORKERS" env-default:"2"`
+	WorkerTimeout time.Duration `yaml:"worker_timeout" env:"QUIZ_WORKER_TIMEOUT" env-default:"90s"`
+	QueueTimeout  time.Duration `yaml:"queue_timeout" env:"QUIZ_QUEUE_TIMEOUT" env-default:"30s"`
+	// BankRatio is the default share of each quiz drawn from the shared
+	// question bank; requests may override it with bank_ratio
+	BankRatio float64 `yaml:"bank_ratio" env:"QUIZ_BANK_RATIO" env-default:"0.5"`
+	// Past-question exclusion: how many of the user's recent questions are
+	// checked for near-duplicates, how many of the most relevant ones are
+	// listed in the prompt, and the MinHash similarity (0-1) that counts as
+	// a repeat
+	HistoryLimit        int     `yaml:"history_limit" env:"QUIZ_HISTORY_LIMIT" env-default:"500"`
+	PromptPastQuestions int     `yaml:"prompt_past_questions" env:"QUIZ_PROMPT_PAST_QUESTIONS" env-default:"30"`
+	SimilarityThreshold float64 `yaml:"similarity_threshold" env:"QUIZ_SIMILARITY_THRESHOLD" env-default:"0.5"`
+	// MaterialPromptChars bounds how much of an uploaded document goes into
+	// the prompt for quizzes generated from it
+	MaterialPromptChars int `yaml:"material_prompt_chars" env:"QUIZ_MATERIAL_PROMPT_CHARS" env-default:"12000"`
+	// GeminiEndpoint overrides the Gemini API base URL, e.g. to point at a
+	// local stub server; the Python workers use it too
+	GeminiEndpoint string `yaml:"gemini_endpoint" env:"GEMINI_ENDPOINT"`
+	// QuizFallbacks are tried in order when QuizGenerator fails, e.g.
+	// "gemini:gemini-2.0-flash-lite,bank"; "bank" serves stored questions
+	QuizFallbacks []string `yaml:"fallbacks" env:"QUIZ_FALLBACKS" env-separator:","`
+	// Per-provider call timeout, retries of transient failures with jittered
+	// exponential backoff, and the circuit breaker that skips a provider
+	// after BreakerThreshold failures in a row for BreakerCooldown
+	ProviderTimeout  time.Duration `yaml:"provider_timeout" env:"QUIZ_PROVIDER_TIMEOUT" env-default:"120s"`
+	ProviderRetries  int           `yaml:"provider_retries" env:"QUIZ_PROVIDER_RETRIES" env-default:"2"`
+	RetryBackoff     time.Duration `yaml:"retry_backoff" env:"QUIZ_RETRY_BACKOFF" env-default:"1s"`
+	RetryMaxBackoff  time.Duration `yaml:"retry_max_backoff" env:"QUIZ_RETRY_MAX_BACKOFF" env-default:"10s"`
+	BreakerThreshold int           `yaml:"breaker_threshold" env:"QUIZ_BREAKER_THRESHOLD" env-default:"5"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown" env:"QUIZ_BREAKER_COOLDOWN" env-default:"30s"`
+
+	// RecordMode "record" saves every Gemini API exchange to FixturesDir and
+	// "replay" answers from those files without network access or an API key.
+	// The Python workers go through a local proxy that does the same
+	RecordMode  string `yaml:"record_mode" env:"QUIZ_RECORD_MODE"`
+	FixturesDir string `yaml:"fixtures_dir" env:"QUIZ_FIXTURES_DIR" env-default:"testdata/llm"`
+}
+
+type Jobs struct {
+	JobWorkers      int           `yaml:"workers" env:"JOB_WORKERS" env-default:"2"`
+	JobPollInterval time.Duration `yaml:"poll_interval" env:"JOB_POLL_INTERVAL" env-default:"5s"`
+	// JobShutdownTimeout is how long shutdown waits for in-flight jobs before
+	// putting them back in the queue.
+	JobShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"JOB_SHUTDOWN_TIMEOUT" env-default:"20s"`
+}
+
+// Quotas limit each user's quiz generations per UTC day and calendar month,
+// by count and by model tokens; 0 means unlimited
+type Quotas struct {
+	DailyGenerations   int `yaml:"daily_generations" env:"QUOTA_DAILY_GENERATIONS" env-default:"0"`
+	MonthlyGenerations int `yaml:"monthly_generations" env:"QUOTA_MONTHLY_GENERATIONS" env-default:"0"`
+	DailyTokens        int `yaml:"daily_tokens" env:"QUOTA_DAILY_TOKENS" env-default:"0"`
+	MonthlyTokens      int `yaml:"monthly_tokens" env:"QUOTA_MONTHLY_TOKENS" env-default:"0"`
+}
+
+// Moderation screens quiz topics before generation. Blocklist terms come
+// from MODERATION_BLOCKLIST and from the files in MODERATION_BLOCKLIST_FILES,
+// one term per line
+type Moderation struct {
+	MaxTopicLength int      `yaml:"max_topic_length" env:"MODERATION_MAX_TOPIC_LENGTH" env-default:"100"`
+	MaxTopicWords  int      `yaml:"max_topic_words" env:"MODERATION_MAX_TOPIC_WORDS" env-default:"15"`
+	Blocklist      []string `yaml:"blocklist" env:"MODERATION_BLOCKLIST" env-separator:","`
+	BlocklistFiles []string `yaml:"blocklist_files" env:"MODERATION_BLOCKLIST_FILES" env-separator:","`
+}
+
+// Topics resolves quiz topics to canonical ones. A topic that matches no
+// name or alias exactly is matched fuzzily if every word is at least
+// TopicMatchThreshold similar to a word of the name; the taxonomy is
+// reloaded every TopicCacheTTL so admin edits on other instances show up
+type Topics struct {
+	TopicMatchThreshold float64       `yaml:"match_threshold" env:"TOPIC_MATCH_THRESHOLD" env-default:"0.8"`
+	TopicCacheTTL       time.Duration `yaml:"cache_ttl" env:"TOPIC_CACHE_TTL" env-default:"5m"`
+}
+
+// Attempts governs quiz taking. Answers to a timed attempt are accepted up
+// to SubmitGrace past its deadline, to allow for network delay. Later ones
+// are "reject"ed, or with LateSubmission "grade_saved" the attempt is graded
+// with the answers saved before the deadline as if they had been submitted.
+// Untimed attempts without activity for AbandonAfter are deleted, 0 keeps
+// them open indefinitely; timed ones past their deadline and SubmitGrace are
+// graded with their saved answers. Both are checked every
+// AbandonCheckInterval
+type Attempts struct {
+	SubmitGrace          time.Duration `yaml:"submit_grace" env:"ATTEMPT_SUBMIT_GRACE" env-default:"10s"`
+	LateSubmission       string        `yaml:"late_submission" env:"ATTEMPT_LATE_SUBMISSION" env-default:"reject"`
+	AbandonAfter         time.Duration `yaml:"abandon_after" env:"ATTEMPT_ABANDON_AFTER" env-default:"24h"`
+	AbandonCheckInterval time.Duration `yaml:"abandon_check_interval" env:"ATTEMPT_ABANDON_CHECK_INTERVAL" env-default:"10m"`
+}
+
+// Stats caches each user's learning stats for StatsCacheTTL, or until they
+// save or take a quiz on this instance. Invalidation is per instance: behind
+// a load balancer, stats can lag a quiz taken on another one by up to
+// StatsCacheTTL
+type Stats struct {
+	StatsCacheTTL time.Duration `yaml:"cache_ttl" env:"STATS_CACHE_TTL" env-default:"5m"`
+}
+
 type Config struct {
-	Env        string `yaml:"env" env:"ENV" env-default:"dev"`
-	PsqlInfo   string `yaml:"postgresqlInfo" env:"PSQL_INFO"`
-	APIKey     string `env:"API_KEY"`
-	JWTSecret  string `env:"JWT_SECRET_KEY"`
-	HTTPServer `yaml:"http_server"`
+	Env            string `yaml:"env" env:"ENV" env-default:"dev"`
+	PsqlInfo       string `yaml:"postgresqlInfo" env:"PSQL_INFO"`
+	APIKey         string `env:"API_KEY"`
+	JWTSecret      string `env:"JWT_SECRET_KEY"`
+	HTTPServer     `yaml:"http_server"`
+	QuizGeneration `yaml:"quiz_generation"`
+	Jobs           `yaml:"jobs"`
+	Quotas         `yaml:"quotas"`
+	Moderation     `yaml:"moderation"`
+	Topics         `yaml:"topics"`
+	Attempts       `yaml:"attempts"`
+	Stats          `yaml:"stats"`
 }
 
 // Load configuration from environment variables or a YAML file
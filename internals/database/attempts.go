@@ -0,0 +1,553 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreateQuizAttemptsTables creates the tables that keep every attempt at a
+// quiz and its answers, apart from the quiz's questions. A quiz has at most
+// one open attempt. Quizzes submitted before attempts existed get one
+// finished attempt holding the answers saved on their questions, once, when
+// the tables are created.
+func CreateQuizAttemptsTables(db *sql.DB) error {
+	query := fmt.Sprintf(`
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'quiz_attempts') THEN
+			CREATE TABLE quiz_attempts (
+				id SERIAL PRIMARY KEY,
+				quiz_id INT NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+				user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				finished_at TIMESTAMPTZ,
+				score INT,
+				total_questions INT NOT NULL DEFAULT 0
+			);
+
+			-- version is the version of the question that was answered, so that a
+			-- regenerated question doesn't rewrite the attempts made before.
+			CREATE TABLE attempt_answers (
+				attempt_id INT NOT NULL REFERENCES quiz_attempts(id) ON DELETE CASCADE,
+				question_id INT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+				version INT NOT NULL DEFAULT 1,
+				user_answer TEXT NOT NULL DEFAULT '',
+				user_answers JSONB,
+				is_correct BOOLEAN,
+				answered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				PRIMARY KEY (attempt_id, question_id)
+			);
+
+			INSERT INTO quiz_attempts (quiz_id, user_id, started_at, finished_at, score, total_questions)
+			SELECT z.id, z.user_id, COALESCE(z.created_at, z.submitted_at), z.submitted_at, z.score, z.totalQuestions
+			FROM quizzes z
+			WHERE z.submitted_at IS NOT NULL;
+
+			INSERT INTO attempt_answers (attempt_id, question_id, version, user_answer, user_answers, is_correct, answered_at)
+			SELECT a.id, q.id, %s, COALESCE(q.user_answer, ''), q.user_answers, q.is_correct, a.finished_at
+			FROM quiz_attempts a
+			JOIN questions q ON q.quiz_id = a.quiz_id;
+		END IF;
+	END $$;
+	CREATE INDEX IF NOT EXISTS quiz_attempts_quiz_idx ON quiz_attempts (quiz_id, started_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS quiz_attempts_open_idx ON quiz_attempts (quiz_id) WHERE finished_at IS NULL;
+	`, fmt.Sprintf(questionVersionSQL, "q.id"))
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create quiz attempts tables: %w", err)
+	}
+	return nil
+}
+
+// attemptLimitSQL is the time limit in seconds of an attempt at the quiz
+// with ID %[1]s: the quiz's own, or the sum of its questions' if they all
+// have one, whichever is less; NULL if untimed.
+const attemptLimitSQL = `(
+	SELECT LEAST(z.time_limit_seconds,
+		CASE WHEN COUNT(q.id) > 0 AND COUNT(q.id) = COUNT(q.time_limit_seconds) THEN SUM(q.time_limit_seconds) END)
+	FROM quizzes z
+	LEFT JOIN questions q ON q.quiz_id = z.id
+	WHERE z.id = %[1]s
+	GROUP BY z.id
+)`
+
+// AddTimeLimitColumns adds the optional time limits of quizzes and
+// questions, and the deadline each timed attempt gets when it starts.
+func AddTimeLimitColumns(db *sql.DB) error {
+	query := `
+	ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS time_limit_seconds INT CHECK (time_limit_seconds > 0);
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS time_limit_seconds INT CHECK (time_limit_seconds > 0);
+	ALTER TABLE quiz_attempts ADD COLUMN IF NOT EXISTS deadline TIMESTAMPTZ;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add time limit columns: %w", err)
+	}
+	return nil
+}
+
+// CreateShownQuestionsTable creates the table that records when an attempt
+// first moved to each question. Questions with their own time limit are
+// timed from then.
+func CreateShownQuestionsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS attempt_shown_questions (
+		attempt_id INT NOT NULL REFERENCES quiz_attempts(id) ON DELETE CASCADE,
+		question_id INT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+		shown_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (attempt_id, question_id)
+	);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create shown questions table: %w", err)
+	}
+	return nil
+}
+
+// showQuestion records that the attempt moved to the question, starting its
+// clock if it has a time limit. Moving to it again doesn't restart it.
+func showQuestion(tx *sql.Tx, attemptID int64, questionID int) error {
+	_, err := tx.Exec(`
+		INSERT INTO attempt_shown_questions (attempt_id, question_id) VALUES ($1, $2)
+		ON CONFLICT (attempt_id, question_id) DO NOTHING
+	`, attemptID, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to record shown question: %w", err)
+	}
+	return nil
+}
+
+// closedQuestions returns the serial numbers of the quiz's timed questions
+// that the attempt may no longer answer, mapped to whether they were shown:
+// those it never moved to, and those shown more than their limit plus grace
+// ago.
+func closedQuestions(tx *sql.Tx, quizID int, attemptID int64, grace time.Duration) (map[int]bool, error) {
+	rows, err := tx.Query(`
+		SELECT q.serial_number, s.shown_at IS NOT NULL
+		FROM questions q
+		LEFT JOIN attempt_shown_questions s ON s.question_id = q.id AND s.attempt_id = $2
+		WHERE q.quiz_id = $1 AND q.time_limit_seconds IS NOT NULL AND (
+			s.shown_at IS NULL OR
+			NOW() > s.shown_at + make_interval(secs => q.time_limit_seconds) + make_interval(secs => $3)
+		)
+	`, quizID, attemptID, grace.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error fetching question time limits: %w", err)
+	}
+	defer rows.Close()
+
+	closed := make(map[int]bool)
+	for rows.Next() {
+		var serial int
+		var shown bool
+		if err := rows.Scan(&serial, &shown); err != nil {
+			return nil, err
+		}
+		closed[serial] = shown
+	}
+	return closed, rows.Err()
+}
+
+// AddAttemptProgressColumns adds the progress of an open attempt: the
+// question the user is at and when they last saved, from which abandoned
+// attempts are told apart.
+func AddAttemptProgressColumns(db *sql.DB) error {
+	query := `
+	ALTER TABLE quiz_attempts ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+	ALTER TABLE quiz_attempts ADD COLUMN IF NOT EXISTS last_active_at TIMESTAMPTZ;
+	UPDATE quiz_attempts SET last_active_at = COALESCE(finished_at, started_at) WHERE last_active_at IS NULL;
+	ALTER TABLE quiz_attempts ALTER COLUMN last_active_at SET DEFAULT NOW();
+	ALTER TABLE quiz_attempts ALTER COLUMN last_active_at SET NOT NULL;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add attempt progress columns: %w", err)
+	}
+	return nil
+}
+
+// AddRetakeColumn marks the attempts finished after a quiz's first. Their
+// answer keys had already been shown, so they are kept out of ratings and
+// stats. Existing attempts are marked once, when the column is added.
+func AddRetakeColumn(db *sql.DB) error {
+	query := `
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns WHERE table_name = 'quiz_attempts' AND column_name = 'is_retake'
+		) THEN
+			ALTER TABLE quiz_attempts ADD COLUMN is_retake BOOLEAN NOT NULL DEFAULT FALSE;
+			UPDATE quiz_attempts a SET is_retake = TRUE
+			WHERE a.finished_at IS NOT NULL AND EXISTS (
+				SELECT 1 FROM quiz_attempts b
+				WHERE b.quiz_id = a.quiz_id AND b.finished_at IS NOT NULL AND (b.finished_at, b.id) < (a.finished_at, a.id)
+			);
+		END IF;
+	END $$;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add retake column: %w", err)
+	}
+	return nil
+}
+
+// StartAttempt opens a new attempt at the user's quiz, or returns the one
+// already open; created tells which. The deadline of a timed attempt is
+// fixed here, so later changes to the limits don't move it.
+func StartAttempt(db *sql.DB, quizID, userID int) (attempt *types.Attempt, created bool, err error) {
+	attempt = &types.Attempt{}
+	err = scanAttempt(db.QueryRow(fmt.Sprintf(`
+		INSERT INTO quiz_attempts (quiz_id, user_id, total_questions, deadline)
+		VALUES ($1, $2, (SELECT COUNT(*) FROM questions WHERE quiz_id = $1), NOW() + make_interval(secs => %s))
+		ON CONFLICT (quiz_id) WHERE finished_at IS NULL DO NOTHING
+		RETURNING `+attemptColumns, fmt.Sprintf(attemptLimitSQL, "$1")), quizID, userID), attempt)
+	if err == nil {
+		return attempt, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to start attempt: %w", err)
+	}
+
+	attempt, err = FetchOpenAttempt(db, quizID)
+	if err != nil {
+		return nil, false, err
+	}
+	return attempt, false, nil
+}
+
+// attemptColumns computes the remaining time with the database's clock, the
+// same one that recorded the start.
+const attemptColumns = `id, quiz_id, started_at, finished_at, score, total_questions, is_retake, position, last_active_at, deadline,
+	CASE WHEN finished_at IS NULL AND deadline IS NOT NULL THEN GREATEST(CEIL(EXTRACT(EPOCH FROM deadline - NOW())), 0)::INT END`
+
+func scanAttempt(row interface{ Scan(...any) error }, attempt *types.Attempt, extra ...any) error {
+	return row.Scan(append([]any{
+		&attempt.ID, &attempt.QuizID, &attempt.StartedAt, &attempt.FinishedAt, &attempt.Score, &attempt.TotalQuestions, &attempt.Retake,
+		&attempt.Position, &attempt.LastActiveAt, &attempt.Deadline, &attempt.RemainingSeconds,
+	}, extra...)...)
+}
+
+// FetchOpenAttempt returns the quiz's open attempt, or ErrNotFound.
+func FetchOpenAttempt(db *sql.DB, quizID int) (*types.Attempt, error) {
+	var attempt types.Attempt
+	err := scanAttempt(db.QueryRow(`SELECT `+attemptColumns+` FROM quiz_attempts WHERE quiz_id = $1 AND finished_at IS NULL`, quizID), &attempt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open attempt: %w", err)
+	}
+	return &attempt, nil
+}
+
+// FetchAttempt returns one attempt at the quiz, or ErrNotFound.
+func FetchAttempt(db *sql.DB, quizID int, attemptID int64) (*types.Attempt, error) {
+	var attempt types.Attempt
+	err := scanAttempt(db.QueryRow(`SELECT `+attemptColumns+` FROM quiz_attempts WHERE id = $1 AND quiz_id = $2`, attemptID, quizID), &attempt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attempt: %w", err)
+	}
+	return &attempt, nil
+}
+
+// FetchAttempts returns every attempt at the quiz, latest first.
+func FetchAttempts(db *sql.DB, quizID int) ([]types.Attempt, error) {
+	rows, err := db.Query(`SELECT `+attemptColumns+` FROM quiz_attempts WHERE quiz_id = $1 ORDER BY started_at DESC, id DESC`, quizID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []types.Attempt{}
+	for rows.Next() {
+		var attempt types.Attempt
+		if err := scanAttempt(rows, &attempt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// versionedQuestionColumns read question q as it read at revision r, joined
+// as question_revisions r ON r.question_id = q.id AND r.revision = <version>;
+// as it reads now if r is not found. Read them with scanVersionedQuestion.
+const versionedQuestionColumns = `q.serial_number,
+	COALESCE(r.type, q.type), COALESCE(r.question, q.question), COALESCE(r.options, q.options),
+	COALESCE(r.correct_answer, q.correct_answer), CASE WHEN r.id IS NULL THEN q.correct_answers ELSE r.correct_answers END,
+	COALESCE(r.case_sensitive, q.case_sensitive), COALESCE(r.ignore_spaces, q.ignore_spaces),
+	CASE WHEN r.id IS NULL THEN COALESCE(q.description, '') ELSE COALESCE(r.description, '') END`
+
+func scanVersionedQuestion(row interface{ Scan(...any) error }, q *types.Question, extra ...any) error {
+	var options, correctAnswers []byte
+	err := row.Scan(append([]any{
+		&q.SerialNumber, &q.Type, &q.Question, &options, &q.CorrectAnswer, &correctAnswers,
+		&q.CaseSensitive, &q.IgnoreSpaces, &q.Description,
+	}, extra...)...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(options, &q.Options); err != nil {
+		return fmt.Errorf("error unmarshalling options: %v", err)
+	}
+	if err := scanStrings(correctAnswers, &q.CorrectAnswers); err != nil {
+		return fmt.Errorf("error unmarshalling correct answers: %v", err)
+	}
+	return nil
+}
+
+// FetchAttemptAnswers returns the graded answers of a finished attempt in
+// serial order, each with the question as it read when it was answered.
+// Answers kept from before grading was stored are graded with isCorrect.
+func FetchAttemptAnswers(db *sql.DB, attemptID int64, isCorrect func(types.Question) bool) ([]types.QuestionView, error) {
+	rows, err := db.Query(`
+		SELECT `+versionedQuestionColumns+`, a.user_answer, a.user_answers, a.is_correct
+		FROM attempt_answers a
+		JOIN questions q ON q.id = a.question_id
+		LEFT JOIN question_revisions r ON r.question_id = a.question_id AND r.revision = a.version
+		WHERE a.attempt_id = $1
+		ORDER BY q.serial_number
+	`, attemptID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attempt answers: %w", err)
+	}
+	defer rows.Close()
+
+	answers := []types.QuestionView{}
+	for rows.Next() {
+		var q types.Question
+		var userAnswers []byte
+		var correct *bool
+		if err := scanVersionedQuestion(rows, &q, &q.UserAnswer, &userAnswers, &correct); err != nil {
+			return nil, err
+		}
+		if err := scanStrings(userAnswers, &q.UserAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling user answers: %v", err)
+		}
+		if correct == nil {
+			graded := isCorrect(q)
+			correct = &graded
+		}
+		answers = append(answers, q.View(true, correct))
+	}
+	return answers, rows.Err()
+}
+
+// FetchAttemptPrompts returns the quiz's questions in serial order with the
+// answers saved to an open attempt, as prompts: their solutions are not even
+// read. Timed questions the attempt hasn't moved to are locked.
+func FetchAttemptPrompts(db *sql.DB, quizID int, attemptID int64) ([]types.QuestionView, error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT q.id, q.serial_number, q.type, q.question, q.options, q.case_sensitive, q.ignore_spaces, q.time_limit_seconds,
+			COALESCE(a.user_answer, ''), a.user_answers, s.shown_at IS NOT NULL
+		FROM questions q
+		LEFT JOIN attempt_answers a ON a.question_id = q.id AND a.attempt_id = $2 AND a.version = %s
+		LEFT JOIN attempt_shown_questions s ON s.question_id = q.id AND s.attempt_id = $2
+		WHERE q.quiz_id = $1
+		ORDER BY q.serial_number
+	`, fmt.Sprintf(questionVersionSQL, "q.id")), quizID, attemptID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attempt questions: %w", err)
+	}
+	defer rows.Close()
+
+	prompts := []types.QuestionView{}
+	for rows.Next() {
+		var q types.Question
+		var options, userAnswers []byte
+		var shown bool
+		if err := rows.Scan(
+			&q.ID, &q.SerialNumber, &q.Type, &q.Question, &options, &q.CaseSensitive, &q.IgnoreSpaces, &q.TimeLimitSeconds,
+			&q.UserAnswer, &userAnswers, &shown,
+		); err != nil {
+			return nil, err
+		}
+		if q.TimeLimitSeconds != nil && !shown {
+			prompts = append(prompts, q.LockedView())
+			continue
+		}
+		if err := json.Unmarshal(options, &q.Options); err != nil {
+			return nil, fmt.Errorf("error unmarshalling options: %v", err)
+		}
+		if err := scanStrings(userAnswers, &q.UserAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling user answers: %v", err)
+		}
+		prompts = append(prompts, q.View(false, nil))
+	}
+	return prompts, rows.Err()
+}
+
+// SaveAttemptAnswers saves answers to the open attempt at the user's quiz
+// without grading them, replacing earlier ones to the same questions, and
+// moves the attempt to position if it is given, which starts the clock of
+// a question with its own time limit. It returns the attempt. Returns
+// ErrNotFound if the quiz isn't the user's, ErrInvalidAnswer for questions
+// the quiz doesn't have and timed ones not moved to yet, ErrNoOpenAttempt if
+// no attempt is open, ErrDeadlinePassed if it is more than grace past its
+// deadline and ErrQuestionTimeUp for answers more than grace past their
+// question's limit.
+func SaveAttemptAnswers(db *sql.DB, quizID, userID int, answers []types.Answer, position *int, grace time.Duration) (*types.Attempt, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT id FROM quizzes WHERE id = $1 AND user_id = $2 FOR UPDATE`, quizID, userID).Scan(&quizID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching quiz: %w", err)
+	}
+	attempt, expired, err := lockOpenAttempt(tx, quizID, grace)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		return nil, ErrNoOpenAttempt
+	}
+	if expired {
+		return nil, ErrDeadlinePassed
+	}
+
+	rows, err := tx.Query(`SELECT id, serial_number, type FROM questions WHERE quiz_id = $1`, quizID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching questions: %w", err)
+	}
+	bySerial := make(map[int]types.Question)
+	for rows.Next() {
+		var q types.Question
+		if err := rows.Scan(&q.ID, &q.SerialNumber, &q.Type); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		bySerial[q.SerialNumber] = q
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if position != nil {
+		q, ok := bySerial[*position]
+		if !ok {
+			return nil, fmt.Errorf("%w: the quiz has no question %d", ErrInvalidAnswer, *position)
+		}
+		if err := showQuestion(tx, attempt.ID, q.ID); err != nil {
+			return nil, err
+		}
+	}
+	closed, err := closedQuestions(tx, quizID, attempt.ID, grace)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO attempt_answers (attempt_id, question_id, version, user_answer, user_answers)
+		VALUES ($1, $2, %s, $3, $4)
+		ON CONFLICT (attempt_id, question_id) DO UPDATE SET
+			version = EXCLUDED.version, user_answer = EXCLUDED.user_answer, user_answers = EXCLUDED.user_answers,
+			is_correct = NULL, answered_at = NOW()
+	`, fmt.Sprintf(questionVersionSQL, "$2")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	answered := make(map[int]bool, len(answers))
+	for _, answer := range answers {
+		q, ok := bySerial[answer.SerialNumber]
+		if !ok {
+			return nil, fmt.Errorf("%w: the quiz has no question %d", ErrInvalidAnswer, answer.SerialNumber)
+		}
+		if answered[answer.SerialNumber] {
+			return nil, fmt.Errorf("%w: question %d is answered twice", ErrInvalidAnswer, answer.SerialNumber)
+		}
+		answered[answer.SerialNumber] = true
+		if shown, ok := closed[answer.SerialNumber]; ok {
+			if !shown {
+				return nil, fmt.Errorf("%w: question %d is timed and hasn't been moved to", ErrInvalidAnswer, answer.SerialNumber)
+			}
+			return nil, fmt.Errorf("%w: question %d", ErrQuestionTimeUp, answer.SerialNumber)
+		}
+
+		setAnswer(&q, answer)
+		userAnswersJSON, err := stringsJSON(q.UserAnswers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal user answers to JSON: %v", err)
+		}
+		if _, err := stmt.Exec(attempt.ID, q.ID, q.UserAnswer, userAnswersJSON); err != nil {
+			return nil, fmt.Errorf("failed to save answer: %w", err)
+		}
+	}
+
+	err = scanAttempt(tx.QueryRow(`
+		UPDATE quiz_attempts SET position = COALESCE($2, position), last_active_at = NOW()
+		WHERE id = $1
+		RETURNING `+attemptColumns, attempt.ID, position), attempt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save attempt progress: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return attempt, nil
+}
+
+// ExpireAbandonedAttempts deletes the open attempts of untimed quizzes that
+// have seen no activity for idle, with their saved answers, and returns how
+// many. Timed attempts end at their deadline instead; see
+// FetchExpiredAttemptQuizzes.
+func ExpireAbandonedAttempts(db *sql.DB, idle time.Duration) (int64, error) {
+	result, err := db.Exec(`
+		DELETE FROM quiz_attempts
+		WHERE finished_at IS NULL AND deadline IS NULL AND last_active_at < NOW() - make_interval(secs => $1)
+	`, idle.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire abandoned attempts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// FetchExpiredAttemptQuizzes returns the quizzes whose open attempt is more
+// than grace past its deadline, for CloseExpiredAttempt to finish.
+func FetchExpiredAttemptQuizzes(db *sql.DB, grace time.Duration) ([]*types.Quiz, error) {
+	rows, err := db.Query(`
+		SELECT quiz_id FROM quiz_attempts
+		WHERE finished_at IS NULL AND deadline IS NOT NULL AND NOW() > deadline + make_interval(secs => $1)
+	`, grace.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error fetching expired attempts: %w", err)
+	}
+	var quizIDs []int
+	for rows.Next() {
+		var quizID int
+		if err := rows.Scan(&quizID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		quizIDs = append(quizIDs, quizID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	quizzes := make([]*types.Quiz, 0, len(quizIDs))
+	for _, quizID := range quizIDs {
+		quiz, err := fetchQuiz(db, `z.id = $1`, quizID)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted since
+		}
+		if err != nil {
+			return nil, err
+		}
+		quizzes = append(quizzes, quiz)
+	}
+	return quizzes, nil
+}
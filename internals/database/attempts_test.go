@@ -0,0 +1,178 @@
+package database_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database/dbtest"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/scoring"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// newQuiz adds a quiz of the user's with one MCQ per given question time
+// limit, answered "a"; 0 means no limit of its own.
+func newQuiz(t *testing.T, db *sql.DB, userID int64, limits ...int) int {
+	t.Helper()
+	var quizID int
+	err := db.QueryRow(`INSERT INTO quizzes (user_id, quiz_name, level, score, totalQuestions) VALUES ($1, 'timed', 'easy', 0, $2) RETURNING id`,
+		userID, len(limits)).Scan(&quizID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, limit := range limits {
+		_, err := db.Exec(`
+			INSERT INTO questions (quiz_id, serial_number, question, options, correct_answer, time_limit_seconds)
+			VALUES ($1, $2, $3, '["a", "b"]', 'a', NULLIF($4, 0))
+		`, quizID, i+1, fmt.Sprintf("Question %d?", i+1), limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return quizID
+}
+
+func TestQuestionTimeLimits(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	quizID := newQuiz(t, db, userID, 1, 0)
+	late := database.LateSubmission{}
+
+	if _, _, err := database.StartAttempt(db, quizID, int(userID)); err != nil {
+		t.Fatal(err)
+	}
+	first := []types.Answer{{SerialNumber: 1, Answer: "a"}}
+	if _, err := database.SaveAttemptAnswers(db, quizID, int(userID), first, nil, late.Grace); !errors.Is(err, database.ErrInvalidAnswer) {
+		t.Fatalf("answering a timed question not moved to: err = %v, want ErrInvalidAnswer", err)
+	}
+	position := 1
+	if _, err := database.SaveAttemptAnswers(db, quizID, int(userID), first, &position, late.Grace); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	position = 2
+	if _, err := database.SaveAttemptAnswers(db, quizID, int(userID), nil, &position, late.Grace); err != nil {
+		t.Fatal(err)
+	}
+	// Moving back doesn't restart the first question's clock.
+	changed := []types.Answer{{SerialNumber: 1, Answer: "b"}}
+	back := 1
+	if _, err := database.SaveAttemptAnswers(db, quizID, int(userID), changed, &back, late.Grace); !errors.Is(err, database.ErrQuestionTimeUp) {
+		t.Fatalf("answering after the question's limit: err = %v, want ErrQuestionTimeUp", err)
+	}
+
+	// The untimed question may still be answered; the late change is voided
+	// and the saved answer graded.
+	submitted := append(changed, types.Answer{SerialNumber: 2, Answer: "a"})
+	graded, err := database.SubmitQuiz(db, quizID, int(userID), submitted, scoring.IsCorrect, late)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(graded.Voided) != "[1]" || graded.Score != 2 {
+		t.Errorf("voided %v and scored %d, want [1] and 2", graded.Voided, graded.Score)
+	}
+}
+
+func TestSubmitRequiresAttemptForTimedQuestions(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	quizID := newQuiz(t, db, userID, 0, 30)
+
+	_, err := database.SubmitQuiz(db, quizID, int(userID), []types.Answer{{SerialNumber: 1, Answer: "a"}}, scoring.IsCorrect, database.LateSubmission{})
+	if !errors.Is(err, database.ErrNoOpenAttempt) {
+		t.Errorf("err = %v, want ErrNoOpenAttempt", err)
+	}
+}
+
+func TestFetchExpiredAttemptQuizzes(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	quizID := newQuiz(t, db, userID, 30, 30)
+
+	attempt, _, err := database.StartAttempt(db, quizID, int(userID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := func() bool {
+		t.Helper()
+		quizzes, err := database.FetchExpiredAttemptQuizzes(db, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, quiz := range quizzes {
+			if quiz.ID == quizID {
+				return true
+			}
+		}
+		return false
+	}
+	if expired() {
+		t.Fatal("a running attempt is expired")
+	}
+
+	// Past the deadline but within the grace it isn't either.
+	if _, err := db.Exec(`UPDATE quiz_attempts SET deadline = NOW() - INTERVAL '30 seconds' WHERE id = $1`, attempt.ID); err != nil {
+		t.Fatal(err)
+	}
+	if expired() {
+		t.Fatal("an attempt within the grace is expired")
+	}
+
+	if _, err := db.Exec(`UPDATE quiz_attempts SET deadline = NOW() - INTERVAL '2 minutes' WHERE id = $1`, attempt.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !expired() {
+		t.Fatal("an attempt past the grace isn't expired")
+	}
+	graded, err := database.CloseExpiredAttempt(db, quizID, time.Minute, scoring.IsCorrect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graded == nil || !graded.Late {
+		t.Fatalf("CloseExpiredAttempt = %+v, want a late grading", graded)
+	}
+	if expired() {
+		t.Error("a closed attempt is still expired")
+	}
+}
+
+func TestAttemptPromptsWithholdUnshownTimedQuestions(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	quizID := newQuiz(t, db, userID, 0, 30)
+
+	attempt, _, err := database.StartAttempt(db, quizID, int(userID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts := func() []types.QuestionView {
+		t.Helper()
+		views, err := database.FetchAttemptPrompts(db, quizID, attempt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(views) != 2 {
+			t.Fatalf("got %d prompts, want 2", len(views))
+		}
+		return views
+	}
+	views := prompts()
+	if views[0].Locked || views[0].Prompt.Question == "" {
+		t.Errorf("the untimed question is withheld: %+v", views[0])
+	}
+	if !views[1].Locked || views[1].Prompt.Question != "" || len(views[1].Prompt.Options) != 0 {
+		t.Errorf("the timed question is shown before its clock starts: %+v", views[1])
+	}
+
+	position := 2
+	if _, err := database.SaveAttemptAnswers(db, quizID, int(userID), nil, &position, database.LateSubmission{}.Grace); err != nil {
+		t.Fatal(err)
+	}
+	if views := prompts(); views[1].Locked || views[1].Prompt.Question != "Question 2?" {
+		t.Errorf("the timed question is still withheld once moved to: %+v", views[1])
+	}
+}
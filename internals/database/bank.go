@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+	"github.com/lib/pq"
+)
+
+// normalizedQuestionSQL is how question text is normalized for deduplication:
+// trimmed, whitespace collapsed and lower-cased. It is used both for the
+// generated bank column and for linking saved questions back to the bank.
+const normalizedQuestionSQL = `lower(regexp_replace(btrim(%s), '\s+', ' ', 'g'))`
+
+// CreateQuestionBankTable creates the shared bank of generated questions and
+// links the per-quiz questions table to it.
+func CreateQuestionBankTable(db *sql.DB) error {
+	query := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS bank_questions (
+		id SERIAL PRIMARY KEY,
+		topic TEXT NOT NULL,
+		difficulty TEXT NOT NULL,
+		question TEXT NOT NULL,
+		normalized_text TEXT GENERATED ALWAYS AS (%s) STORED,
+		options JSONB NOT NULL,
+		correct_answer TEXT NOT NULL,
+		description TEXT,
+		created_at TIMESTAMP DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS bank_questions_normalized_text_key ON bank_questions (normalized_text);
+	CREATE INDEX IF NOT EXISTS bank_questions_topic_idx ON bank_questions (topic, difficulty);
+	ALTER TABLE bank_questions ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'mcq';
+	ALTER TABLE bank_questions ADD COLUMN IF NOT EXISTS correct_answers JSONB;
+	ALTER TABLE bank_questions ADD COLUMN IF NOT EXISTS case_sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE bank_questions ADD COLUMN IF NOT EXISTS ignore_spaces BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS bank_question_id INT REFERENCES bank_questions(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS questions_bank_question_id_idx ON questions (bank_question_id);
+	`, fmt.Sprintf(normalizedQuestionSQL, "question"))
+
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create bank_questions table: %w", err)
+	}
+	return nil
+}
+
+// InsertBankQuestions adds questions in the given language to the bank,
+// skipping any whose normalized text is already there.
+func InsertBankQuestions(ctx context.Context, db *sql.DB, topic, difficulty, language string, questions []types.Question) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO bank_questions (topic, difficulty, question, options, correct_answer, description,
+			type, correct_answers, case_sensitive, ignore_spaces, language)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE(NULLIF($7, ''), 'mcq'), $8, $9, $10, COALESCE(NULLIF($11, ''), 'en'))
+		ON CONFLICT (normalized_text) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, question := range questions {
+		if question.Options == nil {
+			question.Options = []string{}
+		}
+		optionsJSON, err := json.Marshal(question.Options)
+		if err != nil {
+			return fmt.Errorf("failed to marshal options to JSON: %v", err)
+		}
+		correctAnswersJSON, err := stringsJSON(question.CorrectAnswers)
+		if err != nil {
+			return fmt.Errorf("failed to marshal correct answers to JSON: %v", err)
+		}
+		if _, err := stmt.ExecContext(ctx, topic, difficulty, question.Question, optionsJSON, question.CorrectAnswer, question.Description,
+			question.Type, correctAnswersJSON, question.CaseSensitive, question.IgnoreSpaces, language); err != nil {
+			return fmt.Errorf("failed to insert bank question: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FetchUnseenBankQuestions returns up to limit random bank questions of the
+// given types for the topic, difficulty and language that are not part of any
+// of the user's quizzes.
+func FetchUnseenBankQuestions(ctx context.Context, db *sql.DB, userID int64, topic, difficulty, language string, questionTypes []string, limit int) ([]types.Question, error) {
+	query := `
+		SELECT b.id, b.type, b.question, b.options, b.correct_answer, b.correct_answers,
+			b.case_sensitive, b.ignore_spaces, COALESCE(b.description, '')
+		FROM bank_questions b
+		WHERE b.topic = $1 AND b.difficulty = $2 AND b.type = ANY($5) AND b.language = $6
+		AND NOT EXISTS (
+			SELECT 1 FROM questions q
+			JOIN quizzes z ON z.id = q.quiz_id
+			WHERE q.bank_question_id = b.id AND z.user_id = $3
+		)
+		ORDER BY random()
+		LIMIT $4
+	`
+	rows, err := db.QueryContext(ctx, query, topic, difficulty, userID, limit, pq.Array(questionTypes), language)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching bank questions: %v", err)
+	}
+	defer rows.Close()
+
+	var questions []types.Question
+	for rows.Next() {
+		var question types.Question
+		var options, correctAnswers []byte
+		if err := rows.Scan(&question.ID, &question.Type, &question.Question, &options, &question.CorrectAnswer, &correctAnswers,
+			&question.CaseSensitive, &question.IgnoreSpaces, &question.Description); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(options, &question.Options); err != nil {
+			return nil, fmt.Errorf("error unmarshalling options: %v", err)
+		}
+		if err := scanStrings(correctAnswers, &question.CorrectAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling correct answers: %v", err)
+		}
+		questions = append(questions, question)
+	}
+	return questions, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"log"
@@ -142,6 +143,38 @@ func CreateQuestionsTable(db *sql.DB) {
 	fmt.Println("Table 'questions' created or already exists.")
 }
 
+// AddQuestionTypeColumns adds the columns for question types other than
+// single-answer MCQ. Existing rows become "mcq".
+func AddQuestionTypeColumns(db *sql.DB) error {
+	query := `
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'mcq';
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS correct_answers JSONB;
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS case_sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS ignore_spaces BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS user_answers JSONB;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add question type columns: %w", err)
+	}
+	return nil
+}
+
+// stringsJSON encodes an optional string list for a nullable JSONB column.
+func stringsJSON(values []string) ([]byte, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(values)
+}
+
+// scanStrings decodes a nullable JSONB string list.
+func scanStrings(data []byte, dst *[]string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dst)
+}
+
 /*------------------------------------------------------------------------ */
 
 // Create the challenges table
@@ -328,11 +361,11 @@ func RetrieveUser(db *sql.DB, identifier any) (*types.User, error) {
 
 	switch v := identifier.(type) {
 	case string:
-		query = `SELECT id, username, email, password,isVarified,profileImg,bio FROM users WHERE email = $1 OR username = $2 LIMIT 1`
-		err = db.QueryRow(query, v, v).Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.IsVarified, &user.ProfileImg, &user.Bio)
+		query = `SELECT id, username, email, password,isVarified,profileImg,bio, COALESCE(preferred_language, '') FROM users WHERE email = $1 OR username = $2 LIMIT 1`
+		err = db.QueryRow(query, v, v).Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.IsVarified, &user.ProfileImg, &user.Bio, &user.PreferredLanguage)
 	case int, int64:
-		query = `SELECT id, username, email, password,isVarified,profileImg,bio FROM users WHERE id = $1 LIMIT 1`
-		err = db.QueryRow(query, v).Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.IsVarified, &user.ProfileImg, &user.Bio)
+		query = `SELECT id, username, email, password,isVarified,profileImg,bio, COALESCE(preferred_language, '') FROM users WHERE id = $1 LIMIT 1`
+		err = db.QueryRow(query, v).Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.IsVarified, &user.ProfileImg, &user.Bio, &user.PreferredLanguage)
 	default:
 		return nil, fmt.Errorf("unsupported identifier type: %T", identifier)
 	}
@@ -561,12 +594,14 @@ func GetAllStudents(db *sql.DB) ([]types.User, error) {
 
 func InsertNewQuiz(db *sql.DB, quiz *types.Quiz) error {
 	query := `
-		INSERT INTO quizzes (quiz_name, user_id,score ,level,totalQuestions)
-		VALUES ($1, $2,$3,$4,$5)
-		RETURNING id;
+		INSERT INTO quizzes (quiz_name, user_id,score ,level,totalQuestions, language, prompt_template_id, difficulty_level, topic_id, time_limit_seconds, job_id)
+		VALUES ($1, $2,$3,$4,$5, COALESCE(NULLIF($6, ''), 'en'), (SELECT id FROM prompt_templates WHERE id = $7), $8, $9, $10, $11)
+		RETURNING id, language, prompt_template_id;
 	`
 
-	err := db.QueryRow(query, quiz.QuizName, quiz.UserID, quiz.Score, quiz.Level, quiz.TotalQuestions).Scan(&quiz.ID)
+	// A template deleted since generation is stored as NULL rather than
+	// failing the save.
+	err := db.QueryRow(query, quiz.QuizName, quiz.UserID, quiz.Score, quiz.Level, quiz.TotalQuestions, quiz.Language, quiz.PromptTemplateID, quiz.DifficultyLevel, quiz.TopicID, quiz.TimeLimitSeconds, quiz.JobID).Scan(&quiz.ID, &quiz.Language, &quiz.PromptTemplateID)
 	if err != nil {
 		return fmt.Errorf("failed to insert new quiz: %w", err)
 	}
@@ -576,12 +611,42 @@ func InsertNewQuiz(db *sql.DB, quiz *types.Quiz) error {
 
 /*--------------------------------------------------------------------*/
 
+// ErrQuestionsExist is returned when questions are added to a quiz that
+// already has some.
+var ErrQuestionsExist = errors.New("quiz already has questions")
+
+// LockEmptyQuiz locks the quiz for the rest of tx, so that its questions are
+// only inserted once, and returns ErrQuestionsExist if it already has any.
+func LockEmptyQuiz(tx *sql.Tx, quizID int) error {
+	err := tx.QueryRow(`SELECT id FROM quizzes WHERE id = $1 FOR UPDATE`, quizID).Scan(&quizID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error locking quiz: %w", err)
+	}
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM questions WHERE quiz_id = $1)`, quizID).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking quiz questions: %w", err)
+	}
+	if exists {
+		return ErrQuestionsExist
+	}
+	return nil
+}
+
 func InsertNewQuestions(tx *sql.Tx, questions []types.Question) error {
-	stmt, err := tx.Prepare(`
+	// bank_question_id links the question to its shared bank entry, if any,
+	// so the bank can tell which questions a user has already seen. Questions
+	// always start unanswered; answers only come in through SubmitQuiz.
+	stmt, err := tx.Prepare(fmt.Sprintf(`
 		INSERT INTO questions (
-			quiz_id, serial_number, question, options, correct_answer, description, user_answer
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`)
+			quiz_id, serial_number, question, options, correct_answer, description, user_answer, bank_question_id,
+			type, correct_answers, case_sensitive, ignore_spaces, user_answers, source_excerpt, time_limit_seconds
+		) VALUES ($1, $2, $3, $4, $5, $6, '',
+			(SELECT id FROM bank_questions WHERE normalized_text = %s),
+			COALESCE(NULLIF($7, ''), 'mcq'), $8, $9, $10, NULL, NULLIF($11, ''), $12)
+	`, fmt.Sprintf(normalizedQuestionSQL, "$3::text")))
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %v", err)
 	}
@@ -590,10 +655,17 @@ func InsertNewQuestions(tx *sql.Tx, questions []types.Question) error {
 	for _, question := range questions {
 
 		// Convert Options to JSON format
+		if question.Options == nil {
+			question.Options = []string{}
+		}
 		optionsJSON, err := json.Marshal(question.Options)
 		if err != nil {
 			return fmt.Errorf("failed to marshal options to JSON: %v", err)
 		}
+		correctAnswersJSON, err := stringsJSON(question.CorrectAnswers)
+		if err != nil {
+			return fmt.Errorf("failed to marshal correct answers to JSON: %v", err)
+		}
 
 		// Execute the insert statement with question data
 		_, err = stmt.Exec(
@@ -603,7 +675,12 @@ func InsertNewQuestions(tx *sql.Tx, questions []types.Question) error {
 			optionsJSON, // JSON-encoded options
 			question.CorrectAnswer,
 			question.Description,
-			question.UserAnswer,
+			question.Type,
+			correctAnswersJSON,
+			question.CaseSensitive,
+			question.IgnoreSpaces,
+			question.SourceExcerpt,
+			question.TimeLimitSeconds,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert question: %v", err)
@@ -616,7 +693,19 @@ func InsertNewQuestions(tx *sql.Tx, questions []types.Question) error {
 // -------------------- ---------------------------------
 
 func FetchQuizzesByUser(db *sql.DB, userID int) ([]types.Quiz, error) {
-	query := `SELECT id, quiz_name, score, level, totalQuestions, created_at FROM quizzes WHERE user_id = $1`
+	query := `
+		SELECT z.id, z.quiz_name, z.score, z.level, z.totalQuestions, z.language, z.created_at, z.prompt_template_id, z.difficulty_level, z.topic_id, COALESCE(t.name, ''), z.submitted_at,
+			a.attempts, a.best_score, a.latest_score, z.time_limit_seconds
+		FROM quizzes z
+		LEFT JOIN topics t ON t.id = z.topic_id
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS attempts, MAX(score) AS best_score,
+				(ARRAY_AGG(score ORDER BY finished_at DESC))[1] AS latest_score
+			FROM quiz_attempts
+			WHERE quiz_id = z.id AND finished_at IS NOT NULL
+		) a
+		WHERE z.user_id = $1
+	`
 	rows, err := db.Query(query, userID)
 	if err != nil {
 		return nil, err
@@ -626,7 +715,8 @@ func FetchQuizzesByUser(db *sql.DB, userID int) ([]types.Quiz, error) {
 	var quizzes []types.Quiz
 	for rows.Next() {
 		var quiz types.Quiz
-		if err := rows.Scan(&quiz.ID, &quiz.QuizName, &quiz.Score, &quiz.Level, &quiz.TotalQuestions, &quiz.CreatedAt); err != nil {
+		if err := rows.Scan(&quiz.ID, &quiz.QuizName, &quiz.Score, &quiz.Level, &quiz.TotalQuestions, &quiz.Language, &quiz.CreatedAt, &quiz.PromptTemplateID, &quiz.DifficultyLevel, &quiz.TopicID, &quiz.Topic, &quiz.SubmittedAt,
+			&quiz.Attempts, &quiz.BestScore, &quiz.LatestScore, &quiz.TimeLimitSeconds); err != nil {
 			return nil, err
 		}
 		quizzes = append(quizzes, quiz)
@@ -636,7 +726,7 @@ func FetchQuizzesByUser(db *sql.DB, userID int) ([]types.Quiz, error) {
 
 // -------------------------------------------
 func FetchQuizzesByQuizId(db *sql.DB, quizId int) (*types.Quiz, error) {
-	query := `SELECT quiz_name, level, created_at FROM quizzes WHERE id = $1`
+	query := `SELECT quiz_name, level, language, created_at FROM quizzes WHERE id = $1`
 
 	// Use QueryRow for a single result
 	row := db.QueryRow(query, quizId)
@@ -645,7 +735,7 @@ func FetchQuizzesByQuizId(db *sql.DB, quizId int) (*types.Quiz, error) {
 	quiz := &types.Quiz{}
 
 	// Scan the result into the quiz structure
-	err := row.Scan(&quiz.QuizName, &quiz.Level, &quiz.CreatedAt)
+	err := row.Scan(&quiz.QuizName, &quiz.Level, &quiz.Language, &quiz.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Return a nil quiz with a meaningful error if no rows are found
@@ -661,7 +751,17 @@ func FetchQuizzesByQuizId(db *sql.DB, quizId int) (*types.Quiz, error) {
 
 // FetchQuestionsByQuiz retrieves all questions for a specific quiz
 func FetchQuestionsByQuiz(db *sql.DB, quizID int) ([]types.Question, error) {
-	query := `SELECT id, serial_number, question, options, correct_answer, user_answer, description FROM questions WHERE quiz_id = $1`
+	// Answers come from the latest finished attempt, if they were given to
+	// the current version of the question.
+	query := fmt.Sprintf(`
+		SELECT q.id, q.serial_number, q.type, q.question, q.options, q.correct_answer, q.correct_answers, q.case_sensitive, q.ignore_spaces,
+			COALESCE(a.user_answer, ''), a.user_answers, q.description, COALESCE(q.source_excerpt, ''), a.is_correct, q.time_limit_seconds
+		FROM questions q
+		LEFT JOIN attempt_answers a ON a.question_id = q.id AND a.version = %s AND a.attempt_id = (
+			SELECT id FROM quiz_attempts WHERE quiz_id = q.quiz_id AND finished_at IS NOT NULL ORDER BY finished_at DESC LIMIT 1
+		)
+		WHERE q.quiz_id = $1
+	`, fmt.Sprintf(questionVersionSQL, "q.id"))
 	rows, err := db.Query(query, quizID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching questions: %v", err)
@@ -671,16 +771,24 @@ func FetchQuestionsByQuiz(db *sql.DB, quizID int) ([]types.Question, error) {
 	var questions []types.Question
 	for rows.Next() {
 		var question types.Question
-		var options []byte // Read options as []byte first
+		var options, correctAnswers, userAnswers []byte // Read JSONB columns as []byte first
 
 		if err := rows.Scan(
 			&question.ID,
 			&question.SerialNumber,
+			&question.Type,
 			&question.Question,
 			&options,
 			&question.CorrectAnswer,
+			&correctAnswers,
+			&question.CaseSensitive,
+			&question.IgnoreSpaces,
 			&question.UserAnswer,
+			&userAnswers,
 			&question.Description,
+			&question.SourceExcerpt,
+			&question.IsCorrect,
+			&question.TimeLimitSeconds,
 		); err != nil {
 			return nil, err
 		}
@@ -689,6 +797,12 @@ func FetchQuestionsByQuiz(db *sql.DB, quizID int) ([]types.Question, error) {
 		if err := json.Unmarshal(options, &question.Options); err != nil {
 			return nil, fmt.Errorf("error unmarshalling options: %v", err)
 		}
+		if err := scanStrings(correctAnswers, &question.CorrectAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling correct answers: %v", err)
+		}
+		if err := scanStrings(userAnswers, &question.UserAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling user answers: %v", err)
+		}
 
 		questions = append(questions, question)
 	}
@@ -0,0 +1,65 @@
+// Package dbtest gives tests a migrated Postgres database. Tests that need
+// one are skipped unless TEST_DATABASE_URL names a throwaway database.
+package dbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+)
+
+var users atomic.Int64
+
+// Open connects to TEST_DATABASE_URL and migrates it, or skips the test.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The base tables are normally created outside the server.
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS users (
+		id SERIAL PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		email TEXT NOT NULL UNIQUE,
+		password TEXT NOT NULL,
+		isVarified BOOL DEFAULT false
+	);`); err != nil {
+		t.Fatal(err)
+	}
+	database.CreateQuizzesTable(db)
+	database.CreateQuestionsTable(db)
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// NewUser adds a user that is deleted, with everything of theirs, when the
+// test ends.
+func NewUser(t testing.TB, db *sql.DB) int64 {
+	t.Helper()
+	name := fmt.Sprintf("test-%d-%d", time.Now().UnixNano(), users.Add(1))
+	var id int64
+	err := db.QueryRow(`INSERT INTO users (username, email, password) VALUES ($1, $1 || '@example.com', 'x') RETURNING id`, name).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, id) })
+	return id
+}
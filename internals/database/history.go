@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// FetchRecentPastQuestions returns up to limit questions from the user's
+// quizzes, newest first, regardless of topic. Relevance to a new quiz is
+// decided by the caller.
+func FetchRecentPastQuestions(ctx context.Context, db *sql.DB, userID int64, limit int) ([]types.PastQuestion, error) {
+	query := `
+		SELECT q.question, z.quiz_name, z.created_at, z.topic_id
+		FROM questions q
+		JOIN quizzes z ON q.quiz_id = z.id
+		WHERE z.user_id = $1
+		ORDER BY z.created_at DESC, q.serial_number
+		LIMIT $2
+	`
+	rows, err := db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching past questions: %v", err)
+	}
+	defer rows.Close()
+
+	var past []types.PastQuestion
+	for rows.Next() {
+		var pq types.PastQuestion
+		if err := rows.Scan(&pq.Question, &pq.QuizName, &pq.CreatedAt, &pq.TopicID); err != nil {
+			return nil, err
+		}
+		past = append(past, pq)
+	}
+	return past, rows.Err()
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// ErrNotFound is wrapped by lookups that match no row, so handlers can answer
+// 404 instead of 500.
+var ErrNotFound = errors.New("not found")
+
+func CreateQuizJobsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS quiz_jobs (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'queued',
+		progress INT NOT NULL DEFAULT 0,
+		attempts INT NOT NULL DEFAULT 0,
+		request JSONB NOT NULL,
+		result JSONB,
+		error TEXT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		updated_at TIMESTAMP DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS quiz_jobs_queued_idx ON quiz_jobs (id) WHERE status = 'queued';
+	ALTER TABLE quiz_jobs ADD COLUMN IF NOT EXISTS report JSONB;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create quiz_jobs table: %w", err)
+	}
+	return nil
+}
+
+func InsertQuizJob(tx *sql.Tx, quizRequest *types.QuizRequest) (int64, error) {
+	requestJSON, err := json.Marshal(quizRequest)
+	if err != nil {
+		return -1, fmt.Errorf("failed to marshal quiz request: %w", err)
+	}
+
+	var id int64
+	query := `INSERT INTO quiz_jobs (user_id, request) VALUES ($1, $2) RETURNING id`
+	if err := tx.QueryRow(query, quizRequest.UserID, requestJSON).Scan(&id); err != nil {
+		return -1, fmt.Errorf("failed to insert quiz job: %w", err)
+	}
+	return id, nil
+}
+
+// ClaimNextQuizJob marks the oldest queued job as running and returns it.
+// SKIP LOCKED lets several workers, or several server instances, claim jobs
+// concurrently without handing the same job out twice. It returns nil, nil
+// when the queue is empty.
+func ClaimNextQuizJob(ctx context.Context, db *sql.DB) (*types.QuizJob, error) {
+	query := `
+		UPDATE quiz_jobs
+		SET status = 'running', progress = 10, attempts = attempts + 1, updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM quiz_jobs
+			WHERE status = 'queued'
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, user_id, status, progress, attempts, request, created_at, updated_at
+	`
+
+	var job types.QuizJob
+	var requestJSON []byte
+	err := db.QueryRowContext(ctx, query).Scan(
+		&job.ID, &job.UserID, &job.Status, &job.Progress, &job.Attempts, &requestJSON, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim quiz job: %w", err)
+	}
+
+	if err := json.Unmarshal(requestJSON, &job.Request); err != nil {
+		return nil, fmt.Errorf("error unmarshalling job request: %v", err)
+	}
+	return &job, nil
+}
+
+func CompleteQuizJob(db *sql.DB, id int64, questions []types.Question, report *types.GenerationReport) error {
+	resultJSON, err := json.Marshal(questions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job result: %w", err)
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job report: %w", err)
+	}
+
+	query := `UPDATE quiz_jobs SET status = 'succeeded', progress = 100, result = $1, report = $2, error = NULL, updated_at = NOW() WHERE id = $3`
+	if _, err := db.Exec(query, resultJSON, reportJSON, id); err != nil {
+		return fmt.Errorf("failed to complete quiz job %d: %w", id, err)
+	}
+	return nil
+}
+
+func FailQuizJob(db *sql.DB, id int64, message string) error {
+	query := `UPDATE quiz_jobs SET status = 'failed', progress = 100, error = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := db.Exec(query, message, id); err != nil {
+		return fmt.Errorf("failed to mark quiz job %d as failed: %w", id, err)
+	}
+	return nil
+}
+
+// RequeueQuizJob puts a running job back in the queue, e.g. when the server
+// shuts down before the job could finish.
+func RequeueQuizJob(db *sql.DB, id int64) error {
+	query := `UPDATE quiz_jobs SET status = 'queued', progress = 0, updated_at = NOW() WHERE id = $1 AND status = 'running'`
+	if _, err := db.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to requeue quiz job %d: %w", id, err)
+	}
+	return nil
+}
+
+// RequeueStaleQuizJobs requeues jobs left running by an instance that died
+// without a graceful shutdown. It returns the number of jobs requeued.
+func RequeueStaleQuizJobs(db *sql.DB, olderThanSeconds int) (int64, error) {
+	query := `
+		UPDATE quiz_jobs SET status = 'queued', progress = 0, updated_at = NOW()
+		WHERE status = 'running' AND updated_at < NOW() - make_interval(secs => $1)
+	`
+	res, err := db.Exec(query, olderThanSeconds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale quiz jobs: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// InsertCompletedQuizJob records a quiz generated synchronously, as by the
+// streaming endpoint, as a succeeded job, so that saving it works the same
+// as for queued jobs.
+func InsertCompletedQuizJob(db *sql.DB, quizRequest *types.QuizRequest, questions []types.Question, report *types.GenerationReport) (int64, error) {
+	requestJSON, err := json.Marshal(quizRequest)
+	if err != nil {
+		return -1, fmt.Errorf("failed to marshal quiz request: %w", err)
+	}
+	resultJSON, err := json.Marshal(questions)
+	if err != nil {
+		return -1, fmt.Errorf("failed to marshal job result: %w", err)
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return -1, fmt.Errorf("failed to marshal job report: %w", err)
+	}
+
+	var id int64
+	query := `
+		INSERT INTO quiz_jobs (user_id, status, progress, request, result, report)
+		VALUES ($1, 'succeeded', 100, $2, $3, $4)
+		RETURNING id
+	`
+	if err := db.QueryRow(query, quizRequest.UserID, requestJSON, resultJSON, reportJSON).Scan(&id); err != nil {
+		return -1, fmt.Errorf("failed to insert quiz job: %w", err)
+	}
+	return id, nil
+}
+
+// AddQuizJobColumn links quizzes to the job that generated them, whose
+// output is what their questions are checked and graded against. Quizzes
+// saved before then have none.
+func AddQuizJobColumn(db *sql.DB) error {
+	query := `ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS job_id INT REFERENCES quiz_jobs(id) ON DELETE SET NULL;`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add quiz job column: %w", err)
+	}
+	return nil
+}
+
+// JobQuestionsSaved reports whether a quiz saved from the job has its
+// questions, which are then read from the quiz rather than the job.
+func JobQuestionsSaved(db *sql.DB, jobID int64) (bool, error) {
+	var saved bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM quizzes z JOIN questions q ON q.quiz_id = z.id WHERE z.job_id = $1)
+	`, jobID).Scan(&saved)
+	if err != nil {
+		return false, fmt.Errorf("error checking job questions: %w", err)
+	}
+	return saved, nil
+}
+
+// FetchQuizJob returns a job only if it belongs to userID.
+func FetchQuizJob(db *sql.DB, id int64, userID int64) (*types.QuizJob, error) {
+	query := `
+		SELECT id, user_id, status, progress, attempts, request, result, report, COALESCE(error, ''), created_at, updated_at
+		FROM quiz_jobs WHERE id = $1 AND user_id = $2
+	`
+
+	var job types.QuizJob
+	var requestJSON, resultJSON, reportJSON []byte
+	err := db.QueryRow(query, id, userID).Scan(
+		&job.ID, &job.UserID, &job.Status, &job.Progress, &job.Attempts, &requestJSON, &resultJSON, &reportJSON, &job.Error, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no quiz job found with id %d: %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(requestJSON, &job.Request); err != nil {
+		return nil, fmt.Errorf("error unmarshalling job request: %v", err)
+	}
+	if resultJSON != nil {
+		if err := json.Unmarshal(resultJSON, &job.Questions); err != nil {
+			return nil, fmt.Errorf("error unmarshalling job result: %v", err)
+		}
+	}
+	if reportJSON != nil {
+		if err := json.Unmarshal(reportJSON, &job.Report); err != nil {
+			return nil, fmt.Errorf("error unmarshalling job report: %v", err)
+		}
+	}
+	return &job, nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// AddLanguageColumns records the language of quizzes and bank questions
+// (existing rows are English) and adds the users' preferred language.
+func AddLanguageColumns(db *sql.DB) error {
+	query := `
+	ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en';
+	ALTER TABLE bank_questions ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en';
+	CREATE INDEX IF NOT EXISTS bank_questions_topic_language_idx ON bank_questions (topic, difficulty, language);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS preferred_language TEXT;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add language columns: %w", err)
+	}
+	return nil
+}
+
+// FetchPreferredLanguage returns the user's preferred quiz language, or ""
+// if they haven't chosen one.
+func FetchPreferredLanguage(db *sql.DB, userID int64) (string, error) {
+	var language sql.NullString
+	err := db.QueryRow(`SELECT preferred_language FROM users WHERE id = $1`, userID).Scan(&language)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("error fetching preferred language: %w", err)
+	}
+	return language.String, nil
+}
+
+// UpdatePreferredLanguage sets the user's preferred quiz language; "" clears
+// it.
+func UpdatePreferredLanguage(db *sql.DB, userID int, language string) error {
+	result, err := db.Exec(`UPDATE users SET preferred_language = NULLIF($1, '') WHERE id = $2`, language, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no user found with the given ID")
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreateMaterialsTables creates the tables for uploaded study material and
+// the source_excerpt column of generated questions.
+func CreateMaterialsTables(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS materials (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		characters INT NOT NULL,
+		created_at TIMESTAMP DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS materials_user_id_idx ON materials (user_id);
+	CREATE TABLE IF NOT EXISTS material_chunks (
+		material_id INT NOT NULL REFERENCES materials(id) ON DELETE CASCADE,
+		seq INT NOT NULL,
+		text TEXT NOT NULL,
+		PRIMARY KEY (material_id, seq)
+	);
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS source_excerpt TEXT;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create materials tables: %w", err)
+	}
+	return nil
+}
+
+// InsertMaterial stores a material and its chunks, setting material.ID and
+// material.CreatedAt.
+func InsertMaterial(ctx context.Context, db *sql.DB, material *types.Material, chunks []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO materials (user_id, title, kind, characters)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, material.UserID, material.Title, material.Kind, material.Characters).Scan(&material.ID, &material.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert material: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO material_chunks (material_id, seq, text) VALUES ($1, $2, $3)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for i, chunk := range chunks {
+		if _, err := stmt.ExecContext(ctx, material.ID, i, chunk); err != nil {
+			return fmt.Errorf("failed to insert material chunk: %v", err)
+		}
+	}
+	material.Chunks = len(chunks)
+
+	return tx.Commit()
+}
+
+const materialColumns = `m.id, m.user_id, m.title, m.kind, m.characters,
+	(SELECT COUNT(*) FROM material_chunks c WHERE c.material_id = m.id), m.created_at`
+
+func scanMaterial(row interface{ Scan(...any) error }, material *types.Material) error {
+	return row.Scan(&material.ID, &material.UserID, &material.Title, &material.Kind, &material.Characters, &material.Chunks, &material.CreatedAt)
+}
+
+// FetchMaterialsByUser lists the user's materials, newest first.
+func FetchMaterialsByUser(db *sql.DB, userID int64) ([]types.Material, error) {
+	rows, err := db.Query(`SELECT `+materialColumns+` FROM materials m WHERE m.user_id = $1 ORDER BY m.created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching materials: %v", err)
+	}
+	defer rows.Close()
+
+	materials := []types.Material{}
+	for rows.Next() {
+		var material types.Material
+		if err := scanMaterial(rows, &material); err != nil {
+			return nil, err
+		}
+		materials = append(materials, material)
+	}
+	return materials, rows.Err()
+}
+
+// FetchMaterial returns one of the user's materials, or ErrNotFound.
+func FetchMaterial(db *sql.DB, materialID, userID int64) (*types.Material, error) {
+	var material types.Material
+	row := db.QueryRow(`SELECT `+materialColumns+` FROM materials m WHERE m.id = $1 AND m.user_id = $2`, materialID, userID)
+	if err := scanMaterial(row, &material); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("material %d: %w", materialID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("error fetching material: %w", err)
+	}
+	return &material, nil
+}
+
+// FetchMaterialChunks returns the chunks of one of the user's materials in
+// document order, or ErrNotFound.
+func FetchMaterialChunks(ctx context.Context, db *sql.DB, materialID, userID int64) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.text
+		FROM material_chunks c
+		JOIN materials m ON m.id = c.material_id
+		WHERE c.material_id = $1 AND m.user_id = $2
+		ORDER BY c.seq
+	`, materialID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching material chunks: %v", err)
+	}
+	defer rows.Close()
+
+	var chunks []string
+	for rows.Next() {
+		var chunk string
+		if err := rows.Scan(&chunk); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("material %d: %w", materialID, ErrNotFound)
+	}
+	return chunks, nil
+}
+
+// DeleteMaterial removes one of the user's materials, or returns ErrNotFound.
+func DeleteMaterial(db *sql.DB, materialID, userID int64) error {
+	result, err := db.Exec(`DELETE FROM materials WHERE id = $1 AND user_id = $2`, materialID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete material: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("material %d: %w", materialID, ErrNotFound)
+	}
+	return nil
+}
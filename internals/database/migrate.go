@@ -0,0 +1,39 @@
+package database
+
+import "database/sql"
+
+// migrations run in order at startup. Each must be safe to run again on an
+// already migrated database.
+var migrations = []func(*sql.DB) error{
+	CreateQuizJobsTable,
+	AddQuestionTypeColumns,
+	CreateQuestionBankTable,
+	CreateMaterialsTables,
+	AddLanguageColumns,
+	CreateUsageTable,
+	CreateModerationTable,
+	CreatePromptTemplatesTable,
+	CreateSkillTables,
+	CreateQuestionRevisionsTable,
+	CreateTopicsTables,
+	AddSubmissionColumns,
+	CreateQuizAttemptsTables,
+	AddTimeLimitColumns,
+	CreateQuizSharesTable,
+	AddAttemptProgressColumns,
+	CreateReviewCardsTable,
+	AddQuizJobColumn,
+	AddRetakeColumn,
+	CreateShownQuestionsTable,
+}
+
+// Migrate brings the schema up to date. The base tables (users, quizzes,
+// questions) must already exist.
+func Migrate(db *sql.DB) error {
+	for _, migrate := range migrations {
+		if err := migrate(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
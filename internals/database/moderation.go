@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreateModerationTable creates the log of topics rejected by moderation.
+func CreateModerationTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS moderation_events (
+		id SERIAL PRIMARY KEY,
+		user_id INT REFERENCES users(id) ON DELETE SET NULL,
+		text TEXT NOT NULL,
+		code VARCHAR(40) NOT NULL,
+		reason TEXT NOT NULL,
+		check_name TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		review_note TEXT,
+		reviewed_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS moderation_events_status_idx ON moderation_events (status, created_at);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create moderation_events table: %w", err)
+	}
+	return nil
+}
+
+func InsertModerationEvent(db *sql.DB, event *types.ModerationEvent) error {
+	query := `
+		INSERT INTO moderation_events (user_id, text, code, reason, check_name)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+	err := db.QueryRow(query, event.UserID, event.Text, event.Code, event.Reason, event.Check).Scan(&event.ID, &event.Status, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert moderation event: %w", err)
+	}
+	return nil
+}
+
+// FetchModerationEvents returns the newest events with the given review
+// status, or of any status if status is "".
+func FetchModerationEvents(db *sql.DB, status string, limit int) ([]types.ModerationEvent, error) {
+	query := `
+		SELECT id, COALESCE(user_id, 0), text, code, reason, check_name, status, COALESCE(review_note, ''), reviewed_at, created_at
+		FROM moderation_events
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := db.Query(query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching moderation events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []types.ModerationEvent{}
+	for rows.Next() {
+		var event types.ModerationEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Text, &event.Code, &event.Reason, &event.Check,
+			&event.Status, &event.ReviewNote, &event.ReviewedAt, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// ReviewModerationEvent records an admin's decision on an event.
+func ReviewModerationEvent(db *sql.DB, id int64, status, note string) error {
+	query := `UPDATE moderation_events SET status = $1, review_note = NULLIF($2, ''), reviewed_at = NOW() WHERE id = $3`
+	result, err := db.Exec(query, status, note, id)
+	if err != nil {
+		return fmt.Errorf("failed to review moderation event %d: %w", id, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
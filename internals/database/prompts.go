@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreatePromptTemplatesTable creates the versioned prompt templates and
+// records on every quiz which of them generated it.
+func CreatePromptTemplatesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS prompt_templates (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		version INT NOT NULL,
+		body TEXT NOT NULL,
+		weight INT NOT NULL DEFAULT 1 CHECK (weight >= 0),
+		active BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (name, version)
+	);
+	ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS prompt_template_id INT REFERENCES prompt_templates(id) ON DELETE SET NULL;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create prompt_templates table: %w", err)
+	}
+	return nil
+}
+
+// InsertPromptTemplate saves tmpl as the next version of its name, setting
+// its ID, Version and CreatedAt.
+func InsertPromptTemplate(db *sql.DB, tmpl *types.PromptTemplate) error {
+	query := `
+		INSERT INTO prompt_templates (name, version, body, weight, active)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4 FROM prompt_templates WHERE name = $1
+		RETURNING id, version, created_at
+	`
+	err := db.QueryRow(query, tmpl.Name, tmpl.Body, tmpl.Weight, tmpl.Active).Scan(&tmpl.ID, &tmpl.Version, &tmpl.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert prompt template: %w", err)
+	}
+	return nil
+}
+
+// UpdatePromptTemplate changes whether a template is active and its weight.
+// The body of a version never changes.
+func UpdatePromptTemplate(db *sql.DB, id int64, active bool, weight int) error {
+	result, err := db.Exec(`UPDATE prompt_templates SET active = $1, weight = $2 WHERE id = $3`, active, weight, id)
+	if err != nil {
+		return fmt.Errorf("failed to update prompt template %d: %w", id, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// FetchActivePromptTemplates returns the templates users are assigned to, in
+// ID order. Templates with weight 0 are left out.
+func FetchActivePromptTemplates(ctx context.Context, db *sql.DB) ([]types.PromptTemplate, error) {
+	query := `
+		SELECT id, name, version, body, weight, active, created_at
+		FROM prompt_templates
+		WHERE active AND weight > 0
+		ORDER BY id
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []types.PromptTemplate
+	for rows.Next() {
+		var tmpl types.PromptTemplate
+		if err := rows.Scan(&tmpl.ID, &tmpl.Name, &tmpl.Version, &tmpl.Body, &tmpl.Weight, &tmpl.Active, &tmpl.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, rows.Err()
+}
+
+// FetchPromptMetrics returns every template version with quality metrics of
+// what it generated: from the reports of successful generation jobs, and from
+// the scores of the submitted quizzes saved with it.
+func FetchPromptMetrics(db *sql.DB) ([]types.PromptMetrics, error) {
+	query := `
+		SELECT t.id, t.name, t.version, t.body, t.weight, t.active, t.created_at,
+			COALESCE(j.jobs, 0), COALESCE(j.avg_rounds, 0), COALESCE(j.avg_dropped, 0), COALESCE(j.avg_repaired, 0),
+			COALESCE(q.quizzes, 0), COALESCE(q.avg_score_rate, 0)
+		FROM prompt_templates t
+		LEFT JOIN (
+			SELECT (report->>'prompt_template_id')::INT AS template_id,
+				COUNT(*) AS jobs,
+				AVG((report->>'rounds')::FLOAT) AS avg_rounds,
+				AVG(jsonb_array_length(COALESCE(report->'dropped', '[]'::JSONB))) AS avg_dropped,
+				AVG(jsonb_array_length(COALESCE(report->'repaired', '[]'::JSONB))) AS avg_repaired
+			FROM quiz_jobs
+			WHERE status = 'succeeded' AND report ? 'prompt_template_id'
+			GROUP BY 1
+		) j ON j.template_id = t.id
+		LEFT JOIN (
+			SELECT z.prompt_template_id AS template_id,
+				COUNT(*) AS quizzes,
+				AVG(a.score::FLOAT / NULLIF(a.total_questions, 0)) AS avg_score_rate
+			FROM quizzes z
+			JOIN quiz_attempts a ON a.quiz_id = z.id AND a.finished_at IS NOT NULL AND NOT a.is_retake
+			WHERE z.prompt_template_id IS NOT NULL
+			GROUP BY 1
+		) q ON q.template_id = t.id
+		ORDER BY t.name, t.version DESC
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching prompt metrics: %w", err)
+	}
+	defer rows.Close()
+
+	metrics := []types.PromptMetrics{}
+	for rows.Next() {
+		var m types.PromptMetrics
+		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Body, &m.Weight, &m.Active, &m.CreatedAt,
+			&m.Jobs, &m.AvgRounds, &m.AvgDropped, &m.AvgRepaired, &m.Quizzes, &m.AvgScoreRate); err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, rows.Err()
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreateReviewCardsTable creates the table of spaced-repetition review cards:
+// one per user and question they have missed, with the question version
+// they missed and its schedule. A new card is due at once.
+func CreateReviewCardsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS review_cards (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		question_id INT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+		version INT NOT NULL DEFAULT 1,
+		ease_factor DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+		interval_days INT NOT NULL DEFAULT 0,
+		repetitions INT NOT NULL DEFAULT 0,
+		reviews INT NOT NULL DEFAULT 0,
+		due_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_reviewed_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (user_id, question_id)
+	);
+	CREATE INDEX IF NOT EXISTS review_cards_due_idx ON review_cards (user_id, due_at);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create review cards table: %w", err)
+	}
+	return nil
+}
+
+// seedReviewCards adds the questions answered wrong in a finished attempt
+// to its user's review queue. A question missed again starts over, due at
+// once, at the version missed last; its ease is kept.
+func seedReviewCards(tx *sql.Tx, attemptID int64) error {
+	_, err := tx.Exec(`
+		INSERT INTO review_cards (user_id, question_id, version)
+		SELECT t.user_id, a.question_id, a.version
+		FROM attempt_answers a
+		JOIN quiz_attempts t ON t.id = a.attempt_id
+		WHERE a.attempt_id = $1 AND a.is_correct = FALSE
+		ON CONFLICT (user_id, question_id) DO UPDATE SET
+			version = EXCLUDED.version, interval_days = 0, repetitions = 0, due_at = NOW()
+	`, attemptID)
+	if err != nil {
+		return fmt.Errorf("failed to seed review cards: %w", err)
+	}
+	return nil
+}
+
+const reviewCardColumns = `c.id, c.ease_factor, c.interval_days, c.repetitions, c.reviews, c.due_at, c.last_reviewed_at`
+
+func reviewCardFields(card *types.ReviewCard) []any {
+	return []any{&card.ID, &card.EaseFactor, &card.IntervalDays, &card.Repetitions, &card.Reviews, &card.DueAt, &card.LastReviewedAt}
+}
+
+// FetchDueReviewCards returns up to limit of the user's cards that are due,
+// most overdue first, each with the question as it read when it was missed,
+// solution included.
+func FetchDueReviewCards(db *sql.DB, userID int64, limit int) ([]types.ReviewCard, error) {
+	rows, err := db.Query(`
+		SELECT `+versionedQuestionColumns+`, q.quiz_id, `+reviewCardColumns+`
+		FROM review_cards c
+		JOIN questions q ON q.id = c.question_id
+		LEFT JOIN question_revisions r ON r.question_id = c.question_id AND r.revision = c.version
+		WHERE c.user_id = $1 AND c.due_at <= NOW()
+		ORDER BY c.due_at, c.id
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching due review cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := []types.ReviewCard{}
+	for rows.Next() {
+		var card types.ReviewCard
+		var q types.Question
+		if err := scanVersionedQuestion(rows, &q, append([]any{&card.QuizID}, reviewCardFields(&card)...)...); err != nil {
+			return nil, err
+		}
+		view := q.View(true, nil)
+		card.Question = &view
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// RecordReview reschedules one of the user's cards: schedule sets its ease
+// factor, interval and repetitions from the old ones, and the card becomes
+// due interval days from now. Concurrent reviews of a card are serialized.
+// Returns ErrNotFound if the card isn't the user's.
+func RecordReview(db *sql.DB, userID, cardID int64, schedule func(card *types.ReviewCard)) (*types.ReviewCard, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var card types.ReviewCard
+	err = tx.QueryRow(`
+		SELECT `+reviewCardColumns+`, q.quiz_id
+		FROM review_cards c
+		JOIN questions q ON q.id = c.question_id
+		WHERE c.id = $1 AND c.user_id = $2
+		FOR UPDATE OF c
+	`, cardID, userID).Scan(append(reviewCardFields(&card), &card.QuizID)...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching review card: %w", err)
+	}
+
+	schedule(&card)
+	err = tx.QueryRow(`
+		UPDATE review_cards c SET ease_factor = $2, interval_days = $3, repetitions = $4, reviews = reviews + 1,
+			last_reviewed_at = NOW(), due_at = NOW() + make_interval(days => $3)
+		WHERE c.id = $1
+		RETURNING `+reviewCardColumns,
+		card.ID, card.EaseFactor, card.IntervalDays, card.Repetitions).Scan(reviewCardFields(&card)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record review: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &card, nil
+}
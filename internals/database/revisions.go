@@ -0,0 +1,209 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// questionVersionSQL is the current version of the question with ID %s: 1
+// as generated, and one more for every revision kept since. Revision n of a
+// question holds its version n.
+const questionVersionSQL = `(SELECT COALESCE(MAX(revision), 0) + 1 FROM question_revisions WHERE question_id = %s)`
+
+// CreateQuestionRevisionsTable creates the table that keeps the earlier
+// versions of regenerated quiz questions.
+func CreateQuestionRevisionsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS question_revisions (
+		id SERIAL PRIMARY KEY,
+		question_id INT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+		revision INT NOT NULL,
+		type TEXT NOT NULL,
+		question TEXT NOT NULL,
+		options JSONB NOT NULL,
+		correct_answer TEXT NOT NULL,
+		correct_answers JSONB,
+		case_sensitive BOOLEAN NOT NULL DEFAULT FALSE,
+		ignore_spaces BOOLEAN NOT NULL DEFAULT FALSE,
+		user_answer TEXT,
+		user_answers JSONB,
+		description TEXT,
+		source_excerpt TEXT,
+		bank_question_id INT REFERENCES bank_questions(id) ON DELETE SET NULL,
+		replaced_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (question_id, revision)
+	);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create question revisions table: %w", err)
+	}
+	return nil
+}
+
+// FetchUserQuiz returns the quiz if it belongs to the user, or ErrNotFound.
+func FetchUserQuiz(db *sql.DB, quizID, userID int) (*types.Quiz, error) {
+	return fetchQuiz(db, `z.id = $1 AND z.user_id = $2`, quizID, userID)
+}
+
+func fetchQuiz(db *sql.DB, where string, args ...any) (*types.Quiz, error) {
+	query := `
+		SELECT z.id, z.quiz_name, z.score, z.level, z.totalQuestions, z.user_id, z.language, z.created_at, z.prompt_template_id, z.difficulty_level, z.topic_id, COALESCE(t.name, ''), z.submitted_at, z.time_limit_seconds, z.job_id
+		FROM quizzes z
+		LEFT JOIN topics t ON t.id = z.topic_id
+		WHERE ` + where
+	quiz := &types.Quiz{}
+	err := db.QueryRow(query, args...).Scan(&quiz.ID, &quiz.QuizName, &quiz.Score, &quiz.Level, &quiz.TotalQuestions, &quiz.UserID, &quiz.Language, &quiz.CreatedAt, &quiz.PromptTemplateID, &quiz.DifficultyLevel, &quiz.TopicID, &quiz.Topic, &quiz.SubmittedAt, &quiz.TimeLimitSeconds, &quiz.JobID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching quiz: %w", err)
+	}
+	return quiz, nil
+}
+
+// ReplaceQuestion swaps the question with the given serial number in a quiz
+// for replacement, which takes over its ID and serial number. The old
+// version is kept as a revision and returned; attempts that answered it keep
+// pointing at that revision, so their scores stand. Returns ErrNotFound if
+// the quiz has no such question.
+func ReplaceQuestion(db *sql.DB, quizID, serial int, replacement *types.Question) (*types.Question, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The quiz is locked first, in the same order as SubmitQuiz, so that a
+	// submission grades either the old version or the new one.
+	if _, err := tx.Exec(`SELECT id FROM quizzes WHERE id = $1 FOR UPDATE`, quizID); err != nil {
+		return nil, fmt.Errorf("error locking quiz: %w", err)
+	}
+
+	var old types.Question
+	var options, correctAnswers, userAnswers []byte
+	err = tx.QueryRow(`
+		SELECT id, serial_number, type, question, options, correct_answer, correct_answers, case_sensitive, ignore_spaces,
+			COALESCE(user_answer, ''), user_answers, COALESCE(description, ''), COALESCE(source_excerpt, ''), time_limit_seconds
+		FROM questions
+		WHERE quiz_id = $1 AND serial_number = $2
+		FOR UPDATE
+	`, quizID, serial).Scan(
+		&old.ID, &old.SerialNumber, &old.Type, &old.Question, &options, &old.CorrectAnswer, &correctAnswers,
+		&old.CaseSensitive, &old.IgnoreSpaces, &old.UserAnswer, &userAnswers, &old.Description, &old.SourceExcerpt,
+		&old.TimeLimitSeconds,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching question: %w", err)
+	}
+	old.QuizID = quizID
+	if err := json.Unmarshal(options, &old.Options); err != nil {
+		return nil, fmt.Errorf("error unmarshalling options: %v", err)
+	}
+	if err := scanStrings(correctAnswers, &old.CorrectAnswers); err != nil {
+		return nil, fmt.Errorf("error unmarshalling correct answers: %v", err)
+	}
+	if err := scanStrings(userAnswers, &old.UserAnswers); err != nil {
+		return nil, fmt.Errorf("error unmarshalling user answers: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO question_revisions (
+			question_id, revision, type, question, options, correct_answer, correct_answers, case_sensitive, ignore_spaces,
+			user_answer, user_answers, description, source_excerpt, bank_question_id
+		)
+		SELECT id, (SELECT COALESCE(MAX(revision), 0) + 1 FROM question_revisions WHERE question_id = $1),
+			type, question, options, correct_answer, correct_answers, case_sensitive, ignore_spaces,
+			user_answer, user_answers, description, source_excerpt, bank_question_id
+		FROM questions
+		WHERE id = $1
+	`, old.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save question revision: %w", err)
+	}
+
+	if replacement.Options == nil {
+		replacement.Options = []string{}
+	}
+	optionsJSON, err := json.Marshal(replacement.Options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal options to JSON: %v", err)
+	}
+	correctAnswersJSON, err := stringsJSON(replacement.CorrectAnswers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal correct answers to JSON: %v", err)
+	}
+	replacement.ID, replacement.QuizID, replacement.SerialNumber = old.ID, quizID, serial
+	replacement.UserAnswer, replacement.UserAnswers = "", nil
+	replacement.TimeLimitSeconds = old.TimeLimitSeconds // the time limit is the quiz author's, not the generator's
+
+	_, err = tx.Exec(fmt.Sprintf(`
+		UPDATE questions SET
+			type = COALESCE(NULLIF($2, ''), 'mcq'), question = $3, options = $4, correct_answer = $5, correct_answers = $6,
+			case_sensitive = $7, ignore_spaces = $8, user_answer = '', user_answers = NULL, is_correct = NULL, description = $9,
+			source_excerpt = NULLIF($10, ''),
+			bank_question_id = (SELECT id FROM bank_questions WHERE normalized_text = %s)
+		WHERE id = $1
+	`, fmt.Sprintf(normalizedQuestionSQL, "$3::text")),
+		old.ID, replacement.Type, replacement.Question, optionsJSON, replacement.CorrectAnswer, correctAnswersJSON,
+		replacement.CaseSensitive, replacement.IgnoreSpaces, replacement.Description, replacement.SourceExcerpt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace question: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &old, nil
+}
+
+// FetchQuestionRevisions returns the earlier versions of the question with
+// the given serial number in a quiz, oldest first.
+func FetchQuestionRevisions(db *sql.DB, quizID, serial int) ([]types.QuestionRevision, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.revision, r.question_id, q.serial_number, r.type, r.question, r.options, r.correct_answer, r.correct_answers,
+			r.case_sensitive, r.ignore_spaces, COALESCE(r.user_answer, ''), r.user_answers, COALESCE(r.description, ''),
+			COALESCE(r.source_excerpt, ''), r.replaced_at
+		FROM question_revisions r
+		JOIN questions q ON q.id = r.question_id
+		WHERE q.quiz_id = $1 AND q.serial_number = $2
+		ORDER BY r.revision
+	`, quizID, serial)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching question revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []types.QuestionRevision{}
+	for rows.Next() {
+		var rev types.QuestionRevision
+		q := &rev.Question
+		var options, correctAnswers, userAnswers []byte
+		if err := rows.Scan(
+			&rev.ID, &rev.Revision, &q.ID, &q.SerialNumber, &q.Type, &q.Question, &options, &q.CorrectAnswer, &correctAnswers,
+			&q.CaseSensitive, &q.IgnoreSpaces, &q.UserAnswer, &userAnswers, &q.Description,
+			&q.SourceExcerpt, &rev.ReplacedAt,
+		); err != nil {
+			return nil, err
+		}
+		q.QuizID = quizID
+		if err := json.Unmarshal(options, &q.Options); err != nil {
+			return nil, fmt.Errorf("error unmarshalling options: %v", err)
+		}
+		if err := scanStrings(correctAnswers, &q.CorrectAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling correct answers: %v", err)
+		}
+		if err := scanStrings(userAnswers, &q.UserAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling user answers: %v", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
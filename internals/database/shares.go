@@ -0,0 +1,97 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreateQuizSharesTable creates the table of users quizzes are shared with.
+func CreateQuizSharesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS quiz_shares (
+		quiz_id INT NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		shared_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (quiz_id, user_id)
+	);
+	CREATE INDEX IF NOT EXISTS quiz_shares_user_idx ON quiz_shares (user_id);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create quiz shares table: %w", err)
+	}
+	return nil
+}
+
+// FetchViewableQuiz returns the quiz if it belongs to the user or is shared
+// with them, or ErrNotFound.
+func FetchViewableQuiz(db *sql.DB, quizID, userID int) (*types.Quiz, error) {
+	return fetchQuiz(db, `z.id = $1 AND (z.user_id = $2 OR EXISTS (SELECT 1 FROM quiz_shares s WHERE s.quiz_id = z.id AND s.user_id = $2))`, quizID, userID)
+}
+
+// ShareQuiz shares the quiz with the user with the given username or email.
+// Sharing it again is a no-op. Returns ErrNotFound if there is no such user
+// other than the quiz's owner.
+func ShareQuiz(db *sql.DB, quizID int, usernameOrEmail string) (*types.QuizShare, error) {
+	share := &types.QuizShare{}
+	err := db.QueryRow(`
+		WITH target AS (
+			SELECT u.id, u.username FROM users u
+			WHERE (u.username = $2 OR u.email = $2) AND u.id <> (SELECT user_id FROM quizzes WHERE id = $1)
+			LIMIT 1
+		), shared AS (
+			INSERT INTO quiz_shares (quiz_id, user_id)
+			SELECT $1, id FROM target
+			ON CONFLICT (quiz_id, user_id) DO UPDATE SET shared_at = quiz_shares.shared_at
+			RETURNING user_id, shared_at
+		)
+		SELECT s.user_id, t.username, s.shared_at FROM shared s JOIN target t ON t.id = s.user_id
+	`, quizID, usernameOrEmail).Scan(&share.UserID, &share.Username, &share.SharedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to share quiz: %w", err)
+	}
+	return share, nil
+}
+
+// FetchQuizShares lists the users the quiz is shared with, in the order it
+// was shared with them.
+func FetchQuizShares(db *sql.DB, quizID int) ([]types.QuizShare, error) {
+	rows, err := db.Query(`
+		SELECT s.user_id, u.username, s.shared_at
+		FROM quiz_shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.quiz_id = $1
+		ORDER BY s.shared_at, s.user_id
+	`, quizID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching quiz shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := []types.QuizShare{}
+	for rows.Next() {
+		var share types.QuizShare
+		if err := rows.Scan(&share.UserID, &share.Username, &share.SharedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// DeleteQuizShare stops sharing the quiz with the user, or returns
+// ErrNotFound if it wasn't shared with them.
+func DeleteQuizShare(db *sql.DB, quizID int, userID int64) error {
+	result, err := db.Exec(`DELETE FROM quiz_shares WHERE quiz_id = $1 AND user_id = $2`, quizID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unshare quiz: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
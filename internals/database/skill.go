@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreateSkillTables creates the per-topic skill ratings of users and the
+// difficulty_level column of adaptive quizzes.
+func CreateSkillTables(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS skill_ratings (
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		topic TEXT NOT NULL,
+		rating DOUBLE PRECISION NOT NULL,
+		quizzes INT NOT NULL DEFAULT 0,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, topic)
+	);
+	ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS difficulty_level INT;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create skill tables: %w", err)
+	}
+	return nil
+}
+
+// overallRatingSQL is the user's ($1) mean rating over all topics weighted by
+// quizzes, or $2 if they have none.
+const overallRatingSQL = `SELECT COALESCE(SUM(rating * quizzes) / NULLIF(SUM(quizzes), 0), $2) FROM skill_ratings WHERE user_id = $1`
+
+// FetchSkillRating returns the user's rating on a topic and the number of
+// quizzes it is based on. For a topic they haven't been rated on it returns
+// their overall rating (or initial if they have none) and 0.
+func FetchSkillRating(db *sql.DB, userID int64, topic string, initial float64) (float64, int, error) {
+	var rating float64
+	var quizzes int
+	err := db.QueryRow(`SELECT rating, quizzes FROM skill_ratings WHERE user_id = $1 AND topic = $2`, userID, topic).Scan(&rating, &quizzes)
+	if err == sql.ErrNoRows {
+		if err := db.QueryRow(overallRatingSQL, userID, initial).Scan(&rating); err != nil {
+			return 0, 0, fmt.Errorf("error fetching overall rating: %w", err)
+		}
+		return rating, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("error fetching skill rating: %w", err)
+	}
+	return rating, quizzes, nil
+}
+
+// UpdateSkillRating replaces the user's rating on a topic with
+// update(rating, quizzes) and counts one more quiz. A topic without a rating
+// starts from the user's overall rating, or initial. Concurrent updates of
+// the same rating are serialized.
+func UpdateSkillRating(db *sql.DB, userID int64, topic string, initial float64, update func(rating float64, quizzes int) float64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rating float64
+	var quizzes int
+	err = tx.QueryRow(`SELECT rating, quizzes FROM skill_ratings WHERE user_id = $1 AND topic = $2 FOR UPDATE`, userID, topic).Scan(&rating, &quizzes)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow(overallRatingSQL, userID, initial).Scan(&rating)
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching skill rating: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO skill_ratings (user_id, topic, rating, quizzes, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, topic) DO UPDATE SET rating = EXCLUDED.rating, quizzes = EXCLUDED.quizzes, updated_at = NOW()
+	`, userID, topic, update(rating, quizzes), quizzes+1)
+	if err != nil {
+		return fmt.Errorf("failed to update skill rating: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RenameSkillTopic moves ratings from topic oldTopic to newTopic, except for
+// users who already have a rating on newTopic.
+func RenameSkillTopic(db *sql.DB, oldTopic, newTopic string) error {
+	query := `
+		UPDATE skill_ratings s SET topic = $2
+		WHERE s.topic = $1
+		AND NOT EXISTS (SELECT 1 FROM skill_ratings o WHERE o.user_id = s.user_id AND o.topic = $2)
+	`
+	if _, err := db.Exec(query, oldTopic, newTopic); err != nil {
+		return fmt.Errorf("failed to rename skill topic: %w", err)
+	}
+	return nil
+}
+
+// FetchSkillRatings returns all of the user's topic ratings, most practised
+// first. Only Topic, Rating, Quizzes and UpdatedAt are set.
+func FetchSkillRatings(db *sql.DB, userID int64) ([]types.SkillEstimate, error) {
+	rows, err := db.Query(`SELECT topic, rating, quizzes, updated_at FROM skill_ratings WHERE user_id = $1 ORDER BY quizzes DESC, updated_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching skill ratings: %w", err)
+	}
+	defer rows.Close()
+
+	skills := []types.SkillEstimate{}
+	for rows.Next() {
+		var s types.SkillEstimate
+		if err := rows.Scan(&s.Topic, &s.Rating, &s.Quizzes, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		skills = append(skills, s)
+	}
+	return skills, rows.Err()
+}
+
+// FetchQuizTotals returns how many quizzes and questions the user has taken
+// and their mean score rate, counting the first attempt at each submitted
+// quiz only.
+func FetchQuizTotals(db *sql.DB, userID int64) (quizzes int, questions int, avgScoreRate float64, err error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(total_questions), 0), COALESCE(AVG(score::FLOAT / NULLIF(total_questions, 0)), 0)
+		FROM quiz_attempts
+		WHERE user_id = $1 AND finished_at IS NOT NULL AND NOT is_retake
+	`
+	if err := db.QueryRow(query, userID).Scan(&quizzes, &questions, &avgScoreRate); err != nil {
+		return 0, 0, 0, fmt.Errorf("error fetching quiz totals: %w", err)
+	}
+	return quizzes, questions, avgScoreRate, nil
+}
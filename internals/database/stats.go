@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// finishedAttemptsSQL joins the user's ($1) finished first attempts with
+// their quizzes and canonical topics. Retakes are left out: their answer
+// keys had already been shown.
+const finishedAttemptsSQL = `
+	FROM quiz_attempts a
+	JOIN quizzes z ON z.id = a.quiz_id
+	LEFT JOIN topics t ON t.id = z.topic_id
+	WHERE a.user_id = $1 AND a.finished_at IS NOT NULL AND NOT a.is_retake`
+
+// accuracyColumns aggregate the attempts of a group.
+const accuracyColumns = `COUNT(*), COALESCE(SUM(a.total_questions), 0), COALESCE(SUM(a.score), 0)`
+
+// FetchAccuracyByTopic returns the user's attempts, questions and correct
+// answers on each topic: the quiz's canonical topic, or its name if it has
+// none. Accuracy is left for the caller, which may merge topics.
+func FetchAccuracyByTopic(db *sql.DB, userID int64) ([]types.AccuracyStat, error) {
+	return fetchAccuracy(db, `SELECT COALESCE(t.name, z.quiz_name), `+accuracyColumns+finishedAttemptsSQL+`
+		GROUP BY 1 ORDER BY 1`, userID)
+}
+
+// FetchAccuracyByLevel returns the user's attempts, questions and correct
+// answers on each quiz level. Accuracy is left for the caller.
+func FetchAccuracyByLevel(db *sql.DB, userID int64) ([]types.AccuracyStat, error) {
+	return fetchAccuracy(db, `SELECT LOWER(z.level), `+accuracyColumns+finishedAttemptsSQL+`
+		GROUP BY 1 ORDER BY 1`, userID)
+}
+
+func fetchAccuracy(db *sql.DB, query string, userID int64) ([]types.AccuracyStat, error) {
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching accuracy: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []types.AccuracyStat{}
+	for rows.Next() {
+		var s types.AccuracyStat
+		if err := rows.Scan(&s.Name, &s.Attempts, &s.Questions, &s.Correct); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// FetchScoreTrend returns the user's attempts, questions and correct answers
+// per bucket of time they were finished in, oldest first. bucket is a
+// date_trunc field: "day", "week" or "month". Accuracy is left for the
+// caller.
+func FetchScoreTrend(db *sql.DB, userID int64, bucket string) ([]types.TrendPoint, error) {
+	rows, err := db.Query(`SELECT date_trunc($2, a.finished_at), `+accuracyColumns+finishedAttemptsSQL+`
+		GROUP BY 1 ORDER BY 1`, userID, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching score trend: %w", err)
+	}
+	defer rows.Close()
+
+	trend := []types.TrendPoint{}
+	for rows.Next() {
+		var p types.TrendPoint
+		if err := rows.Scan(&p.Start, &p.Attempts, &p.Questions, &p.Correct); err != nil {
+			return nil, err
+		}
+		trend = append(trend, p)
+	}
+	return trend, rows.Err()
+}
+
+// FetchAnswerTotals returns how many questions the user answered in finished
+// first attempts, and the mean time they took per question on timed ones,
+// nil if they have none. Time past the deadline doesn't count.
+func FetchAnswerTotals(db *sql.DB, userID int64) (answered int, avgSecondsPerQuestion *float64, err error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*)
+			FROM attempt_answers x
+			JOIN quiz_attempts a ON a.id = x.attempt_id
+			WHERE a.user_id = $1 AND a.finished_at IS NOT NULL AND NOT a.is_retake AND (x.user_answer <> '' OR x.user_answers IS NOT NULL)),
+			(SELECT AVG(EXTRACT(EPOCH FROM LEAST(a.finished_at, a.deadline) - a.started_at) / a.total_questions)
+			FROM quiz_attempts a
+			WHERE a.user_id = $1 AND a.finished_at IS NOT NULL AND NOT a.is_retake AND a.deadline IS NOT NULL AND a.total_questions > 0)
+	`
+	if err := db.QueryRow(query, userID).Scan(&answered, &avgSecondsPerQuestion); err != nil {
+		return 0, nil, fmt.Errorf("error fetching answer totals: %w", err)
+	}
+	return answered, avgSecondsPerQuestion, nil
+}
@@ -0,0 +1,352 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+var (
+	// ErrInvalidAnswer is wrapped when a submission doesn't fit the quiz's
+	// questions.
+	ErrInvalidAnswer = errors.New("invalid answer")
+	// ErrNoOpenAttempt is returned when a timed quiz is submitted without an
+	// attempt having been started, so there is no start to time it from.
+	ErrNoOpenAttempt = errors.New("no attempt in progress")
+	// ErrDeadlinePassed is returned when answers arrive too late and late
+	// answers are rejected.
+	ErrDeadlinePassed = errors.New("attempt deadline has passed")
+	// ErrQuestionTimeUp is wrapped when an answer is saved after its
+	// question's own time limit.
+	ErrQuestionTimeUp = errors.New("question time limit has passed")
+)
+
+// LateSubmission is how SubmitQuiz treats answers that arrive more than
+// Grace after their attempt's deadline. Either way they are dropped and the
+// attempt is graded with the answers saved before; with GradeSaved that is
+// the result, otherwise ErrDeadlinePassed is returned with it.
+type LateSubmission struct {
+	Grace      time.Duration
+	GradeSaved bool
+}
+
+// AddSubmissionColumns adds the columns that hold the server's grading of a
+// quiz: whether each answer was correct and when the quiz was submitted.
+// Quizzes saved before then were scored by the client when they were
+// created, so they count as submitted at creation.
+func AddSubmissionColumns(db *sql.DB) error {
+	query := `
+	ALTER TABLE questions ADD COLUMN IF NOT EXISTS is_correct BOOLEAN;
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns WHERE table_name = 'quizzes' AND column_name = 'submitted_at'
+		) THEN
+			ALTER TABLE quizzes ADD COLUMN submitted_at TIMESTAMPTZ;
+			UPDATE quizzes SET submitted_at = created_at;
+		END IF;
+	END $$;
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add submission columns: %w", err)
+	}
+	return nil
+}
+
+// SubmitQuiz grades the user's answers to one of their quizzes with
+// isCorrect and finishes the quiz's open attempt with them, or a new attempt
+// if none is open and the quiz is untimed. Answers saved to the attempt
+// earlier count unless they are answered again; answers to timed questions
+// the attempt never moved to, or moved to more than the question's limit
+// plus late.Grace ago, are voided. The answers, their
+// correctness and the score are stored in one transaction, and the quiz's
+// score becomes the attempt's. Questions without an answer count as wrong.
+// Returns ErrNotFound if the quiz isn't the user's, ErrInvalidAnswer for
+// answers to questions the quiz doesn't have, ErrNoOpenAttempt for a timed
+// quiz, or one with timed questions, without an open attempt, and
+// ErrDeadlinePassed as late dictates.
+func SubmitQuiz(db *sql.DB, quizID, userID int, answers []types.Answer, isCorrect func(types.Question) bool, late LateSubmission) (*types.GradedQuiz, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var timed bool
+	err = tx.QueryRow(fmt.Sprintf(`
+		SELECT %s IS NOT NULL OR EXISTS (SELECT 1 FROM questions WHERE quiz_id = $1 AND time_limit_seconds IS NOT NULL)
+		FROM quizzes WHERE id = $1 AND user_id = $2 FOR UPDATE
+	`, fmt.Sprintf(attemptLimitSQL, "$1")), quizID, userID).Scan(&timed)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching quiz: %w", err)
+	}
+
+	attempt, expired, err := lockOpenAttempt(tx, quizID, late.Grace)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		if timed {
+			return nil, ErrNoOpenAttempt
+		}
+		attempt = &types.Attempt{}
+		err = scanAttempt(tx.QueryRow(`
+			INSERT INTO quiz_attempts (quiz_id, user_id, total_questions) VALUES ($1, $2, (SELECT COUNT(*) FROM questions WHERE quiz_id = $1))
+			RETURNING `+attemptColumns, quizID, userID), attempt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start attempt: %w", err)
+		}
+	}
+	var voided []int
+	if expired {
+		answers = nil
+	} else {
+		closed, err := closedQuestions(tx, quizID, attempt.ID, late.Grace)
+		if err != nil {
+			return nil, err
+		}
+		kept := make([]types.Answer, 0, len(answers))
+		for _, answer := range answers {
+			if _, ok := closed[answer.SerialNumber]; ok {
+				voided = append(voided, answer.SerialNumber)
+				continue
+			}
+			kept = append(kept, answer)
+		}
+		answers = kept
+	}
+
+	graded, err := finishAttempt(tx, attempt, answers, isCorrect)
+	if err != nil {
+		return nil, err
+	}
+	graded.Late = expired
+	graded.Voided = voided
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if expired && !late.GradeSaved {
+		return graded, ErrDeadlinePassed
+	}
+	return graded, nil
+}
+
+// CloseExpiredAttempt finishes the quiz's open attempt if it is more than
+// grace past its deadline, grading the answers saved to it with isCorrect.
+// It returns the grading, or nil if no attempt was closed.
+func CloseExpiredAttempt(db *sql.DB, quizID int, grace time.Duration, isCorrect func(types.Question) bool) (*types.GradedQuiz, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM quizzes WHERE id = $1 FOR UPDATE`, quizID); err != nil {
+		return nil, fmt.Errorf("error locking quiz: %w", err)
+	}
+	attempt, expired, err := lockOpenAttempt(tx, quizID, grace)
+	if err != nil || !expired {
+		return nil, err
+	}
+	graded, err := finishAttempt(tx, attempt, nil, isCorrect)
+	if err != nil {
+		return nil, err
+	}
+	graded.Late = true
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return graded, nil
+}
+
+// lockOpenAttempt returns the quiz's open attempt, locked, and whether it is
+// more than grace past its deadline; nil if no attempt is open.
+func lockOpenAttempt(tx *sql.Tx, quizID int, grace time.Duration) (*types.Attempt, bool, error) {
+	var attempt types.Attempt
+	var expired bool
+	err := scanAttempt(tx.QueryRow(`
+		SELECT `+attemptColumns+`, deadline IS NOT NULL AND NOW() > deadline + make_interval(secs => $2)
+		FROM quiz_attempts
+		WHERE quiz_id = $1 AND finished_at IS NULL
+		FOR UPDATE
+	`, quizID, grace.Seconds()), &attempt, &expired)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching open attempt: %w", err)
+	}
+	return &attempt, expired, nil
+}
+
+// finishAttempt grades an open attempt with the answers saved to it,
+// overridden by answers, and stores the result on the attempt and its quiz,
+// both of which the caller has locked. Missed questions go to the user's
+// review queue.
+func finishAttempt(tx *sql.Tx, attempt *types.Attempt, answers []types.Answer, isCorrect func(types.Question) bool) (*types.GradedQuiz, error) {
+	questions, err := lockQuestions(tx, attempt.QuizID)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("%w: the quiz has no questions", ErrInvalidAnswer)
+	}
+	if err := loadSavedAnswers(tx, attempt.ID, questions); err != nil {
+		return nil, err
+	}
+
+	bySerial := make(map[int]*types.Question, len(questions))
+	for i := range questions {
+		bySerial[questions[i].SerialNumber] = &questions[i]
+	}
+	answered := make(map[int]bool, len(answers))
+	for _, answer := range answers {
+		q, ok := bySerial[answer.SerialNumber]
+		if !ok {
+			return nil, fmt.Errorf("%w: the quiz has no question %d", ErrInvalidAnswer, answer.SerialNumber)
+		}
+		if answered[answer.SerialNumber] {
+			return nil, fmt.Errorf("%w: question %d is answered twice", ErrInvalidAnswer, answer.SerialNumber)
+		}
+		answered[answer.SerialNumber] = true
+		setAnswer(q, answer)
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO attempt_answers (attempt_id, question_id, version, user_answer, user_answers, is_correct)
+		VALUES ($1, $2, %s, $3, $4, $5)
+		ON CONFLICT (attempt_id, question_id) DO UPDATE SET
+			version = EXCLUDED.version, user_answer = EXCLUDED.user_answer, user_answers = EXCLUDED.user_answers,
+			is_correct = EXCLUDED.is_correct, answered_at = NOW()
+	`, fmt.Sprintf(questionVersionSQL, "$2")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	graded := &types.GradedQuiz{
+		QuizID:         attempt.QuizID,
+		AttemptID:      attempt.ID,
+		TotalQuestions: len(questions),
+		StartedAt:      attempt.StartedAt,
+		Questions:      make([]types.QuestionView, 0, len(questions)),
+	}
+	for _, q := range questions {
+		correct := isCorrect(q)
+		if correct {
+			graded.Score++
+		}
+		userAnswersJSON, err := stringsJSON(q.UserAnswers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal user answers to JSON: %v", err)
+		}
+		if _, err := stmt.Exec(attempt.ID, q.ID, q.UserAnswer, userAnswersJSON, correct); err != nil {
+			return nil, fmt.Errorf("failed to save answer: %w", err)
+		}
+		graded.Questions = append(graded.Questions, q.View(true, &correct))
+	}
+
+	if err := seedReviewCards(tx, attempt.ID); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(`
+		UPDATE quiz_attempts SET finished_at = NOW(), score = $2, total_questions = $3,
+			is_retake = EXISTS (SELECT 1 FROM quiz_attempts WHERE quiz_id = $4 AND finished_at IS NOT NULL AND id <> $1)
+		WHERE id = $1
+		RETURNING finished_at, is_retake
+	`, attempt.ID, graded.Score, graded.TotalQuestions, attempt.QuizID).Scan(&graded.SubmittedAt, &graded.Retake)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish attempt: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE quizzes SET score = $2, totalQuestions = $3, submitted_at = $4 WHERE id = $1`,
+		attempt.QuizID, graded.Score, graded.TotalQuestions, graded.SubmittedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save score: %w", err)
+	}
+	return graded, nil
+}
+
+// setAnswer puts answer on q in the field its type is graded from.
+func setAnswer(q *types.Question, answer types.Answer) {
+	switch q.Type {
+	case types.QuestionMultiSelect, types.QuestionOrdering:
+		q.UserAnswer, q.UserAnswers = "", answer.Answers
+	default:
+		q.UserAnswer, q.UserAnswers = answer.Answer, nil
+	}
+}
+
+// loadSavedAnswers puts the answers saved to the attempt on questions,
+// skipping answers to versions of a question that have since been replaced.
+func loadSavedAnswers(tx *sql.Tx, attemptID int64, questions []types.Question) error {
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT a.question_id, a.user_answer, a.user_answers
+		FROM attempt_answers a
+		WHERE a.attempt_id = $1 AND a.version = %s
+	`, fmt.Sprintf(questionVersionSQL, "a.question_id")), attemptID)
+	if err != nil {
+		return fmt.Errorf("error fetching saved answers: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*types.Question, len(questions))
+	for i := range questions {
+		byID[questions[i].ID] = &questions[i]
+	}
+	for rows.Next() {
+		var questionID int
+		var answer types.Answer
+		var userAnswers []byte
+		if err := rows.Scan(&questionID, &answer.Answer, &userAnswers); err != nil {
+			return err
+		}
+		if err := scanStrings(userAnswers, &answer.Answers); err != nil {
+			return fmt.Errorf("error unmarshalling user answers: %v", err)
+		}
+		if q, ok := byID[questionID]; ok {
+			setAnswer(q, answer)
+		}
+	}
+	return rows.Err()
+}
+
+// lockQuestions reads a quiz's questions for grading, in serial order, and
+// locks them for the rest of the transaction. User answers are left empty.
+func lockQuestions(tx *sql.Tx, quizID int) ([]types.Question, error) {
+	rows, err := tx.Query(`
+		SELECT id, serial_number, type, question, options, correct_answer, correct_answers, case_sensitive, ignore_spaces, COALESCE(description, '')
+		FROM questions
+		WHERE quiz_id = $1
+		ORDER BY serial_number
+		FOR UPDATE
+	`, quizID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []types.Question
+	for rows.Next() {
+		q := types.Question{QuizID: quizID}
+		var options, correctAnswers []byte
+		if err := rows.Scan(&q.ID, &q.SerialNumber, &q.Type, &q.Question, &options, &q.CorrectAnswer, &correctAnswers, &q.CaseSensitive, &q.IgnoreSpaces, &q.Description); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(options, &q.Options); err != nil {
+			return nil, fmt.Errorf("error unmarshalling options: %v", err)
+		}
+		if err := scanStrings(correctAnswers, &q.CorrectAnswers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling correct answers: %v", err)
+		}
+		questions = append(questions, q)
+	}
+	return questions, rows.Err()
+}
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// ErrTopicCycle is returned when a topic would become its own ancestor.
+var ErrTopicCycle = errors.New("a topic can't be moved under itself or its subtopics")
+
+// CreateTopicsTables creates the topic taxonomy and links quizzes to it.
+// topic_aliases is keyed by the normalized form of the alias, so an alias
+// can only name one topic.
+func CreateTopicsTables(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS topics (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		parent_id INT REFERENCES topics(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS topic_aliases (
+		key TEXT PRIMARY KEY,
+		alias TEXT NOT NULL,
+		topic_id INT NOT NULL REFERENCES topics(id) ON DELETE CASCADE
+	);
+	ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS topic_id INT REFERENCES topics(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS quizzes_topic_id_idx ON quizzes (topic_id);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create topics tables: %w", err)
+	}
+	return nil
+}
+
+// FetchTopics returns every topic with its aliases.
+func FetchTopics(ctx context.Context, db *sql.DB) ([]types.Topic, error) {
+	query := `
+		SELECT t.id, t.name, t.parent_id, t.created_at,
+			COALESCE(json_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '[]')
+		FROM topics t
+		LEFT JOIN topic_aliases a ON a.topic_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching topics: %w", err)
+	}
+	defer rows.Close()
+
+	topics := []types.Topic{}
+	for rows.Next() {
+		var topic types.Topic
+		var aliases []byte
+		if err := rows.Scan(&topic.ID, &topic.Name, &topic.ParentID, &topic.CreatedAt, &aliases); err != nil {
+			return nil, err
+		}
+		if err := scanStrings(aliases, &topic.Aliases); err != nil {
+			return nil, fmt.Errorf("error unmarshalling aliases: %v", err)
+		}
+		topics = append(topics, topic)
+	}
+	return topics, rows.Err()
+}
+
+// InsertTopic saves a new topic, setting topic.ID and topic.CreatedAt.
+// Aliases are added separately with InsertTopicAlias.
+func InsertTopic(db *sql.DB, topic *types.Topic) error {
+	query := `INSERT INTO topics (name, parent_id) VALUES ($1, $2) RETURNING id, created_at`
+	if err := db.QueryRow(query, topic.Name, topic.ParentID).Scan(&topic.ID, &topic.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert topic: %w", err)
+	}
+	return nil
+}
+
+// UpdateTopic renames a topic and moves it under parentID (nil for a root
+// topic). It returns ErrNotFound for an unknown topic or parent and
+// ErrTopicCycle if the parent is the topic itself or one of its descendants.
+func UpdateTopic(db *sql.DB, id int64, name string, parentID *int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if parentID != nil {
+		var exists, cycle bool
+		err := tx.QueryRow(`
+			WITH RECURSIVE descendants AS (
+				SELECT id FROM topics WHERE id = $1
+				UNION
+				SELECT t.id FROM topics t JOIN descendants d ON t.parent_id = d.id
+			)
+			SELECT EXISTS (SELECT 1 FROM topics WHERE id = $2), EXISTS (SELECT 1 FROM descendants WHERE id = $2)
+		`, id, *parentID).Scan(&exists, &cycle)
+		if err != nil {
+			return fmt.Errorf("error checking topic parent: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		if cycle {
+			return ErrTopicCycle
+		}
+	}
+
+	result, err := tx.Exec(`UPDATE topics SET name = $2, parent_id = $3 WHERE id = $1`, id, name, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to update topic: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+// InsertTopicAlias makes alias, normalized to key, resolve to the topic. An
+// alias that already names a topic is moved to this one.
+func InsertTopicAlias(db *sql.DB, topicID int64, alias, key string) error {
+	query := `
+		INSERT INTO topic_aliases (key, alias, topic_id) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET alias = EXCLUDED.alias, topic_id = EXCLUDED.topic_id
+	`
+	if _, err := db.Exec(query, key, alias, topicID); err != nil {
+		return fmt.Errorf("failed to insert topic alias: %w", err)
+	}
+	return nil
+}
+
+// DeleteTopicAlias removes an alias of the topic, returning ErrNotFound if it
+// has no such alias.
+func DeleteTopicAlias(db *sql.DB, topicID int64, key string) error {
+	result, err := db.Exec(`DELETE FROM topic_aliases WHERE topic_id = $1 AND key = $2`, topicID, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete topic alias: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// FetchUnresolvedTopics returns the quiz names without a topic, most
+// frequent first.
+func FetchUnresolvedTopics(ctx context.Context, db *sql.DB, limit int) ([]types.UnresolvedTopic, error) {
+	query := `
+		SELECT quiz_name, COUNT(*) FROM quizzes
+		WHERE topic_id IS NULL
+		GROUP BY quiz_name
+		ORDER BY COUNT(*) DESC, quiz_name
+		LIMIT $1
+	`
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching unresolved topics: %w", err)
+	}
+	defer rows.Close()
+
+	unresolved := []types.UnresolvedTopic{}
+	for rows.Next() {
+		var u types.UnresolvedTopic
+		if err := rows.Scan(&u.QuizName, &u.Quizzes); err != nil {
+			return nil, err
+		}
+		unresolved = append(unresolved, u)
+	}
+	return unresolved, rows.Err()
+}
+
+// LinkQuizzesToTopic sets the topic of the quizzes named quizName that have
+// none yet, returning how many were linked.
+func LinkQuizzesToTopic(ctx context.Context, db *sql.DB, quizName string, topicID int64) (int64, error) {
+	result, err := db.ExecContext(ctx, `UPDATE quizzes SET topic_id = $2 WHERE quiz_name = $1 AND topic_id IS NULL`, quizName, topicID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to link quizzes to topic: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// CreateUsageTable creates the ledger of quiz generations that quotas are
+// enforced against. Timestamps are TIMESTAMPTZ so that period boundaries
+// computed in UTC compare correctly whatever the session time zone.
+func CreateUsageTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS generation_usage (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		job_id INT REFERENCES quiz_jobs(id) ON DELETE SET NULL,
+		provider TEXT NOT NULL DEFAULT '',
+		prompt_tokens INT NOT NULL DEFAULT 0,
+		response_tokens INT NOT NULL DEFAULT 0,
+		latency_ms BIGINT NOT NULL DEFAULT 0,
+		outcome VARCHAR(20) NOT NULL,
+		error TEXT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS generation_usage_user_created_idx ON generation_usage (user_id, created_at);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create generation_usage table: %w", err)
+	}
+	return nil
+}
+
+func InsertUsageRecord(db *sql.DB, record *types.UsageRecord) error {
+	query := `
+		INSERT INTO generation_usage (user_id, job_id, provider, prompt_tokens, response_tokens, latency_ms, outcome, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id, created_at
+	`
+	err := db.QueryRow(query, record.UserID, record.JobID, record.Provider, record.PromptTokens, record.ResponseTokens,
+		record.LatencyMs, record.Outcome, record.Error).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert usage record: %w", err)
+	}
+	return nil
+}
+
+// usageLockClass namespaces the advisory locks taken by LockUsage.
+const usageLockClass = 0x7175
+
+// LockUsage serializes quota checks of the user until tx ends, so that a
+// check and the row that counts the generation it allows are one step.
+func LockUsage(tx *sql.Tx, userID int64) error {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, usageLockClass, userID); err != nil {
+		return fmt.Errorf("failed to lock usage: %w", err)
+	}
+	return nil
+}
+
+// InsertPendingUsage reserves a ledger entry for a generation that is about
+// to start. FinishUsageRecord fills it in once the generation is over.
+func InsertPendingUsage(tx *sql.Tx, userID int64) (int64, error) {
+	var id int64
+	query := `INSERT INTO generation_usage (user_id, outcome) VALUES ($1, $2) RETURNING id`
+	if err := tx.QueryRow(query, userID, types.UsagePending).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to reserve usage record: %w", err)
+	}
+	return id, nil
+}
+
+// FinishUsageRecord fills in the pending ledger entry id. It keeps the
+// entry's creation time, so the generation counts in the period it started.
+func FinishUsageRecord(db *sql.DB, id int64, record *types.UsageRecord) error {
+	query := `
+		UPDATE generation_usage
+		SET job_id = $2, provider = $3, prompt_tokens = $4, response_tokens = $5, latency_ms = $6, outcome = $7, error = NULLIF($8, '')
+		WHERE id = $1
+		RETURNING id, created_at
+	`
+	err := db.QueryRow(query, id, record.JobID, record.Provider, record.PromptTokens, record.ResponseTokens,
+		record.LatencyMs, record.Outcome, record.Error).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to finish usage record %d: %w", id, err)
+	}
+	return nil
+}
+
+// FetchUsageSince returns how many generations of the user succeeded since
+// the given time and how many tokens all of their generations used, failed
+// ones included.
+func FetchUsageSince(db *sql.DB, userID int64, since time.Time) (generations int, tokens int, err error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE outcome = 'succeeded'), COALESCE(SUM(prompt_tokens + response_tokens), 0)
+		FROM generation_usage
+		WHERE user_id = $1 AND created_at >= $2
+	`
+	if err := db.QueryRow(query, userID, since).Scan(&generations, &tokens); err != nil {
+		return 0, 0, fmt.Errorf("error fetching usage: %w", err)
+	}
+	return generations, tokens, nil
+}
+
+// CountPendingGenerations returns how many of the user's generations are
+// under way: jobs that are queued or running and reserved ledger entries.
+// An entry still pending after an hour was left by a server that stopped
+// mid-generation and no longer counts.
+func CountPendingGenerations(db *sql.DB, userID int64) (int, error) {
+	var n int
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM quiz_jobs WHERE user_id = $1 AND status IN ('queued', 'running')) +
+			(SELECT COUNT(*) FROM generation_usage WHERE user_id = $1 AND outcome = $2 AND created_at > NOW() - INTERVAL '1 hour')
+	`
+	if err := db.QueryRow(query, userID, types.UsagePending).Scan(&n); err != nil {
+		return 0, fmt.Errorf("error counting pending generations: %w", err)
+	}
+	return n, nil
+}
+
+// FetchRecentUsage returns the user's latest ledger entries, newest first.
+func FetchRecentUsage(db *sql.DB, userID int64, limit int) ([]types.UsageRecord, error) {
+	query := `
+		SELECT id, user_id, job_id, provider, prompt_tokens, response_tokens, latency_ms, outcome, COALESCE(error, ''), created_at
+		FROM generation_usage
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := db.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching usage records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []types.UsageRecord{}
+	for rows.Next() {
+		var record types.UsageRecord
+		if err := rows.Scan(&record.ID, &record.UserID, &record.JobID, &record.Provider, &record.PromptTokens, &record.ResponseTokens,
+			&record.LatencyMs, &record.Outcome, &record.Error, &record.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,127 @@
+package generateQuiz
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Circuit breaker states
+const (
+	breakerClosed   = "closed"    // calls go through
+	breakerOpen     = "open"      // calls are skipped until the cooldown ends
+	breakerHalfOpen = "half_open" // one trial call decides whether to close again
+)
+
+// breaker is a consecutive-failure circuit breaker. After threshold failures
+// in a row it opens and the provider is skipped for cooldown; then a single
+// trial call is let through, which closes the breaker if it succeeds and
+// reopens it if it fails.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	state       string
+	consecutive int
+	openedAt    time.Time
+	probing     bool
+
+	requests    int64
+	failures    int64
+	lastError   string
+	lastFailure time.Time
+	lastSuccess time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// allow reports whether a call may be made now. Every allowed call must be
+// followed by success, failure or release.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = false
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	b.requests++
+	return true
+}
+
+func (b *breaker) success(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.consecutive = 0
+	b.probing = false
+	b.lastSuccess = now
+}
+
+func (b *breaker) failure(now time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutive++
+	b.failures++
+	b.lastError = err.Error()
+	b.lastFailure = now
+	if b.state == breakerHalfOpen || b.consecutive >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+	b.probing = false
+}
+
+// release ends a call that says nothing about the provider's health, such as
+// one cancelled by the client.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) health(name string) types.ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := types.ProviderHealth{
+		Name:                name,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutive,
+		Requests:            b.requests,
+		Failures:            b.failures,
+		LastError:           b.lastError,
+	}
+	if !b.lastFailure.IsZero() {
+		t := b.lastFailure
+		h.LastFailureAt = &t
+	}
+	if !b.lastSuccess.IsZero() {
+		t := b.lastSuccess
+		h.LastSuccessAt = &t
+	}
+	if b.state == breakerOpen {
+		t := b.openedAt.Add(b.cooldown)
+		h.RetryAt = &t
+	}
+	return h
+}
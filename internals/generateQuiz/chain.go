@@ -0,0 +1,294 @@
+package generateQuiz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// ErrUnavailable is returned when every provider in a Chain failed or was
+// skipped by its circuit breaker. Callers should ask the client to retry
+// later.
+var ErrUnavailable = errors.New("all quiz providers are unavailable")
+
+// errNothingToDraw is returned by a SourceGenerator that has no questions for
+// the request. It moves on to the next provider without counting against the
+// source's health.
+var errNothingToDraw = errors.New("no stored questions to draw")
+
+// StatusError is a failed call to a model API, with the HTTP status it
+// answered with (0 if unknown).
+type StatusError struct {
+	Status  int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Status == 0 {
+		return "quiz provider error: " + e.Message
+	}
+	return fmt.Sprintf("quiz provider error (status %d): %s", e.Status, e.Message)
+}
+
+// Provider is one entry of a Chain.
+type Provider struct {
+	Name      string
+	Generator QuizGenerator
+	// Timeout bounds a single call; zero means no limit beyond the caller's.
+	Timeout time.Duration
+}
+
+type ChainConfig struct {
+	// Retries is how often a provider is called again after a transient
+	// failure (429, 5xx, timeout) before the chain moves on.
+	Retries int
+	// Backoff is the delay before the first retry. It doubles with every
+	// retry up to MaxBackoff, and a random half of it is jitter.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// A provider's breaker opens after FailureThreshold failures in a row and
+	// stays open for Cooldown.
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+type chainProvider struct {
+	Provider
+	breaker *breaker
+}
+
+// Chain tries its providers in order until one of them produces questions.
+// Each provider has its own circuit breaker, so one that keeps failing is
+// skipped instead of making every request wait for it to time out. A refusal
+// is an answer, not a failure, and is returned without trying further
+// providers.
+type Chain struct {
+	cfg       ChainConfig
+	providers []*chainProvider
+	// sleep waits between retries; replaced in tests to avoid real delays.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewChain(cfg ChainConfig, providers ...Provider) *Chain {
+	c := &Chain{cfg: cfg, sleep: sleepContext}
+	for _, p := range providers {
+		c.providers = append(c.providers, &chainProvider{Provider: p, breaker: newBreaker(cfg.FailureThreshold, cfg.Cooldown)})
+	}
+	return c
+}
+
+func (c *Chain) Generate(ctx context.Context, quizRequest *types.QuizRequest) ([]types.Question, error) {
+	var questions []types.Question
+	err := c.run(ctx, func(ctx context.Context, g QuizGenerator) (bool, error) {
+		var err error
+		questions, err = g.Generate(ctx, quizRequest)
+		return false, err
+	})
+	return questions, err
+}
+
+// GenerateStream streams from the first provider that works. Once a provider
+// has emitted questions its failure is returned as is, since falling back
+// would start the quiz over; the Checker tops up what is missing.
+func (c *Chain) GenerateStream(ctx context.Context, quizRequest *types.QuizRequest, emit func(types.Question) error) error {
+	return c.run(ctx, func(ctx context.Context, g QuizGenerator) (bool, error) {
+		emitted := 0
+		var emitErr error
+		err := Stream(ctx, g, quizRequest, func(q types.Question) error {
+			if err := emit(q); err != nil {
+				emitErr = err
+				return err
+			}
+			emitted++
+			return nil
+		})
+		if emitErr != nil {
+			return true, emitErr
+		}
+		return emitted > 0, err
+	})
+}
+
+// run calls each provider in turn until call succeeds. call reports final
+// when its error must be returned without retrying or falling back.
+func (c *Chain) run(ctx context.Context, call func(ctx context.Context, g QuizGenerator) (final bool, err error)) error {
+	var lastErr error
+	for _, p := range c.providers {
+		for attempt := 0; ; attempt++ {
+			if !p.breaker.allow(time.Now()) {
+				if attempt == 0 {
+					lastErr = fmt.Errorf("%s: circuit open", p.Name)
+				}
+				break
+			}
+
+			final, err := c.attempt(ctx, p, call)
+			if err == nil {
+				p.breaker.success(time.Now())
+				addProvider(ctx, p.Name)
+				return nil
+			}
+
+			var refused *RefusedError
+			switch {
+			case ctx.Err() != nil:
+				p.breaker.release()
+				return err
+			case errors.As(err, &refused):
+				p.breaker.success(time.Now())
+				return err
+			case errors.Is(err, errNothingToDraw):
+				p.breaker.release()
+				lastErr = fmt.Errorf("%s: %w", p.Name, err)
+			default:
+				p.breaker.failure(time.Now(), err)
+				lastErr = fmt.Errorf("%s: %w", p.Name, err)
+				log.Printf("[Chain] Provider %s failed (attempt %d): %v", p.Name, attempt+1, err)
+			}
+			if final {
+				return err
+			}
+			if !retryable(err) || attempt >= c.cfg.Retries {
+				break
+			}
+			if err := c.sleep(ctx, c.backoff(attempt, err)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no providers configured")
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+func (c *Chain) attempt(ctx context.Context, p *chainProvider, call func(ctx context.Context, g QuizGenerator) (bool, error)) (bool, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	return call(ctx, p.Generator)
+}
+
+// backoff returns the delay before retry number attempt+1: exponential with
+// full jitter over its upper half, and at least what a 429's Retry-After
+// asks for, all capped at MaxBackoff.
+func (c *Chain) backoff(attempt int, err error) time.Duration {
+	d := c.cfg.Backoff << attempt
+	if d <= 0 || (c.cfg.MaxBackoff > 0 && d > c.cfg.MaxBackoff) {
+		d = c.cfg.MaxBackoff
+	}
+	if d > 1 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	if after := retryAfter(err); after > d {
+		d = after
+	}
+	if c.cfg.MaxBackoff > 0 && d > c.cfg.MaxBackoff {
+		d = c.cfg.MaxBackoff
+	}
+	return d
+}
+
+// retryable reports whether err is worth calling the same provider again
+// for. Rate limits, server errors and timeouts are; other client errors
+// (e.g. a bad API key) are not.
+func retryable(err error) bool {
+	if errors.Is(err, errNothingToDraw) || errors.Is(err, ErrPoolClosed) || errors.Is(err, ErrNoQuestions) {
+		return false
+	}
+	status := 0
+	var apiErr *googleapi.Error
+	var statusErr *StatusError
+	if errors.As(err, &apiErr) {
+		status = apiErr.Code
+	} else if errors.As(err, &statusErr) {
+		status = statusErr.Status
+	}
+	switch {
+	case status == 0, status == http.StatusRequestTimeout, status == http.StatusTooManyRequests, status >= 500:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay a model API asked for in its Retry-After
+// header, if any.
+func retryAfter(err error) time.Duration {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Header == nil {
+		return 0
+	}
+	seconds, convErr := strconv.Atoi(apiErr.Header.Get("Retry-After"))
+	if convErr != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Health reports the circuit breaker state of every provider, in chain
+// order.
+func (c *Chain) Health() []types.ProviderHealth {
+	health := make([]types.ProviderHealth, len(c.providers))
+	for i, p := range c.providers {
+		health[i] = p.breaker.health(p.Name)
+	}
+	return health
+}
+
+// Close closes every provider that holds resources.
+func (c *Chain) Close() error {
+	var errs []error
+	for _, p := range c.providers {
+		if closer, ok := p.Generator.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SourceGenerator serves quizzes from a QuestionSource, typically the shared
+// question bank, as the last resort of a Chain when no model is reachable.
+type SourceGenerator struct {
+	Source QuestionSource
+}
+
+func (s SourceGenerator) Generate(ctx context.Context, quizRequest *types.QuizRequest) ([]types.Question, error) {
+	// Bank questions aren't based on the user's material.
+	if quizRequest.MaterialID != nil {
+		return nil, errNothingToDraw
+	}
+	questions, err := s.Source.Draw(ctx, quizRequest, quizRequest.NumQuestions)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, errNothingToDraw
+	}
+	return questions, nil
+}
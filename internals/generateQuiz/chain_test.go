@@ -0,0 +1,256 @@
+package generateQuiz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+const stubQuiz = `[{"ok": true}, [
+{"serial_number": 1, "question": "What is 2 + 2?", "options": ["3", "4", "5", "6"], "correctAnswer": "4", "description": "Two plus two is four."},
+{"serial_number": 2, "question": "What is 3 x 3?", "options": ["6", "8", "9", "12"], "correctAnswer": "9", "description": "Three times three is nine."},
+{"serial_number": 3, "question": "What is 10 - 7?", "options": ["2", "3", "4", "7"], "correctAnswer": "3", "description": "Ten minus seven is three."},
+{"serial_number": 4, "question": "What is 12 / 4?", "options": ["2", "3", "4", "6"], "correctAnswer": "3", "description": "Twelve divided by four is three."},
+{"serial_number": 5, "question": "What is 5 + 6?", "options": ["10", "11", "12", "13"], "correctAnswer": "11", "description": "Five plus six is eleven."}
+]]`
+
+const stubRefusal = `[{"ok": false}, ["This topic is not appropriate for a quiz."]]`
+
+// stubGemini is a Gemini API stand-in that answers with the given statuses in
+// turn, repeating the last one. A 200 carries text as the model's answer.
+type stubGemini struct {
+	*httptest.Server
+	text string
+
+	mu       sync.Mutex
+	statuses []int
+	calls    int
+}
+
+func newStubGemini(t *testing.T, text string, statuses ...int) *stubGemini {
+	t.Helper()
+	s := &stubGemini{text: text, statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubGemini) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := s.statuses[min(s.calls, len(s.statuses)-1)]
+	s.calls++
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if status != http.StatusOK {
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "3")
+		}
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"error": {"code": %d, "message": "stub failure"}}`, status)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"candidates": []interface{}{map[string]interface{}{
+			"content":      map[string]interface{}{"role": "model", "parts": []interface{}{map[string]interface{}{"text": s.text}}},
+			"finishReason": "STOP",
+		}},
+	})
+}
+
+func (s *stubGemini) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *stubGemini) provider(t *testing.T, name string) Provider {
+	t.Helper()
+	generator, err := NewGeminiGenerator(context.Background(), "test-key", "stub", option.WithEndpoint(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Provider{Name: name, Generator: generator}
+}
+
+// testChain returns a chain that records its backoff delays instead of
+// sleeping.
+func testChain(cfg ChainConfig, providers ...Provider) (*Chain, *[]time.Duration) {
+	c := NewChain(cfg, providers...)
+	var delays []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return c, &delays
+}
+
+func stubRequest() *types.QuizRequest {
+	return &types.QuizRequest{Topic: "arithmetic", NumQuestions: 5, Difficulty: "easy", Language: "en"}
+}
+
+func TestChainSucceeds(t *testing.T) {
+	stub := newStubGemini(t, stubQuiz, http.StatusOK)
+	chain, delays := testChain(ChainConfig{Retries: 2}, stub.provider(t, "primary"))
+
+	usage := &Usage{}
+	questions, err := chain.Generate(WithUsage(context.Background(), usage), stubRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 5 || stub.Calls() != 1 || len(*delays) != 0 {
+		t.Errorf("got %d questions in %d calls with delays %v, want 5 in 1 without", len(questions), stub.Calls(), *delays)
+	}
+	if got := usage.Providers(); got != "primary" {
+		t.Errorf("providers = %q, want primary", got)
+	}
+	if state := chain.Health()[0].State; state != breakerClosed {
+		t.Errorf("breaker is %s, want closed", state)
+	}
+}
+
+func TestChainRetriesRateLimitsAndServerErrors(t *testing.T) {
+	stub := newStubGemini(t, stubQuiz, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusOK)
+	chain, delays := testChain(ChainConfig{Retries: 2, Backoff: 10 * time.Millisecond, MaxBackoff: 5 * time.Second, FailureThreshold: 5}, stub.provider(t, "primary"))
+
+	if _, err := chain.Generate(context.Background(), stubRequest()); err != nil {
+		t.Fatal(err)
+	}
+	if stub.Calls() != 3 {
+		t.Errorf("made %d calls, want 3", stub.Calls())
+	}
+	if len(*delays) != 2 {
+		t.Fatalf("backed off %d times, want 2", len(*delays))
+	}
+	// The 429 asked for 3 seconds; the 500 gets the jittered backoff.
+	if d := (*delays)[0]; d != 3*time.Second {
+		t.Errorf("delay after 429 = %v, want the 3s Retry-After", d)
+	}
+	if d := (*delays)[1]; d < 10*time.Millisecond || d > 20*time.Millisecond {
+		t.Errorf("delay after 500 = %v, want 10-20ms", d)
+	}
+}
+
+func TestChainRetriesAreBounded(t *testing.T) {
+	stub := newStubGemini(t, stubQuiz, http.StatusBadGateway)
+	chain, _ := testChain(ChainConfig{Retries: 2, FailureThreshold: 10}, stub.provider(t, "primary"))
+
+	_, err := chain.Generate(context.Background(), stubRequest())
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+	if stub.Calls() != 3 {
+		t.Errorf("made %d calls, want 1 + 2 retries", stub.Calls())
+	}
+}
+
+func TestChainFallsBackInOrder(t *testing.T) {
+	broken := newStubGemini(t, stubQuiz, http.StatusInternalServerError)
+	rejected := newStubGemini(t, stubQuiz, http.StatusForbidden)
+	working := newStubGemini(t, stubQuiz, http.StatusOK)
+	unused := newStubGemini(t, stubQuiz, http.StatusOK)
+	chain, _ := testChain(ChainConfig{Retries: 1, FailureThreshold: 10},
+		broken.provider(t, "broken"), rejected.provider(t, "rejected"), working.provider(t, "working"), unused.provider(t, "unused"))
+
+	usage := &Usage{}
+	if _, err := chain.Generate(WithUsage(context.Background(), usage), stubRequest()); err != nil {
+		t.Fatal(err)
+	}
+	// The 500 is retried, the 403 isn't.
+	calls := []int{broken.Calls(), rejected.Calls(), working.Calls(), unused.Calls()}
+	if want := []int{2, 1, 1, 0}; fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("calls per provider = %v, want %v", calls, want)
+	}
+	if got := usage.Providers(); got != "working" {
+		t.Errorf("providers = %q, want working", got)
+	}
+}
+
+func TestChainReturnsRefusalWithoutFallback(t *testing.T) {
+	refusing := newStubGemini(t, stubRefusal, http.StatusOK)
+	other := newStubGemini(t, stubQuiz, http.StatusOK)
+	chain, _ := testChain(ChainConfig{Retries: 2}, refusing.provider(t, "refusing"), other.provider(t, "other"))
+
+	_, err := chain.Generate(context.Background(), stubRequest())
+	var refused *RefusedError
+	if !errors.As(err, &refused) {
+		t.Fatalf("err = %v, want a RefusedError", err)
+	}
+	if refusing.Calls() != 1 || other.Calls() != 0 {
+		t.Errorf("calls = %d, %d, want 1, 0", refusing.Calls(), other.Calls())
+	}
+}
+
+func TestChainBreakerOpensAndHalfOpens(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	// Two failures open the breaker, the first trial call fails and the
+	// second one succeeds.
+	stub := newStubGemini(t, stubQuiz, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	chain, _ := testChain(ChainConfig{FailureThreshold: 2, Cooldown: cooldown}, stub.provider(t, "primary"))
+	ctx := context.Background()
+
+	generate := func(wantCalls int, wantState string) error {
+		t.Helper()
+		_, err := chain.Generate(ctx, stubRequest())
+		if stub.Calls() != wantCalls {
+			t.Errorf("made %d calls, want %d", stub.Calls(), wantCalls)
+		}
+		if state := chain.Health()[0].State; state != wantState {
+			t.Errorf("breaker is %s, want %s", state, wantState)
+		}
+		return err
+	}
+
+	generate(1, breakerClosed)
+	generate(2, breakerOpen)
+	if err := generate(2, breakerOpen); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("open breaker: err = %v, want ErrUnavailable", err)
+	}
+
+	time.Sleep(cooldown + 10*time.Millisecond)
+	generate(3, breakerOpen) // the failed trial reopens it
+	generate(3, breakerOpen)
+
+	time.Sleep(cooldown + 10*time.Millisecond)
+	if err := generate(4, breakerClosed); err != nil {
+		t.Errorf("trial call: %v", err)
+	}
+}
+
+func TestBreakerLetsOneTrialCallThrough(t *testing.T) {
+	b := newBreaker(1, time.Minute)
+	now := time.Now()
+	if !b.allow(now) {
+		t.Fatal("closed breaker refused a call")
+	}
+	b.failure(now, errors.New("boom"))
+	if b.allow(now.Add(time.Second)) {
+		t.Error("open breaker allowed a call during the cooldown")
+	}
+
+	later := now.Add(time.Minute)
+	if !b.allow(later) {
+		t.Fatal("breaker refused the trial call after the cooldown")
+	}
+	if b.allow(later) {
+		t.Error("half-open breaker allowed a second concurrent call")
+	}
+	b.release()
+	if !b.allow(later) {
+		t.Error("released trial call wasn't replaced")
+	}
+	b.success(later)
+	if !b.allow(later) || !b.allow(later) {
+		t.Error("closed breaker refused calls")
+	}
+}
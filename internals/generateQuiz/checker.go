@@ -0,0 +1,438 @@
+package generateQuiz
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/locale"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/similarity"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// minRequestSize is the smallest quiz a backend accepts (see ValidateRequest),
+// so top-up rounds never ask for fewer questions than this.
+const minRequestSize = 5
+
+// minScriptShare is the share of a question's letters that must be in the
+// script of the requested language. Technical terms and names are often left
+// in Latin script, so it is well below one.
+const minScriptShare = 0.5
+
+// QuestionSource supplies previously generated questions so the model only
+// has to produce the remainder, and keeps the fresh ones for later reuse.
+type QuestionSource interface {
+	// Draw returns up to n questions for the request that its user has not
+	// seen yet.
+	Draw(ctx context.Context, quizRequest *types.QuizRequest, n int) ([]types.Question, error)
+	// Store saves freshly generated questions.
+	Store(ctx context.Context, quizRequest *types.QuizRequest, questions []types.Question) error
+}
+
+// Result is a validated quiz together with the report of what was fixed.
+type Result struct {
+	Questions []types.Question
+	Report    types.GenerationReport
+}
+
+// Checker wraps a QuizGenerator with validation and repair. Invalid questions
+// are repaired where the intent is unambiguous and dropped otherwise, and the
+// model is asked again until the requested number of questions is reached or
+// maxRounds calls have been made.
+//
+// With a QuestionSource set, part of every quiz is drawn from it first. With
+// a QuestionHistory set, near-duplicates of questions the user has already
+// seen are dropped like invalid ones and regenerated. With a MaterialSource
+// set, requests can name uploaded material to base the quiz on. With a
+// PromptSource set, the prompt is the template assigned to the user.
+// SkipModelChecks turns off the checks that only make sense for a model's
+// writing.
+type Checker struct {
+	generator QuizGenerator
+	maxRounds int
+
+	source       QuestionSource
+	defaultRatio float64
+
+	history     QuestionHistory
+	promptLimit int
+	threshold   float64
+
+	materials      MaterialSource
+	materialBudget int
+
+	prompts PromptSource
+
+	skipModelChecks bool
+}
+
+func NewChecker(generator QuizGenerator, maxRounds int) *Checker {
+	if maxRounds <= 0 {
+		maxRounds = 1
+	}
+	return &Checker{generator: generator, maxRounds: maxRounds}
+}
+
+// UseSource makes the checker draw a share of every quiz from source.
+// defaultRatio applies to requests that don't set BankRatio.
+func (c *Checker) UseSource(source QuestionSource, defaultRatio float64) {
+	c.source = source
+	c.defaultRatio = defaultRatio
+}
+
+// SkipModelChecks turns off the checks aimed at what a model writes: that
+// questions are in the language's script and aren't near-duplicates of the
+// user's past ones. It is for backends that make up placeholder questions,
+// such as FakeGenerator, which would fail them.
+func (c *Checker) SkipModelChecks() {
+	c.skipModelChecks = true
+}
+
+// Generator returns the wrapped backend.
+func (c *Checker) Generator() QuizGenerator {
+	return c.generator
+}
+
+func (c *Checker) Generate(ctx context.Context, quizRequest *types.QuizRequest) (*Result, error) {
+	quizRequest, acc, err := c.prepare(ctx, quizRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.draw(ctx, quizRequest, acc, nil); err != nil {
+		return nil, err
+	}
+	drawn := len(acc.questions)
+
+	var lastErr error
+	for acc.missing() > 0 && acc.report.Rounds < c.maxRounds {
+		acc.report.Rounds++
+
+		questions, err := c.generator.Generate(ctx, topUpRequest(quizRequest, acc))
+		if err != nil {
+			if ctx.Err() != nil || len(acc.questions) == 0 {
+				// A failure before anything was accepted (e.g. a refusal) is
+				// the answer.
+				return nil, err
+			}
+			lastErr = err
+			break
+		}
+		for _, q := range questions {
+			if acc.missing() == 0 {
+				break
+			}
+			acc.add(q)
+		}
+	}
+
+	if len(acc.questions) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, ErrNoQuestions
+	}
+
+	c.store(ctx, quizRequest, acc.questions[drawn:])
+	return acc.result(), nil
+}
+
+// Stream is the streaming counterpart of Generate: each question is emitted
+// as soon as it passes validation, then missing ones are topped up.
+func (c *Checker) Stream(ctx context.Context, quizRequest *types.QuizRequest, emit func(types.Question) error) (*types.GenerationReport, error) {
+	quizRequest, acc, err := c.prepare(ctx, quizRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	forward := func(q types.Question) error {
+		if acc.missing() == 0 {
+			return nil
+		}
+		if accepted, ok := acc.add(q); ok {
+			return emit(accepted)
+		}
+		return nil
+	}
+
+	if err := c.draw(ctx, quizRequest, acc, emit); err != nil {
+		return nil, err
+	}
+	drawn := len(acc.questions)
+
+	if acc.missing() > 0 {
+		acc.report.Rounds++
+		err := Stream(ctx, c.generator, topUpRequest(quizRequest, acc), forward)
+		if err != nil && (ctx.Err() != nil || len(acc.questions) == 0) {
+			return nil, err
+		}
+	}
+
+	for acc.missing() > 0 && acc.report.Rounds < c.maxRounds {
+		acc.report.Rounds++
+		questions, err := c.generator.Generate(ctx, topUpRequest(quizRequest, acc))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			break
+		}
+		for _, q := range questions {
+			if err := forward(q); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(acc.questions) == 0 {
+		return nil, ErrNoQuestions
+	}
+
+	c.store(ctx, quizRequest, acc.questions[drawn:])
+	report := acc.result().Report
+	return &report, nil
+}
+
+// Replace generates a single question for the request to take the place of
+// one in a saved quiz. The replacement must not repeat any of exclude (the
+// quiz's questions, including the one being replaced) and, with a
+// QuestionHistory set, must not resemble them or the user's past questions.
+// It is always generated, never drawn from the source.
+func (c *Checker) Replace(ctx context.Context, quizRequest *types.QuizRequest, exclude []types.Question) (*Result, error) {
+	single := *quizRequest
+	single.NumQuestions = 1
+	quizRequest, acc, err := c.prepare(ctx, &single)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range exclude {
+		acc.exclude(q.Question)
+		quizRequest.PastQuestions = append(quizRequest.PastQuestions, q.Question)
+	}
+
+	for acc.missing() > 0 && acc.report.Rounds < c.maxRounds {
+		acc.report.Rounds++
+
+		questions, err := c.generator.Generate(ctx, topUpRequest(quizRequest, acc))
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range questions {
+			if _, ok := acc.add(q); ok {
+				break
+			}
+		}
+	}
+
+	if len(acc.questions) == 0 {
+		return nil, ErrNoQuestions
+	}
+
+	c.store(ctx, quizRequest, acc.questions)
+	return acc.result(), nil
+}
+
+// prepare copies the request with the server-chosen fields (material, past
+// questions and prompt template) filled in, and returns it with an empty
+// accumulator for it.
+func (c *Checker) prepare(ctx context.Context, quizRequest *types.QuizRequest) (*types.QuizRequest, *accumulator, error) {
+	req := *quizRequest
+	req.PastQuestions, req.Material, req.PromptTemplate = nil, nil, nil
+
+	if err := c.loadMaterial(ctx, &req); err != nil {
+		return nil, nil, err
+	}
+	near := c.loadHistory(ctx, &req)
+	c.loadPrompt(ctx, &req)
+
+	acc := newAccumulator(&req, near)
+	if c.skipModelChecks {
+		acc.near, acc.script = nil, ""
+	}
+	if req.PromptTemplate != nil {
+		id := req.PromptTemplate.ID
+		acc.report.PromptTemplateID = &id
+	}
+	acc.report.PromptVersion = req.PromptTemplate.Label()
+	return &req, acc, nil
+}
+
+// draw seeds acc with questions from the source, emitting each one if emit is
+// set. Source errors are logged and the model makes up the difference; only
+// emit errors are returned.
+func (c *Checker) draw(ctx context.Context, quizRequest *types.QuizRequest, acc *accumulator, emit func(types.Question) error) error {
+	// Bank questions aren't based on the user's material.
+	if c.source == nil || quizRequest.MaterialID != nil {
+		return nil
+	}
+
+	ratio := c.defaultRatio
+	if quizRequest.BankRatio != nil {
+		ratio = *quizRequest.BankRatio
+	}
+	n := int(float64(quizRequest.NumQuestions) * ratio)
+	if n <= 0 {
+		return nil
+	}
+
+	questions, err := c.source.Draw(ctx, quizRequest, n)
+	if err != nil {
+		log.Printf("[Checker] Drawing from question bank failed: %v", err)
+		return nil
+	}
+
+	for _, q := range questions {
+		accepted, ok := acc.add(q)
+		if !ok {
+			continue
+		}
+		acc.report.FromBank++
+		if emit != nil {
+			if err := emit(accepted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Checker) store(ctx context.Context, quizRequest *types.QuizRequest, fresh []types.Question) {
+	// Questions on a user's own material stay private.
+	if c.source == nil || len(fresh) == 0 || quizRequest.MaterialID != nil {
+		return
+	}
+	if err := c.source.Store(ctx, quizRequest, fresh); err != nil {
+		log.Printf("[Checker] Saving to question bank failed: %v", err)
+	}
+}
+
+// topUpRequest asks for the questions acc is missing, telling the model not
+// to repeat the ones already accepted.
+func topUpRequest(quizRequest *types.QuizRequest, acc *accumulator) *types.QuizRequest {
+	req := *quizRequest
+	req.NumQuestions = max(acc.missing(), minRequestSize)
+	if len(acc.questions) > 0 {
+		req.PastQuestions = append([]string(nil), quizRequest.PastQuestions...)
+		for _, q := range acc.questions {
+			req.PastQuestions = append(req.PastQuestions, q.Question)
+		}
+	}
+	return &req
+}
+
+// accumulator collects accepted questions across rounds, renumbering them
+// and rejecting duplicates and types the request didn't ask for. If near is
+// set, questions similar to one in it are rejected too, and accepted
+// questions are added to it. If material is set, every question must quote
+// it in its source_excerpt. If script is set, questions must be written in
+// it, which catches a model falling back to English for a non-Latin language.
+type accumulator struct {
+	questions []types.Question
+	seen      map[string]bool
+	allowed   map[string]bool
+	near      *similarity.Index
+	material  []string
+	script    string
+	report    types.GenerationReport
+}
+
+func newAccumulator(quizRequest *types.QuizRequest, near *similarity.Index) *accumulator {
+	allowed := make(map[string]bool)
+	for _, kind := range requestedTypes(quizRequest) {
+		allowed[kind] = true
+	}
+	// Latin-script languages can't be told apart from English this way.
+	script := locale.Script(quizRequest.Language)
+	if script == "Latn" {
+		script = ""
+	}
+	return &accumulator{
+		seen:     make(map[string]bool),
+		allowed:  allowed,
+		near:     near,
+		material: quizRequest.Material,
+		script:   script,
+		report:   types.GenerationReport{Requested: quizRequest.NumQuestions},
+	}
+}
+
+func (a *accumulator) missing() int {
+	return a.report.Requested - len(a.questions)
+}
+
+// add validates q and, if it is acceptable, stores and returns the repaired
+// question with its final serial number.
+func (a *accumulator) add(q types.Question) (types.Question, bool) {
+	label := fmt.Sprintf("question %q", truncate(q.Question, 60))
+
+	fixed, notes, reason := normalizeQuestion(q)
+	if reason != "" {
+		a.report.Dropped = append(a.report.Dropped, label+": "+reason)
+		return types.Question{}, false
+	}
+
+	if !a.allowed[fixed.Type] {
+		a.report.Dropped = append(a.report.Dropped, fmt.Sprintf("%s: type %s was not requested", label, fixed.Type))
+		return types.Question{}, false
+	}
+
+	if a.script != "" {
+		if share, ok := locale.ScriptShare(fixed.Question, a.script); ok && share < minScriptShare {
+			a.report.Dropped = append(a.report.Dropped, fmt.Sprintf("%s: only %.0f%% of letters in %s script", label, 100*share, a.script))
+			return types.Question{}, false
+		}
+	}
+
+	if len(a.material) > 0 {
+		excerpt, note, reason := checkExcerpt(fixed.SourceExcerpt, a.material)
+		if reason != "" {
+			a.report.Dropped = append(a.report.Dropped, label+": "+reason)
+			return types.Question{}, false
+		}
+		if note != "" {
+			notes = append(notes, note)
+		}
+		fixed.SourceExcerpt = excerpt
+	} else {
+		fixed.SourceExcerpt = ""
+	}
+
+	key := questionKey(fixed.Question)
+	if a.seen[key] {
+		a.report.Dropped = append(a.report.Dropped, label+": duplicate question")
+		return types.Question{}, false
+	}
+	if a.near != nil {
+		if match, score, ok := a.near.Match(fixed.Question); ok {
+			a.report.Dropped = append(a.report.Dropped, fmt.Sprintf("%s: near-duplicate (%.2f) of %q", label, score, truncate(match, 60)))
+			return types.Question{}, false
+		}
+		a.near.Add(fixed.Question)
+	}
+	a.seen[key] = true
+
+	serial := len(a.questions) + 1
+	if fixed.SerialNumber != serial {
+		notes = append(notes, fmt.Sprintf("serial_number %d renumbered to %d", fixed.SerialNumber, serial))
+		fixed.SerialNumber = serial
+	}
+	for _, note := range notes {
+		a.report.Repaired = append(a.report.Repaired, label+": "+note)
+	}
+
+	a.questions = append(a.questions, fixed)
+	return fixed, true
+}
+
+// exclude makes add reject text and, if near is set, questions similar to it,
+// without counting it as accepted.
+func (a *accumulator) exclude(text string) {
+	a.seen[questionKey(text)] = true
+	if a.near != nil {
+		a.near.Add(text)
+	}
+}
+
+func (a *accumulator) result() *Result {
+	a.report.Returned = len(a.questions)
+	return &Result{Questions: a.questions, Report: a.report}
+}
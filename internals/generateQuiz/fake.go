@@ -0,0 +1,61 @@
+package generateQuiz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// FakeGenerator returns deterministic placeholder questions without calling
+// any model. It is meant for tests and offline development. Its questions
+// are in English whatever the language asked for, so a Checker wrapping it
+// should have SkipModelChecks set.
+type FakeGenerator struct{}
+
+func (FakeGenerator) Generate(ctx context.Context, quizRequest *types.QuizRequest) ([]types.Question, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Numbering continues after the questions the request says to avoid, so
+	// that successive calls don't repeat each other.
+	offset := len(quizRequest.PastQuestions)
+	questions := make([]types.Question, 0, quizRequest.NumQuestions)
+	kinds := requestedTypes(quizRequest)
+	for i := 0; i < quizRequest.NumQuestions; i++ {
+		options := []string{"Option A", "Option B", "Option C", "Option D"}
+		q := types.Question{
+			QuestionPrompt: types.QuestionPrompt{
+				SerialNumber: i + 1,
+				Type:         kinds[i%len(kinds)],
+				Question:     fmt.Sprintf("Sample %s question %d about %s?", quizRequest.Difficulty, offset+i+1, quizRequest.Topic),
+				Options:      options,
+			},
+			QuestionSolution: types.QuestionSolution{CorrectAnswer: options[i%len(options)]},
+		}
+		switch q.Type {
+		case types.QuestionMultiSelect:
+			q.CorrectAnswers = []string{options[0], options[2]}
+			q.CorrectAnswer = strings.Join(q.CorrectAnswers, ", ")
+		case types.QuestionTrueFalse:
+			q.Options = []string{"True", "False"}
+			q.CorrectAnswer = q.Options[i%2]
+		case types.QuestionFillIn:
+			q.Options = []string{}
+			q.CorrectAnswer = "sample"
+			q.CorrectAnswers = []string{"sample", "example"}
+		case types.QuestionOrdering:
+			q.Options = []string{options[2], options[0], options[3], options[1]}
+			q.CorrectAnswers = options
+			q.CorrectAnswer = strings.Join(q.CorrectAnswers, ", ")
+		}
+		q.Description = fmt.Sprintf("%s is the correct answer to sample question %d.", q.CorrectAnswer, i+1)
+		if len(quizRequest.Material) > 0 {
+			q.SourceExcerpt = truncate(quizRequest.Material[i%len(quizRequest.Material)], 80)
+		}
+		questions = append(questions, q)
+	}
+	return questions, nil
+}
@@ -0,0 +1,31 @@
+package generateQuiz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+func TestCheckerAcceptsPlaceholdersWithoutModelChecks(t *testing.T) {
+	chain := NewChain(ChainConfig{}, Provider{Name: "fake", Generator: FakeGenerator{}})
+	quizRequest := func() *types.QuizRequest {
+		return &types.QuizRequest{Topic: "rivers", NumQuestions: 5, Difficulty: "easy", Language: "bn"}
+	}
+
+	// English placeholders fail the Bengali script check...
+	if _, err := NewChecker(chain, 1).Generate(context.Background(), quizRequest()); err == nil {
+		t.Error("placeholders passed the script check")
+	}
+
+	// ...which is skipped on request.
+	checker := NewChecker(chain, 1)
+	checker.SkipModelChecks()
+	result, err := checker.Generate(context.Background(), quizRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Questions) != 5 || len(result.Report.Dropped) != 0 {
+		t.Errorf("got %d questions, dropped %v; want 5 and none dropped", len(result.Questions), result.Report.Dropped)
+	}
+}
@@ -0,0 +1,201 @@
+package generateQuiz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/config"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/replay"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// recordingClient returns the HTTP client for the genai client in record or
+// replay mode. A client given with option.WithHTTPClient is used as is, so
+// in record mode the API key is added by the underlying transport.
+func recordingClient(ctx context.Context, cfg *config.Config) (*http.Client, error) {
+	var base http.RoundTripper
+	if cfg.RecordMode == replay.Record {
+		keyed, _, err := htransport.NewClient(ctx, option.WithAPIKey(cfg.APIKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gemini transport: %w", err)
+		}
+		base = keyed.Transport
+	}
+	transport, err := replay.New(cfg.RecordMode, cfg.FixturesDir, base)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Gemini] %s mode, fixtures in %s", cfg.RecordMode, cfg.FixturesDir)
+	return transport.Client(), nil
+}
+
+// defaultGeminiEndpoint is where the Python workers' Gemini client goes when
+// GEMINI_ENDPOINT isn't set.
+const defaultGeminiEndpoint = "https://generativelanguage.googleapis.com"
+
+// pythonEnv returns the environment that points the Python workers' Gemini
+// client at cfg.GeminiEndpoint or, in record or replay mode, at a local
+// replay proxy in front of it.
+func pythonEnv(cfg *config.Config) ([]string, error) {
+	endpoint := cfg.GeminiEndpoint
+	if cfg.RecordMode == replay.Off {
+		if endpoint == "" {
+			return nil, nil
+		}
+		return []string{"GEMINI_ENDPOINT=" + endpoint}, nil
+	}
+
+	if endpoint == "" {
+		endpoint = defaultGeminiEndpoint
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	// The workers send their own API key, so the proxy needs no credentials.
+	transport, err := replay.New(cfg.RecordMode, cfg.FixturesDir, nil)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := transport.Serve(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[PythonPool] %s mode via %s, fixtures in %s", cfg.RecordMode, proxy, cfg.FixturesDir)
+
+	env := []string{"GEMINI_ENDPOINT=" + proxy}
+	if cfg.RecordMode == replay.Replay && cfg.APIKey == "" {
+		env = append(env, "API_KEY=replay")
+	}
+	return env, nil
+}
+
+// GeminiGenerator calls the Gemini API directly through the genai client.
+type GeminiGenerator struct {
+	client *genai.Client
+	model  string
+}
+
+// NewGeminiGenerator creates a client for model. Extra options are passed to
+// the genai client, e.g. option.WithEndpoint to talk to a stub server.
+func NewGeminiGenerator(ctx context.Context, apiKey, model string, opts ...option.ClientOption) (*GeminiGenerator, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API_KEY is required for the gemini quiz generator")
+	}
+
+	client, err := genai.NewClient(ctx, append([]option.ClientOption{option.WithAPIKey(apiKey)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gemini client: %w", err)
+	}
+
+	return &GeminiGenerator{client: client, model: model}, nil
+}
+
+func (g *GeminiGenerator) Generate(ctx context.Context, quizRequest *types.QuizRequest) ([]types.Question, error) {
+	model := g.client.GenerativeModel(g.model)
+	model.GenerationConfig = genai.GenerationConfig{
+		ResponseMIMEType: "application/json",
+	}
+
+	prompt := generatePrompt(quizRequest)
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("gemini request failed: %w", err)
+	}
+	recordGeminiUsage(ctx, resp)
+
+	return parseEnvelope([]byte(responseText(resp)))
+}
+
+// GenerateStream emits each question as soon as its JSON object is complete
+// in the streamed answer.
+func (g *GeminiGenerator) GenerateStream(ctx context.Context, quizRequest *types.QuizRequest, emit func(types.Question) error) error {
+	model := g.client.GenerativeModel(g.model)
+	model.GenerationConfig = genai.GenerationConfig{
+		ResponseMIMEType: "application/json",
+	}
+
+	prompt := generatePrompt(quizRequest)
+	iter := model.GenerateContentStream(ctx, genai.Text(prompt))
+
+	scanner := newQuestionScanner()
+	emitted := 0
+	// Every chunk carries the usage so far; the last one has the total.
+	var last *genai.GenerateContentResponse
+	defer func() { recordGeminiUsage(ctx, last) }()
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("gemini stream failed: %w", err)
+		}
+		if resp.UsageMetadata != nil {
+			last = resp
+		}
+
+		for _, item := range scanner.Write(responseText(resp)) {
+			var rq rawQuestion
+			if err := json.Unmarshal(item, &rq); err != nil {
+				log.Printf("[GeminiGenerator] Skipping malformed streamed question: %v", err)
+				continue
+			}
+			question := rq.toQuestion(emitted)
+			question.SerialNumber = emitted + 1
+			if err := emit(question); err != nil {
+				return err
+			}
+			emitted++
+		}
+	}
+
+	if emitted > 0 {
+		return nil
+	}
+	// Nothing streamed: either a refusal or an answer in an unexpected shape.
+	// Parsing the whole answer reports which.
+	questions, err := parseEnvelope(scanner.Bytes())
+	if err != nil {
+		return err
+	}
+	for _, question := range questions {
+		if err := emit(question); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GeminiGenerator) Close() error {
+	return g.client.Close()
+}
+
+func recordGeminiUsage(ctx context.Context, resp *genai.GenerateContentResponse) {
+	if resp == nil || resp.UsageMetadata == nil {
+		return
+	}
+	addTokens(ctx, int(resp.UsageMetadata.PromptTokenCount), int(resp.UsageMetadata.CandidatesTokenCount))
+}
+
+// responseText concatenates the text parts of the first candidate.
+func responseText(resp *genai.GenerateContentResponse) string {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			sb.WriteString(string(text))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,30 @@
+package generateQuiz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+func TestValidateRequest(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		modify func(*types.QuizRequest)
+		err    string // a substring of the error, or "" for none
+	}{
+		{"valid", func(*types.QuizRequest) {}, ""},
+		{"level unset", func(r *types.QuizRequest) { r.Level = 0 }, ""},
+		{"level 10", func(r *types.QuizRequest) { r.Level = 10 }, ""},
+		{"level 11", func(r *types.QuizRequest) { r.Level = 11 }, "level must be between 1 and 10"},
+		{"unresolved adaptive", func(r *types.QuizRequest) { r.Difficulty = "adaptive" }, "difficulty must be easy, medium or hard"},
+		{"unknown difficulty", func(r *types.QuizRequest) { r.Difficulty = "extreme" }, "difficulty must be easy, medium or hard"},
+	} {
+		request := &types.QuizRequest{Topic: "Binary", NumQuestions: 5, Difficulty: "easy", Level: 3}
+		test.modify(request)
+		err := ValidateRequest(request)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: err = %v, want %q", test.name, err, test.err)
+		}
+	}
+}
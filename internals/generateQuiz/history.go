@@ -0,0 +1,87 @@
+package generateQuiz
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/similarity"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// QuestionHistory supplies the questions a user has already seen, newest
+// first.
+type QuestionHistory interface {
+	PastQuestions(ctx context.Context, quizRequest *types.QuizRequest) ([]types.PastQuestion, error)
+}
+
+// UseHistory makes the checker steer the model away from the user's past
+// questions and drop returned questions whose estimated similarity to a past
+// (or already accepted) question is at least threshold. At most promptLimit
+// past questions, the ones most relevant to the topic, go into the prompt;
+// filtering uses all of them.
+func (c *Checker) UseHistory(history QuestionHistory, promptLimit int, threshold float64) {
+	c.history = history
+	c.promptLimit = promptLimit
+	c.threshold = threshold
+}
+
+// loadHistory fills in req.PastQuestions from the user's history and returns
+// the near-duplicate index to filter against, which is nil when filtering is
+// off.
+func (c *Checker) loadHistory(ctx context.Context, req *types.QuizRequest) *similarity.Index {
+	if c.history == nil {
+		return nil
+	}
+
+	index := similarity.NewIndex(c.threshold)
+	past, err := c.history.PastQuestions(ctx, req)
+	if err != nil {
+		log.Printf("[Checker] Loading past questions failed: %v", err)
+		return index
+	}
+	for _, pq := range past {
+		index.Add(pq.Question)
+	}
+	req.PastQuestions = selectPastQuestions(req.Topic, req.TopicID, past, c.promptLimit)
+	return index
+}
+
+// selectPastQuestions picks up to limit past questions for the prompt,
+// ranked by how much of topic they mention and, secondarily, by recency.
+// Questions from quizzes on the same canonical topic (topicID) count as
+// fully relevant whatever their wording. Questions that share nothing with
+// the topic are left out; they can't be repeated by accident and only
+// lengthen the prompt.
+func selectPastQuestions(topic string, topicID *int64, past []types.PastQuestion, limit int) []string {
+	type candidate struct {
+		question string
+		score    float64
+	}
+
+	var candidates []candidate
+	for i, pq := range past {
+		relevance := similarity.Relevance(topic, pq.QuizName+" "+pq.Question)
+		if topicID != nil && pq.TopicID != nil && *pq.TopicID == *topicID {
+			relevance = 1
+		}
+		if relevance == 0 {
+			continue
+		}
+		recency := 1 - float64(i)/float64(len(past))
+		candidates = append(candidates, candidate{question: pq.Question, score: relevance + 0.25*recency})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	selected := make([]string, len(candidates))
+	for i, c := range candidates {
+		selected[i] = c.question
+	}
+	return selected
+}
@@ -0,0 +1,92 @@
+package generateQuiz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/materials"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/similarity"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// maxExcerptLength bounds a source_excerpt in characters; longer quotes are
+// shortened.
+const maxExcerptLength = 300
+
+// looseExcerptMatch is the share of an excerpt's words that must occur in one
+// chunk when the excerpt isn't a verbatim quote.
+const looseExcerptMatch = 0.8
+
+// MaterialSource supplies the text of a user's uploaded material in chunks,
+// in document order.
+type MaterialSource interface {
+	MaterialChunks(ctx context.Context, userID, materialID int64) ([]string, error)
+}
+
+// UseMaterials lets requests with a MaterialID be generated from that
+// material. Up to budget characters of the most relevant chunks go into the
+// prompt.
+func (c *Checker) UseMaterials(source MaterialSource, budget int) {
+	c.materials = source
+	c.materialBudget = budget
+}
+
+// loadMaterial fills in req.Material for requests that name a material.
+func (c *Checker) loadMaterial(ctx context.Context, req *types.QuizRequest) error {
+	if req.MaterialID == nil {
+		return nil
+	}
+	if c.materials == nil {
+		return errors.New("quizzes from uploaded material are not enabled")
+	}
+
+	chunks, err := c.materials.MaterialChunks(ctx, req.UserID, *req.MaterialID)
+	if err != nil {
+		return fmt.Errorf("failed to load material %d: %w", *req.MaterialID, err)
+	}
+	req.Material = materials.Select(chunks, req.Topic, c.materialBudget)
+	return nil
+}
+
+// checkExcerpt verifies that excerpt comes from the material. Quotes are
+// compared ignoring case and whitespace, and "..." may join several pieces.
+// An excerpt that isn't verbatim is still accepted if most of its words occur
+// in one chunk, since models tidy up punctuation when quoting.
+func checkExcerpt(excerpt string, material []string) (string, string, string) {
+	excerpt = strings.TrimSpace(strings.Trim(strings.TrimSpace(excerpt), `"'“”‘’`))
+	if excerpt == "" {
+		return "", "", "missing source_excerpt"
+	}
+
+	var note string
+	if len([]rune(excerpt)) > maxExcerptLength {
+		excerpt = truncate(excerpt, maxExcerptLength)
+		note = fmt.Sprintf("shortened source_excerpt to %d characters", maxExcerptLength)
+	}
+
+	text := excerptKey(strings.Join(material, "\n"))
+	verbatim := true
+	for _, piece := range strings.FieldsFunc(excerpt, func(r rune) bool { return r == '…' }) {
+		for _, part := range strings.Split(piece, "...") {
+			if key := excerptKey(part); key != "" && !strings.Contains(text, key) {
+				verbatim = false
+			}
+		}
+	}
+	if verbatim {
+		return excerpt, note, ""
+	}
+
+	for _, chunk := range material {
+		if similarity.Relevance(excerpt, chunk) >= looseExcerptMatch {
+			return excerpt, "source_excerpt is not verbatim but matches the material", ""
+		}
+	}
+	return "", "", fmt.Sprintf("source_excerpt %q not found in the material", truncate(excerpt, 60))
+}
+
+func excerptKey(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
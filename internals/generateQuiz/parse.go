@@ -0,0 +1,214 @@
+package generateQuiz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// flexString accepts a JSON string, number or boolean. Models regularly send
+// numeric answers and serial numbers unquoted (or quoted when told not to).
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*f = flexString(s)
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case float64, bool:
+		*f = flexString(strings.TrimSpace(string(data)))
+		return nil
+	case nil:
+		*f = ""
+		return nil
+	}
+	return fmt.Errorf("expected a string, got %s", data)
+}
+
+// flexStrings accepts a JSON array of flexStrings or a single flexString.
+type flexStrings []flexString
+
+func (f *flexStrings) UnmarshalJSON(data []byte) error {
+	var list []flexString
+	if err := json.Unmarshal(data, &list); err == nil {
+		*f = list
+		return nil
+	}
+	var one flexString
+	if err := json.Unmarshal(data, &one); err != nil {
+		return err
+	}
+	if one == "" {
+		*f = nil
+	} else {
+		*f = flexStrings{one}
+	}
+	return nil
+}
+
+func (f flexStrings) strings() []string {
+	if f == nil {
+		return nil
+	}
+	out := make([]string, len(f))
+	for i, s := range f {
+		out[i] = string(s)
+	}
+	return out
+}
+
+// flexBool reads "true", true, "yes" and "1" as true.
+func flexBool(f flexString) bool {
+	switch strings.ToLower(strings.TrimSpace(string(f))) {
+	case "true", "yes", "1":
+		return true
+	}
+	return false
+}
+
+// rawQuestion mirrors the JSON the model is asked to produce, decoded
+// leniently; the validation stage decides what is actually acceptable.
+type rawQuestion struct {
+	SerialNumber   flexString  `json:"serial_number"`
+	Type           flexString  `json:"type"`
+	Question       flexString  `json:"question"`
+	Options        flexStrings `json:"options"`
+	CorrectAnswer  flexString  `json:"correctAnswer"`
+	CorrectAnswers flexStrings `json:"correctAnswers"`
+	CaseSensitive  flexString  `json:"caseSensitive"`
+	IgnoreSpaces   flexString  `json:"ignoreSpaces"`
+	Description    flexString  `json:"description"`
+	SourceExcerpt  flexString  `json:"source_excerpt"`
+}
+
+func (rq rawQuestion) toQuestion(index int) types.Question {
+	serial := index + 1
+	if n, err := strconv.Atoi(strings.TrimSpace(string(rq.SerialNumber))); err == nil {
+		serial = n
+	}
+
+	options := rq.Options.strings()
+	if options == nil {
+		options = []string{}
+	}
+
+	return types.Question{
+		QuestionPrompt: types.QuestionPrompt{
+			SerialNumber:  serial,
+			Type:          string(rq.Type),
+			Question:      string(rq.Question),
+			Options:       options,
+			CaseSensitive: flexBool(rq.CaseSensitive),
+			IgnoreSpaces:  flexBool(rq.IgnoreSpaces),
+		},
+		QuestionSolution: types.QuestionSolution{
+			CorrectAnswer:  string(rq.CorrectAnswer),
+			CorrectAnswers: rq.CorrectAnswers.strings(),
+			Description:    string(rq.Description),
+			SourceExcerpt:  string(rq.SourceExcerpt),
+		},
+	}
+}
+
+// parseEnvelope decodes either envelope the backends use:
+//
+//	[ {"ok": true}, [ ...questions ] ]   (the prompt's format)
+//	{"ok": true, "data": [ ...questions ]} (the Python service's format)
+//
+// A model answer wrapped in a Markdown code fence is accepted as well. The
+// Python service reports failures of the model API as {"ok": false, "error":
+// "...", "status": 429}, which become a StatusError rather than a refusal.
+func parseEnvelope(body []byte) ([]types.Question, error) {
+	body = stripCodeFence(body)
+
+	var ok bool
+	var data []json.RawMessage
+
+	if len(body) > 0 && body[0] == '[' {
+		var parts []json.RawMessage
+		if err := json.Unmarshal(body, &parts); err != nil {
+			return nil, fmt.Errorf("failed to decode quiz response: %w", err)
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("quiz response has %d elements, expected 2", len(parts))
+		}
+		var status struct {
+			OK bool `json:"ok"`
+		}
+		if err := json.Unmarshal(parts[0], &status); err != nil {
+			return nil, fmt.Errorf("invalid or missing 'ok' field: %w", err)
+		}
+		if err := json.Unmarshal(parts[1], &data); err != nil {
+			return nil, fmt.Errorf("invalid quiz data: %w", err)
+		}
+		ok = status.OK
+	} else {
+		var envelope struct {
+			OK     *bool             `json:"ok"`
+			Data   []json.RawMessage `json:"data"`
+			Error  string            `json:"error"`
+			Status int               `json:"status"`
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to decode quiz response: %w", err)
+		}
+		if envelope.OK == nil {
+			return nil, fmt.Errorf("invalid or missing 'ok' field")
+		}
+		if !*envelope.OK && envelope.Error != "" {
+			return nil, &StatusError{Status: envelope.Status, Message: envelope.Error}
+		}
+		ok, data = *envelope.OK, envelope.Data
+	}
+
+	if !ok {
+		msg := "Quiz generation failed"
+		if len(data) > 0 {
+			var s string
+			if err := json.Unmarshal(data[0], &s); err == nil && s != "" {
+				msg = s
+			}
+		}
+		return nil, &RefusedError{Message: msg}
+	}
+
+	questions := make([]types.Question, 0, len(data))
+	for i, item := range data {
+		var rq rawQuestion
+		if err := json.Unmarshal(item, &rq); err != nil {
+			// One malformed item shouldn't cost the whole quiz; the validation
+			// stage asks for a replacement.
+			log.Printf("[generateQuiz] Skipping undecodable question at index %d: %v", i, err)
+			continue
+		}
+		questions = append(questions, rq.toQuestion(i))
+	}
+
+	if len(questions) == 0 {
+		return nil, ErrNoQuestions
+	}
+	return questions, nil
+}
+
+func stripCodeFence(body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	if !bytes.HasPrefix(body, []byte("```")) {
+		return body
+	}
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	}
+	body = bytes.TrimSuffix(bytes.TrimSpace(body), []byte("```"))
+	return bytes.TrimSpace(body)
+}
@@ -0,0 +1,163 @@
+package generateQuiz
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/locale"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// PromptSource assigns each user one of the active prompt templates. It
+// returns nil if there are none, in which case the built-in prompt is used.
+type PromptSource interface {
+	AssignPrompt(ctx context.Context, userID int64) (*types.PromptTemplate, error)
+}
+
+// UsePrompts makes the checker generate with the prompt template source
+// assigns to the user, and record its version in the report.
+func (c *Checker) UsePrompts(source PromptSource) {
+	c.prompts = source
+}
+
+// loadPrompt fills in req.PromptTemplate. If no template can be assigned the
+// built-in prompt is used, so a database hiccup doesn't stop generation.
+func (c *Checker) loadPrompt(ctx context.Context, req *types.QuizRequest) {
+	if c.prompts == nil {
+		return
+	}
+	tmpl, err := c.prompts.AssignPrompt(ctx, req.UserID)
+	if err != nil {
+		log.Printf("[Checker] Assigning a prompt template failed, using the built-in prompt: %v", err)
+		return
+	}
+	req.PromptTemplate = tmpl
+}
+
+// promptData is what prompt templates are executed with. The sections are
+// empty when they don't apply and otherwise end with a blank line, so a
+// template can simply list them.
+type promptData struct {
+	Topic        string
+	NumQuestions int
+	Difficulty   string
+	// Level is the 1-10 level of an adaptive quiz, 0 otherwise.
+	Level int
+	// Language is the BCP-47 tag, LanguageName its display name.
+	Language      string
+	LanguageName  string
+	QuestionTypes []string
+	PastQuestions []string
+	Material      []string
+
+	QuestionTypesSection string
+	MaterialSection      string
+	PastQuestionsSection string
+	LanguageSection      string
+}
+
+func newPromptData(quizRequest *types.QuizRequest) promptData {
+	language := quizRequest.Language
+	if language == "" {
+		language = locale.Default
+	}
+	return promptData{
+		Topic:                quizRequest.Topic,
+		NumQuestions:         quizRequest.NumQuestions,
+		Difficulty:           quizRequest.Difficulty,
+		Level:                quizRequest.Level,
+		Language:             language,
+		LanguageName:         locale.Name(language),
+		QuestionTypes:        requestedTypes(quizRequest),
+		PastQuestions:        quizRequest.PastQuestions,
+		Material:             quizRequest.Material,
+		QuestionTypesSection: questionTypesSection(quizRequest.QuestionTypes),
+		MaterialSection:      materialSection(quizRequest.Material),
+		PastQuestionsSection: pastQuestionsSection(quizRequest.PastQuestions),
+		LanguageSection:      languageSection(quizRequest.Language),
+	}
+}
+
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+var defaultPrompt = template.Must(template.New("builtin").Funcs(promptFuncs).Parse(DefaultPromptTemplate))
+
+// parsedPrompts caches parsed stored templates by ID. A template's body never
+// changes; edits are saved as a new version.
+var parsedPrompts sync.Map
+
+// ParsePromptTemplate parses body and tries it on a sample request, so that
+// templates with unknown placeholders are rejected when they are saved
+// rather than failing generations.
+func ParsePromptTemplate(body string) (*template.Template, error) {
+	tmpl, err := template.New("prompt").Funcs(promptFuncs).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	sample := &types.QuizRequest{
+		Topic:         "Photosynthesis",
+		NumQuestions:  10,
+		Difficulty:    "medium",
+		Level:         5,
+		Language:      "bn",
+		QuestionTypes: []string{types.QuestionMCQ, types.QuestionTrueFalse},
+		PastQuestions: []string{"What do plants release during photosynthesis?"},
+		Material:      []string{"Photosynthesis converts light energy into chemical energy."},
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, newPromptData(sample)); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return nil, fmt.Errorf("template renders an empty prompt")
+	}
+	return tmpl, nil
+}
+
+// generatePrompt renders the request's prompt template, or the built-in
+// prompt if it has none. A stored template that fails to render falls back
+// to the built-in prompt.
+func generatePrompt(quizRequest *types.QuizRequest) string {
+	data := newPromptData(quizRequest)
+	if stored := quizRequest.PromptTemplate; stored != nil {
+		prompt, err := renderStored(stored, data)
+		if err == nil {
+			return prompt
+		}
+		log.Printf("[Prompt] Template %s failed, using the built-in prompt: %v", stored.Label(), err)
+	}
+
+	var sb strings.Builder
+	if err := defaultPrompt.Execute(&sb, data); err != nil {
+		// The built-in template is parsed at init and only uses promptData
+		// fields, so this can't happen.
+		panic(err)
+	}
+	return sb.String()
+}
+
+func renderStored(stored *types.PromptTemplate, data promptData) (string, error) {
+	var tmpl *template.Template
+	if cached, ok := parsedPrompts.Load(stored.ID); ok {
+		tmpl = cached.(*template.Template)
+	} else {
+		var err error
+		tmpl, err = template.New(stored.Label()).Funcs(promptFuncs).Option("missingkey=error").Parse(stored.Body)
+		if err != nil {
+			return "", err
+		}
+		parsedPrompts.Store(stored.ID, tmpl)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
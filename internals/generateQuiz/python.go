@@ -0,0 +1,357 @@
+package generateQuiz
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// ErrPoolBusy is returned when every worker stayed busy for the whole queue
+// timeout. Callers should ask the client to retry later.
+var ErrPoolBusy = errors.New("all quiz workers are busy")
+
+// ErrPoolClosed is returned for requests made after Close.
+var ErrPoolClosed = errors.New("quiz worker pool is closed")
+
+// maxResponseLine bounds a single JSON-lines response from a worker.
+const maxResponseLine = 4 << 20
+
+type PoolConfig struct {
+	PythonPath string
+	ScriptPath string
+	// Env is added to the workers' environment.
+	Env []string
+	// Size is the number of long-lived worker processes.
+	Size int
+	// RequestTimeout bounds one generation inside a worker. A worker that
+	// exceeds it is killed and replaced.
+	RequestTimeout time.Duration
+	// QueueTimeout bounds how long a request waits for an idle worker.
+	QueueTimeout time.Duration
+}
+
+// PythonPool keeps quizlogic/app.py running in --worker mode and sends it one
+// JSON line per request, so the interpreter, LangChain and the database
+// connection are set up once per process instead of once per quiz.
+type PythonPool struct {
+	cfg PoolConfig
+
+	// idle holds one slot per worker. A slot whose process has exited is
+	// restarted by whoever takes it next.
+	idle   chan *pythonWorker
+	closed chan struct{}
+	once   sync.Once
+
+	mu      sync.Mutex
+	workers map[*pythonWorker]struct{}
+
+	nextID atomic.Uint64
+}
+
+type pythonWorker struct {
+	slot   int
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan []byte
+	exited chan struct{}
+}
+
+type workerMessage struct {
+	ID      string             `json:"id"`
+	Request *types.QuizRequest `json:"request"`
+	// Prompt is rendered here from the user's assigned template or the
+	// default one; the worker has no prompt of its own.
+	Prompt string `json:"prompt"`
+}
+
+// NewPythonPool starts cfg.Size workers. Workers that fail to start are
+// logged and retried on first use, so a missing virtualenv doesn't stop the
+// server from booting.
+func NewPythonPool(cfg PoolConfig) *PythonPool {
+	if cfg.PythonPath == "" {
+		if runtime.GOOS == "windows" {
+			cfg.PythonPath = filepath.Join("quizlogic", "venv", "Scripts", "python.exe")
+		} else {
+			cfg.PythonPath = filepath.Join("quizlogic", "venv", "bin", "python")
+		}
+	}
+	if cfg.ScriptPath == "" {
+		cfg.ScriptPath = filepath.Join("quizlogic", "app.py")
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = 1
+	}
+
+	p := &PythonPool{
+		cfg:     cfg,
+		idle:    make(chan *pythonWorker, cfg.Size),
+		closed:  make(chan struct{}),
+		workers: make(map[*pythonWorker]struct{}),
+	}
+
+	for i := 0; i < cfg.Size; i++ {
+		w, err := p.spawn(i)
+		if err != nil {
+			log.Printf("[PythonPool] Failed to start worker %d: %v", i, err)
+			w = &pythonWorker{slot: i}
+		}
+		p.idle <- w
+	}
+
+	return p
+}
+
+func (p *PythonPool) Generate(ctx context.Context, quizRequest *types.QuizRequest) ([]types.Question, error) {
+	w, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := p.roundTrip(ctx, w, quizRequest)
+	if err != nil {
+		// The worker may be stuck mid-request; replace it rather than reuse it.
+		log.Printf("[PythonPool] Worker %d failed: %v", w.slot, err)
+		p.release(p.replace(w))
+		return nil, err
+	}
+
+	p.release(w)
+
+	var reply struct {
+		Usage *struct {
+			PromptTokens   int `json:"prompt_tokens"`
+			ResponseTokens int `json:"response_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(line, &reply); err == nil && reply.Usage != nil {
+		addTokens(ctx, reply.Usage.PromptTokens, reply.Usage.ResponseTokens)
+	}
+	return parseEnvelope(line)
+}
+
+// acquire waits for an idle worker, restarting it if its process has died.
+func (p *PythonPool) acquire(ctx context.Context) (*pythonWorker, error) {
+	timer := time.NewTimer(p.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	var w *pythonWorker
+	select {
+	case w = <-p.idle:
+	case <-timer.C:
+		return nil, ErrPoolBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	}
+
+	if !w.alive() {
+		w = p.replace(w)
+		if !w.alive() {
+			p.release(w)
+			return nil, fmt.Errorf("quiz worker %d is unavailable", w.slot)
+		}
+	}
+	return w, nil
+}
+
+func (p *PythonPool) release(w *pythonWorker) {
+	select {
+	case <-p.closed:
+		p.stop(w)
+	default:
+		p.idle <- w
+	}
+}
+
+func (p *PythonPool) roundTrip(ctx context.Context, w *pythonWorker, quizRequest *types.QuizRequest) ([]byte, error) {
+	id := strconv.FormatUint(p.nextID.Add(1), 10)
+	message := workerMessage{ID: id, Request: quizRequest, Prompt: generatePrompt(quizRequest)}
+	msg, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal quiz request: %w", err)
+	}
+
+	if _, err := w.stdin.Write(append(msg, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write to worker: %w", err)
+	}
+
+	timer := time.NewTimer(p.cfg.RequestTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				return nil, fmt.Errorf("quiz worker exited")
+			}
+			var reply struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(line, &reply); err != nil {
+				return nil, fmt.Errorf("invalid response from quiz worker: %w", err)
+			}
+			if reply.ID != id {
+				// A late answer to a request that was abandoned; skip it.
+				continue
+			}
+			return line, nil
+		case <-timer.C:
+			return nil, fmt.Errorf("quiz worker timed out after %v", p.cfg.RequestTimeout)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// replace stops w and starts a fresh process in its slot. If the new process
+// cannot be started, a dead placeholder is returned so the slot is kept.
+func (p *PythonPool) replace(w *pythonWorker) *pythonWorker {
+	p.stop(w)
+
+	select {
+	case <-p.closed:
+		return &pythonWorker{slot: w.slot}
+	default:
+	}
+
+	fresh, err := p.spawn(w.slot)
+	if err != nil {
+		log.Printf("[PythonPool] Failed to restart worker %d: %v", w.slot, err)
+		return &pythonWorker{slot: w.slot}
+	}
+	log.Printf("[PythonPool] Worker %d restarted", w.slot)
+	return fresh
+}
+
+func (p *PythonPool) spawn(slot int) (*pythonWorker, error) {
+	cmd := exec.Command(p.cfg.PythonPath, p.cfg.ScriptPath, "--worker")
+	cmd.Stderr = os.Stderr
+	if len(p.cfg.Env) > 0 {
+		cmd.Env = append(os.Environ(), p.cfg.Env...)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	// An explicit pipe rather than StdoutPipe, so that Wait returning on a
+	// crash doesn't close the read end under the scanner.
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	cmd.Stdout = stdoutW
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		stdoutW.Close()
+		return nil, fmt.Errorf("failed to start worker: %w", err)
+	}
+	stdoutW.Close()
+
+	w := &pythonWorker{
+		slot:   slot,
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan []byte),
+		exited: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.lines)
+		defer stdout.Close()
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), maxResponseLine)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case w.lines <- line:
+			case <-w.exited:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		err := cmd.Wait()
+		log.Printf("[PythonPool] Worker %d (pid %d) exited: %v", slot, cmd.Process.Pid, err)
+		close(w.exited)
+	}()
+
+	p.mu.Lock()
+	p.workers[w] = struct{}{}
+	p.mu.Unlock()
+
+	return w, nil
+}
+
+func (w *pythonWorker) alive() bool {
+	if w.cmd == nil {
+		return false
+	}
+	select {
+	case <-w.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// stop closes the worker's stdin, which ends its read loop, and kills it if
+// it hasn't exited shortly after.
+func (p *PythonPool) stop(w *pythonWorker) {
+	p.mu.Lock()
+	delete(p.workers, w)
+	p.mu.Unlock()
+
+	if w.cmd == nil {
+		return
+	}
+
+	w.stdin.Close()
+	select {
+	case <-w.exited:
+	case <-time.After(2 * time.Second):
+		w.cmd.Process.Kill()
+		<-w.exited
+	}
+}
+
+// Close stops every worker. Requests still running are cut off.
+func (p *PythonPool) Close() error {
+	p.once.Do(func() {
+		close(p.closed)
+
+		p.mu.Lock()
+		workers := make([]*pythonWorker, 0, len(p.workers))
+		for w := range p.workers {
+			workers = append(workers, w)
+		}
+		p.mu.Unlock()
+
+		var wg sync.WaitGroup
+		for _, w := range workers {
+			wg.Add(1)
+			go func(w *pythonWorker) {
+				defer wg.Done()
+				p.stop(w)
+			}(w)
+		}
+		wg.Wait()
+	})
+	return nil
+}
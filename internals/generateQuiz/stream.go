@@ -0,0 +1,101 @@
+package generateQuiz
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// StreamingGenerator is implemented by backends that can hand out questions
+// while the model is still producing the rest of the quiz.
+type StreamingGenerator interface {
+	GenerateStream(ctx context.Context, quizRequest *types.QuizRequest, emit func(types.Question) error) error
+}
+
+// Stream calls emit for every generated question. Backends that can't stream
+// generate the whole quiz first and then emit the questions one by one. An
+// error returned by emit stops the stream and is returned as is.
+func Stream(ctx context.Context, generator QuizGenerator, quizRequest *types.QuizRequest, emit func(types.Question) error) error {
+	if sg, ok := generator.(StreamingGenerator); ok {
+		return sg.GenerateStream(ctx, quizRequest, emit)
+	}
+
+	questions, err := generator.Generate(ctx, quizRequest)
+	if err != nil {
+		return err
+	}
+	for _, question := range questions {
+		if err := emit(question); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// questionScanner picks complete question objects out of a partially
+// received model answer. It tracks bracket nesting outside of JSON strings
+// and reports every object whose parent is the second-level array, which is
+// where both envelopes (see parseEnvelope) keep their questions.
+type questionScanner struct {
+	buf      []byte
+	pos      int
+	stack    []byte
+	inString bool
+	escaped  bool
+	objStart int
+}
+
+func newQuestionScanner() *questionScanner {
+	return &questionScanner{objStart: -1}
+}
+
+// Write appends a chunk of model output and returns the raw question objects
+// completed by it.
+func (s *questionScanner) Write(chunk string) []json.RawMessage {
+	s.buf = append(s.buf, chunk...)
+
+	var done []json.RawMessage
+	for ; s.pos < len(s.buf); s.pos++ {
+		c := s.buf[s.pos]
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			s.inString = true
+		case '[':
+			s.stack = append(s.stack, c)
+		case '{':
+			if s.objStart < 0 && len(s.stack) == 2 && s.stack[1] == '[' {
+				s.objStart = s.pos
+			}
+			s.stack = append(s.stack, c)
+		case ']', '}':
+			if len(s.stack) == 0 {
+				continue
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+			if c == '}' && s.objStart >= 0 && len(s.stack) == 2 {
+				done = append(done, json.RawMessage(append([]byte(nil), s.buf[s.objStart:s.pos+1]...)))
+				s.objStart = -1
+			}
+		}
+	}
+	return done
+}
+
+// Bytes returns everything written so far.
+func (s *questionScanner) Bytes() []byte {
+	return s.buf
+}
@@ -0,0 +1,108 @@
+package generateQuiz
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Usage accumulates what one quiz generation cost across all of its model
+// calls. Attach it to the context with WithUsage before calling the Checker;
+// providers add their token counts and the Chain the providers that answered.
+type Usage struct {
+	mu             sync.Mutex
+	providers      []string
+	promptTokens   int
+	responseTokens int
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context whose generations are accounted to u.
+func WithUsage(ctx context.Context, u *Usage) context.Context {
+	return context.WithValue(ctx, usageKey{}, u)
+}
+
+func usageFrom(ctx context.Context) *Usage {
+	u, _ := ctx.Value(usageKey{}).(*Usage)
+	return u
+}
+
+// addTokens records the token counts a model reported for one call.
+func addTokens(ctx context.Context, prompt, response int) {
+	u := usageFrom(ctx)
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.promptTokens += prompt
+	u.responseTokens += response
+}
+
+// addProvider records that the named provider answered.
+func addProvider(ctx context.Context, name string) {
+	u := usageFrom(ctx)
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, p := range u.providers {
+		if p == name {
+			return
+		}
+	}
+	u.providers = append(u.providers, name)
+}
+
+// Providers returns the providers that answered, comma-separated in the
+// order they first did.
+func (u *Usage) Providers() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return strings.Join(u.providers, ",")
+}
+
+// Tokens returns the prompt and response tokens used so far.
+func (u *Usage) Tokens() (prompt, response int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.promptTokens, u.responseTokens
+}
+
+// Record returns the ledger entry for a generation that took latency and
+// ended with err.
+func (u *Usage) Record(userID int64, jobID *int64, latency time.Duration, err error) *types.UsageRecord {
+	prompt, response := u.Tokens()
+	record := &types.UsageRecord{
+		UserID:         userID,
+		JobID:          jobID,
+		Provider:       u.Providers(),
+		PromptTokens:   prompt,
+		ResponseTokens: response,
+		LatencyMs:      latency.Milliseconds(),
+		Outcome:        types.UsageSucceeded,
+	}
+	if err == nil {
+		return record
+	}
+
+	record.Error = err.Error()
+	var refused *RefusedError
+	switch {
+	case errors.As(err, &refused):
+		record.Outcome = types.UsageRefused
+	case errors.Is(err, ErrUnavailable):
+		record.Outcome = types.UsageUnavailable
+	case errors.Is(err, context.Canceled):
+		record.Outcome = types.UsageCancelled
+	default:
+		record.Outcome = types.UsageFailed
+	}
+	return record
+}
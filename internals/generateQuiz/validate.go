@@ -0,0 +1,448 @@
+package generateQuiz
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/scoring"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// optionsPerQuestion is the number of choices every MCQ must have.
+const optionsPerQuestion = 4
+
+// maxMultiSelectOptions bounds the choices of a multi_select question.
+const maxMultiSelectOptions = 6
+
+// minOrderingItems is the fewest items an ordering question may have.
+const minOrderingItems = 3
+
+// optionLabel matches a leading "A)", "b.", "(C)" or "D:" style label.
+var optionLabel = regexp.MustCompile(`^\(?([A-Fa-f])[\).:]\s+`)
+
+// optionIndex matches an answer that names an option by its position: a
+// bare letter ("B", "(b)") or "option 2" / "choice C". A bare number isn't
+// one, as it is as likely to be a numeric answer the model got wrong.
+var optionIndex = regexp.MustCompile(`(?i)^(?:(?:option|choice)\s*#?\s*([1-6]|[a-f])|\(?([a-f])\)?[.:]?)$`)
+
+// questionTypeAliases maps the spellings models use to question types.
+var questionTypeAliases = map[string]string{
+	"":                  types.QuestionMCQ,
+	"mcq":               types.QuestionMCQ,
+	"multiple_choice":   types.QuestionMCQ,
+	"single_choice":     types.QuestionMCQ,
+	"multi_select":      types.QuestionMultiSelect,
+	"multiple_select":   types.QuestionMultiSelect,
+	"multiselect":       types.QuestionMultiSelect,
+	"multiple_answer":   types.QuestionMultiSelect,
+	"true_false":        types.QuestionTrueFalse,
+	"truefalse":         types.QuestionTrueFalse,
+	"boolean":           types.QuestionTrueFalse,
+	"fill_in":           types.QuestionFillIn,
+	"fill_in_the_blank": types.QuestionFillIn,
+	"fill_in_blank":     types.QuestionFillIn,
+	"fill_blank":        types.QuestionFillIn,
+	"ordering":          types.QuestionOrdering,
+	"order":             types.QuestionOrdering,
+	"sequence":          types.QuestionOrdering,
+	"sequencing":        types.QuestionOrdering,
+}
+
+// QuestionType returns the canonical question type for name, accepting
+// common variants such as "true/false" or "fill-in-the-blank".
+func QuestionType(name string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	key = strings.NewReplacer("-", "_", " ", "_", "/", "_").Replace(key)
+	t, ok := questionTypeAliases[key]
+	return t, ok
+}
+
+// normalizeQuestion trims and repairs q. It returns the repaired question,
+// notes describing each repair, and a non-empty reason if q must be dropped.
+func normalizeQuestion(q types.Question) (types.Question, []string, string) {
+	q.Question = strings.TrimSpace(q.Question)
+	q.CorrectAnswer = strings.TrimSpace(q.CorrectAnswer)
+	q.Description = strings.TrimSpace(q.Description)
+
+	if q.Question == "" {
+		return q, nil, "empty question text"
+	}
+
+	kind, ok := QuestionType(q.Type)
+	if !ok {
+		return q, nil, fmt.Sprintf("unknown question type %q", q.Type)
+	}
+	q.Type = kind
+
+	switch kind {
+	case types.QuestionMultiSelect:
+		return normalizeMultiSelect(q)
+	case types.QuestionTrueFalse:
+		return normalizeTrueFalse(q)
+	case types.QuestionFillIn:
+		return normalizeFillIn(q)
+	case types.QuestionOrdering:
+		return normalizeOrdering(q)
+	default:
+		return normalizeMCQ(q)
+	}
+}
+
+func normalizeMCQ(q types.Question) (types.Question, []string, string) {
+	options, notes := cleanOptions(q.Options)
+	q.CorrectAnswers = nil
+
+	correct, note, ok := matchAnswer(q.CorrectAnswer, options, q.Options)
+	if !ok {
+		return q, nil, fmt.Sprintf("correctAnswer %q is not one of the options", q.CorrectAnswer)
+	}
+	if note != "" {
+		notes = append(notes, note)
+	}
+	q.CorrectAnswer = correct
+
+	if len(options) < optionsPerQuestion {
+		return q, nil, fmt.Sprintf("only %d distinct options", len(options))
+	}
+	if len(options) > optionsPerQuestion {
+		options = trimOptions(options, []string{correct}, optionsPerQuestion)
+		notes = append(notes, fmt.Sprintf("trimmed options to %d", optionsPerQuestion))
+	}
+	q.Options = options
+
+	return q, notes, ""
+}
+
+// normalizeMultiSelect requires at least one correct option. Answers given
+// only in correctAnswer as a comma or semicolon separated list are split.
+func normalizeMultiSelect(q types.Question) (types.Question, []string, string) {
+	options, notes := cleanOptions(q.Options)
+
+	answers := q.CorrectAnswers
+	if len(answers) == 0 && q.CorrectAnswer != "" {
+		answers = strings.FieldsFunc(q.CorrectAnswer, func(r rune) bool { return r == ',' || r == ';' })
+		notes = append(notes, "split correctAnswer into correctAnswers")
+	}
+
+	var correct []string
+	seen := make(map[string]bool)
+	for _, answer := range answers {
+		match, note, ok := matchAnswer(strings.TrimSpace(answer), options, q.Options)
+		if !ok {
+			return q, nil, fmt.Sprintf("correct answer %q is not one of the options", answer)
+		}
+		if note != "" {
+			notes = append(notes, note)
+		}
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+		correct = append(correct, match)
+	}
+	if len(correct) == 0 {
+		return q, nil, "no correct answers"
+	}
+
+	if len(options) < optionsPerQuestion {
+		return q, nil, fmt.Sprintf("only %d distinct options", len(options))
+	}
+	if len(options) > maxMultiSelectOptions {
+		if len(correct) > maxMultiSelectOptions-1 {
+			return q, nil, fmt.Sprintf("%d correct answers", len(correct))
+		}
+		options = trimOptions(options, correct, maxMultiSelectOptions)
+		notes = append(notes, fmt.Sprintf("trimmed options to %d", maxMultiSelectOptions))
+	}
+	q.Options = options
+
+	// Keep the correct answers in option order.
+	q.CorrectAnswers = q.CorrectAnswers[:0:0]
+	for _, option := range options {
+		if seen[option] {
+			q.CorrectAnswers = append(q.CorrectAnswers, option)
+		}
+	}
+	q.CorrectAnswer = strings.Join(q.CorrectAnswers, ", ")
+
+	return q, notes, ""
+}
+
+func normalizeTrueFalse(q types.Question) (types.Question, []string, string) {
+	var notes []string
+
+	value, ok := parseTruth(q.CorrectAnswer)
+	if !ok && len(q.Options) > 0 {
+		// "A"/"B" or "1"/"2" against the options as sent.
+		if match, _, found := matchAnswer(q.CorrectAnswer, trimAll(q.Options), q.Options); found {
+			value, ok = parseTruth(match)
+		}
+	}
+	if !ok {
+		return q, nil, fmt.Sprintf("correctAnswer %q is neither true nor false", q.CorrectAnswer)
+	}
+
+	answer := "False"
+	if value {
+		answer = "True"
+	}
+	if q.CorrectAnswer != answer {
+		notes = append(notes, fmt.Sprintf("correctAnswer %q resolved to %q", q.CorrectAnswer, answer))
+	}
+	if len(q.Options) != 2 || q.Options[0] != "True" || q.Options[1] != "False" {
+		notes = append(notes, "set options to True/False")
+	}
+
+	q.Options = []string{"True", "False"}
+	q.CorrectAnswer = answer
+	q.CorrectAnswers = nil
+	return q, notes, ""
+}
+
+func parseTruth(s string) (bool, bool) {
+	value, ok := truthWords[strings.ToLower(strings.Trim(strings.TrimSpace(norm.NFC.String(s)), ".!।"))]
+	return value, ok
+}
+
+// truthWords are the spellings of true and false that models use, including
+// translations when the quiz isn't in English.
+var truthWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range []string{"true", "t", "yes", "correct", "verdadero", "verdadeiro", "vrai", "wahr", "सही", "सत्य", "সত্য", "সঠিক", "ঠিক"} {
+		words[norm.NFC.String(w)] = true
+	}
+	for _, w := range []string{"false", "f", "no", "incorrect", "falso", "faux", "falsch", "गलत", "ग़लत", "असत्य", "মিথ্যা", "ভুল"} {
+		words[norm.NFC.String(w)] = false
+	}
+	return words
+}()
+
+// normalizeFillIn keeps the distinct accepted answers; correctAnswer counts as
+// one of them.
+func normalizeFillIn(q types.Question) (types.Question, []string, string) {
+	var notes []string
+
+	if len(q.Options) > 0 {
+		notes = append(notes, "removed options from fill-in question")
+	}
+	q.Options = []string{}
+
+	var variants []string
+	seen := make(map[string]bool)
+	for _, answer := range append([]string{q.CorrectAnswer}, q.CorrectAnswers...) {
+		answer = strings.Join(strings.Fields(answer), " ")
+		key := scoring.FillInKey(answer, q.CaseSensitive, q.IgnoreSpaces)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		variants = append(variants, answer)
+	}
+	if len(variants) == 0 {
+		return q, nil, "no accepted answers"
+	}
+
+	q.CorrectAnswers = variants
+	q.CorrectAnswer = variants[0]
+	return q, notes, ""
+}
+
+// normalizeOrdering requires correctAnswers to be a permutation of the
+// options. Options listed in the correct order are shuffled, since the client
+// shows them as given.
+func normalizeOrdering(q types.Question) (types.Question, []string, string) {
+	options, notes := cleanOptions(q.Options)
+	if len(options) != len(q.Options) {
+		// A removed duplicate makes the order ambiguous.
+		return q, nil, "ordering items must be distinct and non-empty"
+	}
+	if len(options) < minOrderingItems {
+		return q, nil, fmt.Sprintf("only %d items to order", len(options))
+	}
+	if len(q.CorrectAnswers) != len(options) {
+		return q, nil, fmt.Sprintf("correctAnswers has %d items, expected %d", len(q.CorrectAnswers), len(options))
+	}
+
+	order := make([]string, 0, len(options))
+	used := make(map[string]bool)
+	for _, item := range q.CorrectAnswers {
+		match, note, ok := matchAnswer(strings.TrimSpace(item), options, q.Options)
+		if !ok || used[match] {
+			return q, nil, fmt.Sprintf("correctAnswers is not a permutation of the options (%q)", item)
+		}
+		if note != "" {
+			notes = append(notes, note)
+		}
+		used[match] = true
+		order = append(order, match)
+	}
+
+	if equalStrings(options, order) {
+		options = shuffled(options, q.Question)
+		notes = append(notes, "shuffled items that were listed in the correct order")
+	}
+
+	q.Options = options
+	q.CorrectAnswers = order
+	q.CorrectAnswer = strings.Join(order, ", ")
+	return q, notes, ""
+}
+
+// shuffled returns a permutation of items other than their current order,
+// seeded by seed so that the same question is always shuffled the same way.
+func shuffled(items []string, seed string) []string {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	out := append([]string(nil), items...)
+	for equalStrings(out, items) {
+		rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanOptions trims options and removes empty and duplicate ones. "A) ..."
+// labels are stripped, but only when every option carries one, so an answer
+// that merely starts with a letter is left alone.
+func cleanOptions(raw []string) ([]string, []string) {
+	var notes []string
+
+	labelled := len(raw) > 0
+	for _, option := range raw {
+		if !optionLabel.MatchString(strings.TrimSpace(option)) {
+			labelled = false
+			break
+		}
+	}
+
+	options := make([]string, 0, len(raw))
+	seen := make(map[string]bool)
+	for _, option := range raw {
+		option = strings.TrimSpace(option)
+		if labelled {
+			option = optionLabel.ReplaceAllString(option, "")
+		}
+		if option == "" {
+			notes = append(notes, "removed empty option")
+			continue
+		}
+		if seen[strings.ToLower(option)] {
+			notes = append(notes, fmt.Sprintf("removed duplicate option %q", option))
+			continue
+		}
+		seen[strings.ToLower(option)] = true
+		options = append(options, option)
+	}
+	if labelled {
+		notes = append(notes, "stripped option labels")
+	}
+	return options, notes
+}
+
+func trimAll(items []string) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = strings.TrimSpace(optionLabel.ReplaceAllString(strings.TrimSpace(item), ""))
+	}
+	return out
+}
+
+// matchAnswer resolves answer to one of options. Exact matches need no note;
+// case or whitespace differences, option labels ("C", "C) Paris") and
+// explicit indexes ("option 2") are repaired. Anything else doesn't match,
+// including a number that is none of the options. original is the option list as sent by the
+// model, which labels and indexes refer to.
+func matchAnswer(answer string, options, original []string) (string, string, bool) {
+	for _, option := range options {
+		if option == answer {
+			return option, "", true
+		}
+	}
+	for _, option := range options {
+		if strings.EqualFold(strings.Join(strings.Fields(option), " "), strings.Join(strings.Fields(answer), " ")) {
+			return option, fmt.Sprintf("correctAnswer %q matched option %q", answer, option), true
+		}
+	}
+
+	stripped := optionLabel.ReplaceAllString(answer, "")
+	if stripped != answer {
+		for _, option := range options {
+			if strings.EqualFold(option, stripped) {
+				return option, fmt.Sprintf("correctAnswer %q matched option %q", answer, option), true
+			}
+		}
+	}
+
+	index := -1
+	if m := optionIndex.FindStringSubmatch(strings.TrimSpace(answer)); m != nil {
+		name := m[1] + m[2]
+		if n, err := strconv.Atoi(name); err == nil {
+			index = n - 1
+		} else {
+			index = int(strings.ToUpper(name)[0] - 'A')
+		}
+	}
+	if index >= 0 && index < len(original) {
+		target := strings.TrimSpace(optionLabel.ReplaceAllString(strings.TrimSpace(original[index]), ""))
+		for _, option := range options {
+			if strings.EqualFold(option, target) {
+				return option, fmt.Sprintf("correctAnswer %q resolved to option %q", answer, option), true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// trimOptions keeps the correct answers and the first other options up to
+// limit, preserving their order.
+func trimOptions(options []string, correct []string, limit int) []string {
+	isCorrect := make(map[string]bool, len(correct))
+	for _, c := range correct {
+		isCorrect[c] = true
+	}
+
+	kept := make([]string, 0, limit)
+	others := limit - len(correct)
+	for _, option := range options {
+		if isCorrect[option] {
+			kept = append(kept, option)
+		} else if others > 0 {
+			kept = append(kept, option)
+			others--
+		}
+	}
+	return kept
+}
+
+// questionKey normalizes question text for duplicate detection.
+func questionKey(question string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFC.String(question))), " ")
+}
+
+func truncate(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "…"
+}
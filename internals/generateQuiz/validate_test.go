@@ -0,0 +1,25 @@
+package generateQuiz
+
+import "testing"
+
+func TestMatchAnswer(t *testing.T) {
+	options := []string{"12", "24", "36", "48"}
+	for _, test := range []struct {
+		answer string
+		want   string // "" for no match
+	}{
+		{"24", "24"},
+		{"B", "24"},
+		{"(c)", "36"},
+		{"option 4", "48"},
+		{"Choice A", "12"},
+		{"2", ""}, // a numeric answer, not an index
+		{"60", ""},
+		{"option 9", ""},
+	} {
+		got, _, ok := matchAnswer(test.answer, options, options)
+		if ok != (test.want != "") || got != test.want {
+			t.Errorf("matchAnswer(%q) = %q, %v, want %q", test.answer, got, ok, test.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// GetProviderHealth reports the circuit breaker state of every quiz
+// generation provider, in the order they are tried.
+func GetProviderHealth(checker *generateQuiz.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		reporter, ok := checker.Generator().(interface{ Health() []types.ProviderHealth })
+		if !ok {
+			http.Error(w, "Provider health is not available", http.StatusNotFound)
+			return
+		}
+
+		response.WriteResponse(w, response.CreateResponse(reporter.Health(), http.StatusOK, "Provider health retrieved successfully"))
+	}
+}
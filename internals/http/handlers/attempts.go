@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/scoring"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// StartAttempt opens a new attempt at one of the user's quizzes, starting
+// the clock on timed quizzes. If one is already open it is returned instead,
+// with 200 rather than 201 and the time it has left, so that a reloaded
+// client can resume its countdown.
+func StartAttempt(db *sql.DB, tracker *skill.Tracker, late database.LateSubmission) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz := userQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		closeExpiredAttempt(db, tracker, quiz, late)
+		attempt, created, err := database.StartAttempt(db, quiz.ID, quiz.UserID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !created {
+			response.WriteResponse(w, response.CreateResponse(attempt, http.StatusOK, "Attempt already in progress"))
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(attempt, http.StatusCreated, "Attempt started successfully"))
+	}
+}
+
+// ResumeAttempt returns the open attempt at one of the user's quizzes with
+// the answers saved to it, the question the user was at and, if timed, the
+// time it has left, so that a reopened client can carry on where it stopped.
+// An attempt whose time ran out stays open, with none left, until it is
+// submitted or the expiry sweep closes it.
+func ResumeAttempt(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz := userQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		attempt, err := database.FetchOpenAttempt(db, quiz.ID)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "No attempt in progress", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		questions, err := database.FetchAttemptPrompts(db, quiz.ID, attempt.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		view := types.AttemptView{Attempt: *attempt, Questions: questions}
+		response.WriteResponse(w, response.CreateResponse(view, http.StatusOK, "Attempt resumed successfully"))
+	}
+}
+
+// SaveAttemptAnswers autosaves answers to the open attempt at one of the
+// user's quizzes as they are given, and the question the user is at. The
+// answers are graded when the attempt is submitted. The attempt is returned
+// with its prompts, so that moving to a timed question unlocks it.
+func SaveAttemptAnswers(db *sql.DB, tracker *skill.Tracker, late database.LateSubmission) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz := userQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		var progress types.AttemptProgress
+		if err := json.NewDecoder(r.Body).Decode(&progress); err != nil {
+			if errors.Is(err, io.EOF) {
+				http.Error(w, "No data provided", http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("Failed to decode JSON: %v", err), http.StatusBadRequest)
+			}
+			return
+		}
+		if err := validator.New().Struct(&progress); err != nil {
+			response.ValidateResponse(w, err)
+			return
+		}
+
+		attempt, err := database.SaveAttemptAnswers(db, quiz.ID, quiz.UserID, progress.Answers, progress.Position, late.Grace)
+		if err != nil {
+			switch {
+			case errors.Is(err, database.ErrNotFound):
+				http.Error(w, "Quiz not found", http.StatusNotFound)
+			case errors.Is(err, database.ErrInvalidAnswer):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, database.ErrNoOpenAttempt):
+				http.Error(w, "Start an attempt before saving answers", http.StatusConflict)
+			case errors.Is(err, database.ErrDeadlinePassed):
+				closeExpiredAttempt(db, tracker, quiz, late)
+				http.Error(w, "The attempt's deadline has passed; it was graded with the answers saved before it", http.StatusConflict)
+			case errors.Is(err, database.ErrQuestionTimeUp):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		questions, err := database.FetchAttemptPrompts(db, quiz.ID, attempt.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		view := types.AttemptView{Attempt: *attempt, Questions: questions}
+		response.WriteResponse(w, response.CreateResponse(view, http.StatusOK, "Answers saved successfully"))
+	}
+}
+
+// GetAttempts lists the attempts at a quiz the user may view, latest first.
+func GetAttempts(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz := viewableQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		attempts, err := database.FetchAttempts(db, quiz.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(attempts, http.StatusOK, "Attempts retrieved successfully"))
+	}
+}
+
+// GetAttempt returns one attempt at a quiz the user may view. A finished
+// attempt comes with its graded answers and the solutions, unless a retake
+// is open, when it only comes with the prompts; an open one only with the
+// prompts and the answers saved so far.
+func GetAttempt(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		attemptID, err := strconv.ParseInt(mux.Vars(r)["attemptID"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid attempt ID: %v", err), http.StatusBadRequest)
+			return
+		}
+		quiz := viewableQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		attempt, err := database.FetchAttempt(db, quiz.ID, attemptID)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Attempt not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		reveal, ok := revealSolutions(w, db, quiz)
+		if !ok {
+			return
+		}
+
+		view := types.AttemptView{Attempt: *attempt}
+		if attempt.FinishedAt != nil {
+			view.Questions, err = database.FetchAttemptAnswers(db, attempt.ID, scoring.IsCorrect)
+			if !reveal {
+				for i := range view.Questions {
+					view.Questions[i] = types.QuestionView{Prompt: view.Questions[i].Prompt}
+				}
+			}
+		} else {
+			view.Questions, err = database.FetchAttemptPrompts(db, quiz.ID, attempt.ID)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(view, http.StatusOK, "Attempt retrieved successfully"))
+	}
+}
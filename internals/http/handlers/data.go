@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -10,20 +9,24 @@ import (
 	"log"
 
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
-	"os/exec"
-	"runtime"
-	"path/filepath"
+	"unicode"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
-	//"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/jobs"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/moderation"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/quota"
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/topics"
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
-
-	
 )
 
 // const pythonServerProduction_old = "https://try-your-gyan-quiz-generation.onrender.com/generate-quiz"
@@ -32,17 +35,90 @@ import (
 
 //const pythonServerProduction = "https://try-your-gyan-quiz-generation-fastapi.onrender.com/generate-quiz"
 
+// topicFillers are request phrasings that aren't part of the topic, in the
+// languages users most often type them in. Longer phrases come first so that
+// "a quiz on" is removed before "quiz on" could leave the "a" behind.
+var topicFillers = normalizedPhrases(
+	"generate me a quiz on", "generate a quiz on", "create a quiz about", "a quiz about", "a quiz on", "quiz about", "quiz on",
+	"genera un cuestionario sobre", "un cuestionario sobre", "cuestionario sobre", "un quiz sobre", "quiz sobre",
+	"génère un quiz sur", "un quiz sur", "quiz sur",
+	"erstelle ein quiz über", "ein quiz über", "quiz über", "quiz zu",
+	"के बारे में क्विज़", "पर एक क्विज़", "पर क्विज़", "पर क्विज",
+	"সম্পর্কে একটি কুইজ", "নিয়ে একটি কুইজ", "সম্পর্কে কুইজ", "বিষয়ে কুইজ", "নিয়ে কুইজ", "এর উপর কুইজ",
+)
 
+func normalizedPhrases(phrases ...string) [][]string {
+	out := make([][]string, len(phrases))
+	for i, phrase := range phrases {
+		out[i] = strings.Fields(norm.NFC.String(phrase))
+	}
+	return out
+}
+
+// normalizeTopic lower-cases the topic, brings it to Unicode NFC (so that
+// e.g. precomposed and combining Bengali or Devanagari nukta forms compare
+// equal), collapses all kinds of whitespace, drops invisible formatting
+// characters other than the joiners Indic scripts depend on, and removes
+// filler phrases such as "quiz on" on word boundaries.
 func normalizeTopic(topic string) string {
-	topic = strings.ToLower(strings.TrimSpace(topic))
-	phrasesToRemove := []string{"generate me a quiz on", "create a quiz about", "quiz on", "a quiz on"}
-	for _, phrase := range phrasesToRemove {
-		topic = strings.ReplaceAll(topic, phrase, "")
+	topic = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) && r != '\u200c' && r != '\u200d' {
+			return -1
+		}
+		return r
+	}, norm.NFC.String(topic))
+	words := strings.Fields(strings.ToLower(topic))
+
+	for _, phrase := range topicFillers {
+		for i := 0; i+len(phrase) <= len(words); {
+			if slices.Equal(words[i:i+len(phrase)], phrase) {
+				words = slices.Delete(words, i, i+len(phrase))
+				continue
+			}
+			i++
+		}
+	}
+
+	return strings.TrimFunc(strings.Join(words, " "), func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+// applyAdaptiveDifficulty resolves an "adaptive" difficulty to the one
+// recommended for the user, falling back to medium if there is no estimate.
+func applyAdaptiveDifficulty(tracker *skill.Tracker, quizRequest *types.QuizRequest) {
+	if err := tracker.ApplyAdaptive(quizRequest); err != nil {
+		log.Printf("[applyAdaptiveDifficulty] Falling back to medium for user %d: %v", quizRequest.UserID, err)
+		quizRequest.Difficulty = "medium"
+	}
+}
+
+// applyTopic resolves the request's topic to its canonical topic. If the
+// taxonomy can't be loaded the quiz goes ahead without one.
+func applyTopic(r *http.Request, resolver *topics.Resolver, quizRequest *types.QuizRequest) {
+	if err := resolver.Apply(r.Context(), quizRequest); err != nil {
+		log.Printf("[applyTopic] Not resolving topic %q: %v", quizRequest.Topic, err)
 	}
-	return strings.TrimSpace(topic)
 }
 
-func GenerateQuiz() http.HandlerFunc {
+// applyPreferredLanguage fills in the user's preferred language when the
+// request doesn't name one. ValidateRequest falls back to English if there is
+// no preference either.
+func applyPreferredLanguage(db *sql.DB, quizRequest *types.QuizRequest) {
+	if strings.TrimSpace(quizRequest.Language) != "" {
+		return
+	}
+	language, err := database.FetchPreferredLanguage(db, quizRequest.UserID)
+	if err != nil {
+		log.Printf("[applyPreferredLanguage] Falling back to the default language for user %d: %v", quizRequest.UserID, err)
+		return
+	}
+	quizRequest.Language = language
+}
+
+// GenerateQuiz validates the request and queues a generation job. The client
+// polls GetQuizJob with the returned job ID for the result.
+func GenerateQuiz(db *sql.DB, runner *jobs.Runner, limiter *quota.Limiter, screener *moderation.Screener, tracker *skill.Tracker, resolver *topics.Resolver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := log.New(log.Writer(), "[GenerateQuiz] ", log.LstdFlags)
 
@@ -60,127 +136,114 @@ func GenerateQuiz() http.HandlerFunc {
 		}
 
 		var quizRequest types.QuizRequest
-		quizRequest.UserID = int64(userID)
 
 		if err := json.NewDecoder(r.Body).Decode(&quizRequest); err != nil {
 			logger.Printf("Failed to decode JSON: %v", err)
 			http.Error(w, "Failed to decode request body", http.StatusInternalServerError)
 			return
 		}
+		quizRequest.UserID = int64(userID)
+		quizRequest.PastQuestions = nil // chosen by the server
+		if !applyMaterial(w, db, &quizRequest) {
+			return
+		}
+		applyPreferredLanguage(db, &quizRequest)
+		if !screenTopic(w, r, screener, quizRequest.UserID, quizRequest.Topic) {
+			return
+		}
 
 		// Normalize topic and difficulty
 		quizRequest.Topic = normalizeTopic(quizRequest.Topic)
 		quizRequest.Difficulty = strings.ToLower(quizRequest.Difficulty)
+		applyTopic(r, resolver, &quizRequest)
+		applyAdaptiveDifficulty(tracker, &quizRequest)
 
-		// Marshal struct to JSON
-		jsonData, err := json.Marshal(quizRequest)
-		if err != nil {
-			logger.Printf("Failed to marshal quizRequest: %v", err)
-			http.Error(w, "Failed to process request", http.StatusInternalServerError)
+		if err := generateQuiz.ValidateRequest(&quizRequest); err != nil {
+			logger.Printf("Invalid quiz request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		logger.Printf("Executing Python quiz generation with input: %s", string(jsonData))
-
-		// Determine pythonPath dynamically based on OS
-		var pythonPath string
-		if runtime.GOOS == "windows" {
-			pythonPath = filepath.Join("quizlogic", "venv", "Scripts", "python.exe")
-		} else {
-			pythonPath = filepath.Join("quizlogic", "venv", "bin", "python")
-		}
-		scriptPath := filepath.Join("quizlogic", "app.py")
-
-		// Execute Python script as a subprocess
-		cmd := exec.Command(pythonPath, scriptPath)
-
-		// Set up stdin for Python script
-		stdin, err := cmd.StdinPipe()
-		if err != nil {
-			logger.Printf("Failed to create stdin pipe: %v", err)
-			http.Error(w, "Failed to initialize quiz generation", http.StatusInternalServerError)
+		var jobID int64
+		if !reserveQuota(w, limiter, quizRequest.UserID, func(tx *sql.Tx) (err error) {
+			jobID, err = database.InsertQuizJob(tx, &quizRequest)
+			return err
+		}) {
+			logger.Printf("Did not queue a quiz job for user %d", quizRequest.UserID)
 			return
 		}
+		runner.Notify()
 
-		// Set up stdout and stderr
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+		logger.Printf("Queued job %d for user %d: topic=%q, questions=%d, difficulty=%s, language=%s", jobID, quizRequest.UserID, quizRequest.Topic, quizRequest.NumQuestions, quizRequest.Difficulty, quizRequest.Language)
 
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			logger.Printf("Failed to start Python script: %v", err)
-			http.Error(w, "Failed to start quiz generation", http.StatusInternalServerError)
-			return
+		data := map[string]interface{}{
+			"job_id":     jobID,
+			"status":     types.JobQueued,
+			"difficulty": quizRequest.Difficulty,
 		}
-
-		// Write JSON input to Python script's stdin
-		if _, err := stdin.Write(jsonData); err != nil {
-			logger.Printf("Failed to write to Python stdin: %v", err)
-			http.Error(w, "Failed to send quiz request", http.StatusInternalServerError)
-			return
+		if quizRequest.Level > 0 {
+			data["level"] = quizRequest.Level
 		}
-		stdin.Close()
-
-		// Wait for the command to complete and check if err
-		if err := cmd.Wait(); err != nil {
-			logger.Printf("Python script failed: %v, stderr: %s", err, stderr.String())
-			http.Error(w, fmt.Sprintf("Quiz generation error: %s", stderr.String()), http.StatusInternalServerError)
-			return
+		if quizRequest.TopicID != nil {
+			data["topic_id"] = *quizRequest.TopicID
+			data["canonical_topic"] = quizRequest.CanonicalTopic
 		}
+		response.WriteResponse(w, response.CreateResponse(data, http.StatusAccepted, "Quiz generation started"))
+	}
+}
 
-		// Log stderr even on success
-		if stderr.Len() > 0 {
-			logger.Printf("Python script stderr: %s", stderr.String())
+// GetQuizJob reports the status of a generation job and, once it has
+// succeeded, the generated questions' prompts until they are saved to a
+// quiz; from then on they are read from the quiz, where timed ones are
+// locked until taken. Their answer keys are only ever read from the job by
+// InsertQuestions.
+func GetQuizJob(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
 		}
 
-		// Read Python script output
-		body := stdout.Bytes()
-		logger.Printf("Python script output: %s", string(body))
-
-		// Decode response as object
-		var responseData map[string]interface{}
-		if err := json.Unmarshal(body, &responseData); err != nil {
-			logger.Printf("Failed to decode Python response: %v", err)
-			http.Error(w, "Invalid response from quiz service", http.StatusInternalServerError)
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		// Validate response
-		ok, isOkBool := responseData["ok"].(bool)
-		if !isOkBool {
-			logger.Printf("Invalid or missing 'ok' field")
-			http.Error(w, "Invalid quiz response", http.StatusInternalServerError)
+		jobID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid job Id : %v", err.Error()), http.StatusBadRequest)
 			return
 		}
 
-		if !ok {
-			errorMsg := "Quiz generation failed"
-			if errors, ok := responseData["data"].([]interface{}); ok && len(errors) > 0 {
-				if msg, ok := errors[0].(string); ok {
-					errorMsg = msg
-				}
+		job, err := database.FetchQuizJob(db, jobID, int64(userID))
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Quiz job not found", http.StatusNotFound)
+				return
 			}
-			logger.Printf("Python script error: %s", errorMsg)
-			http.Error(w, errorMsg, http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Error fetching quiz job : %v", err.Error()), http.StatusInternalServerError)
 			return
 		}
 
-		questions, isQuestionsArray := responseData["data"].([]interface{})
-		if !isQuestionsArray || len(questions) == 0 {
-			logger.Printf("No quiz questions generated")
-			http.Error(w, "No quiz questions available", http.StatusInternalServerError)
+		saved, err := database.JobQuestionsSaved(db, job.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
 			return
 		}
-
-		logger.Printf("Generated %d quiz questions", len(questions))
-		respData := response.CreateResponse(questions, 200, "Quiz generated successfully")
-		response.WriteResponse(w, respData)
+		view := job.View()
+		if saved {
+			view.Questions = nil
+		}
+		response.WriteResponse(w, response.CreateResponse(view, http.StatusOK, "Quiz job retrieved successfully"))
 	}
 }
 
 /*---------------------------------------*/
-func CreateQuizInDatabase(db *sql.DB) http.HandlerFunc {
+// CreateQuizInDatabase saves a new, unanswered quiz for the user from one of
+// their succeeded generation jobs. Its score starts at zero and is only set
+// by SubmitQuiz; its language, level and prompt version come from the job.
+func CreateQuizInDatabase(db *sql.DB, resolver *topics.Resolver, tracker *skill.Tracker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusBadRequest)
@@ -199,16 +262,55 @@ func CreateQuizInDatabase(db *sql.DB) http.HandlerFunc {
 			}
 		}
 
+		// The owner and score are the server's to set, whatever the client sent.
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		quiz.UserID, quiz.Score, quiz.SubmittedAt = userID, 0, nil
+
 		validate := validator.New()
 		if err := validate.Struct(&quiz); err != nil {
 			response.ValidateResponse(w, err)
 			return
 		}
 
+		job, err := database.FetchQuizJob(db, *quiz.JobID, int64(userID))
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Quiz job not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if job.Status != types.JobSucceeded {
+			http.Error(w, "Only quizzes from succeeded jobs can be saved", http.StatusConflict)
+			return
+		}
+		quiz.Language, quiz.PromptTemplateID, quiz.DifficultyLevel = job.Request.Language, nil, nil
+		if job.Report != nil {
+			quiz.PromptTemplateID = job.Report.PromptTemplateID
+		}
+		if job.Request.Level > 0 {
+			quiz.DifficultyLevel = &job.Request.Level
+		}
+
+		// The topic is resolved here rather than trusted from the client;
+		// quiz_name keeps the user's wording.
+		quiz.TopicID, quiz.Topic = nil, ""
+		if topic, err := resolver.Resolve(r.Context(), quiz.QuizName); err != nil {
+			log.Printf("[CreateQuizInDatabase] Not resolving topic %q: %v", quiz.QuizName, err)
+		} else if topic != nil {
+			quiz.TopicID, quiz.Topic = &topic.ID, topic.Name
+		}
+
 		if err := database.InsertNewQuiz(db, &quiz); err != nil {
 			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
 			return
 		}
+		tracker.Forget(int64(quiz.UserID))
 
 		quizResponse := response.CreateResponse(quiz, http.StatusCreated, "Quiz created successfully", "<DeveloperMessage>", "<UserMessage>", false, "Err")
 		response.WriteResponse(w, quizResponse)
@@ -247,12 +349,61 @@ func InsertQuestions(db *sql.DB) http.HandlerFunc {
 			}
 		}
 
+		// Questions may only be added to the user's own quizzes before they
+		// are submitted, and must be ones their job generated. The question
+		// itself, its options, grading rules and solution are taken from the
+		// job, so the answer keys are the server's; any answers sent along
+		// are ignored. The time limit is the client's to set. A quiz's
+		// questions are inserted once, all together.
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		generated := make(map[int]map[int]types.Question) // by quiz and serial number
+		inserted := make(map[int]map[int]bool)
+		for i, question := range questions {
+			if generated[question.QuizID] == nil {
+				if generated[question.QuizID] = generatedQuestions(w, db, question.QuizID, userID); generated[question.QuizID] == nil {
+					return
+				}
+				inserted[question.QuizID] = make(map[int]bool)
+			}
+			if inserted[question.QuizID][question.SerialNumber] {
+				http.Error(w, fmt.Sprintf("Question %d is sent twice", question.SerialNumber), http.StatusBadRequest)
+				return
+			}
+			inserted[question.QuizID][question.SerialNumber] = true
+			original, ok := generated[question.QuizID][question.SerialNumber]
+			if !ok || (question.Type != "" && original.Type != question.Type) || original.Question != question.Question {
+				http.Error(w, fmt.Sprintf("Question %d does not match the generated quiz", question.SerialNumber), http.StatusBadRequest)
+				return
+			}
+			question.Type, question.Question, question.Options = original.Type, original.Question, original.Options
+			question.CaseSensitive, question.IgnoreSpaces = original.CaseSensitive, original.IgnoreSpaces
+			question.QuestionSolution = original.QuestionSolution
+			question.UserAnswer, question.UserAnswers, question.IsCorrect = "", nil, nil
+			questions[i] = question
+		}
+
 		tx, err := db.Begin()
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Database transaction error: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		for quizID := range generated {
+			if err := database.LockEmptyQuiz(tx, quizID); err != nil {
+				tx.Rollback()
+				if errors.Is(err, database.ErrQuestionsExist) {
+					http.Error(w, fmt.Sprintf("Quiz %d already has its questions", quizID), http.StatusConflict)
+					return
+				}
+				http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
+
 		// Insert questions in a single call
 		if err := database.InsertNewQuestions(tx, questions); err != nil {
 			tx.Rollback()
@@ -270,6 +421,40 @@ func InsertQuestions(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// generatedQuestions returns the questions the generation job of one of the
+// user's unsubmitted quizzes produced, by serial number. It writes the error
+// response and returns nil if there are none to add.
+func generatedQuestions(w http.ResponseWriter, db *sql.DB, quizID, userID int) map[int]types.Question {
+	quiz, err := database.FetchUserQuiz(db, quizID, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("Quiz %d not found", quizID), http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return nil
+	}
+	if quiz.SubmittedAt != nil {
+		http.Error(w, fmt.Sprintf("Quiz %d has already been submitted", quizID), http.StatusConflict)
+		return nil
+	}
+	if quiz.JobID == nil {
+		http.Error(w, fmt.Sprintf("Quiz %d was not saved from a generation job", quizID), http.StatusConflict)
+		return nil
+	}
+	job, err := database.FetchQuizJob(db, *quiz.JobID, int64(userID))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return nil
+	}
+
+	bySerial := make(map[int]types.Question, len(job.Questions))
+	for _, q := range job.Questions {
+		bySerial[q.SerialNumber] = q
+	}
+	return bySerial
+}
+
 func GetUserQuizzesHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -297,7 +482,10 @@ func GetUserQuizzesHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// GetQuizQuestionsHandler handles the endpoint to get questions for a quiz
+// GetQuizQuestionsHandler returns a quiz with its questions to its owner and
+// the users it is shared with. The solutions and the grading of the latest
+// attempt are only included once the quiz has been submitted and no retake
+// is in progress; until then only the prompts are.
 func GetQuizQuestionsHandler(db *sql.DB) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -307,29 +495,37 @@ func GetQuizQuestionsHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		quizIDStr := r.URL.Query().Get("quizID")
-
-		quizID, err := strconv.Atoi(quizIDStr)
-
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Invalid Quiz Id : %v", err.Error()), http.StatusBadRequest)
+		quiz := fetchQuiz(w, r, db, r.URL.Query().Get("quizID"), database.FetchViewableQuiz)
+		if quiz == nil {
 			return
 		}
 
-		questions, err := database.FetchQuestionsByQuiz(db, quizID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Error fetching questions : %v", err.Error()), http.StatusInternalServerError)
+		reveal, ok := revealSolutions(w, db, quiz)
+		if !ok {
 			return
 		}
 
-		quiz, err := database.FetchQuizzesByQuizId(db, quizID)
+		questions, err := database.FetchQuestionsByQuiz(db, quiz.ID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error fetching quiz : %v", err.Error()), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Error fetching questions : %v", err.Error()), http.StatusInternalServerError)
 			return
 		}
+		// Until the solutions are shown, timed questions are only read
+		// through the attempt they are taken in, which times them.
+		views := make([]types.QuestionView, 0, len(questions))
+		for _, q := range questions {
+			if !reveal {
+				q.UserAnswer, q.UserAnswers = "", nil
+				if q.TimeLimitSeconds != nil {
+					views = append(views, q.LockedView())
+					continue
+				}
+			}
+			views = append(views, q.View(reveal, q.IsCorrect))
+		}
 
 		data := map[string]interface{}{
-			"questions": questions,
+			"questions": views,
 			"quiz":      quiz,
 		}
 		res := response.CreateResponse(data, http.StatusOK, "Questions retrived successfully")
@@ -338,7 +534,7 @@ func GetQuizQuestionsHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func DeleteQuiz(db *sql.DB) http.HandlerFunc {
+func DeleteQuiz(db *sql.DB, tracker *skill.Tracker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
@@ -359,6 +555,9 @@ func DeleteQuiz(db *sql.DB) http.HandlerFunc {
 			http.Error(w, fmt.Sprintf("Error deleting Quiz : %v", err.Error()), http.StatusInternalServerError)
 			return
 		}
+		if userID, err := strconv.Atoi(r.Header.Get("userID")); err == nil {
+			tracker.Forget(int64(userID))
+		}
 
 		res := response.CreateResponse(nil, http.StatusOK, "Quiz deleted successfully")
 
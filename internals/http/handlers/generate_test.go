@@ -0,0 +1,261 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/config"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database/dbtest"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/http/handlers"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/jobs"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/moderation"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/quota"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/replay"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/topics"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// The recorded Gemini answers are shared with the generateQuiz tests.
+var fixturesDir = filepath.Join("..", "..", "generateQuiz", "testdata", "llm")
+
+// call sends body as JSON to the server as userID and decodes the data of the
+// response into out, failing unless it has status want.
+func call(t *testing.T, srv *httptest.Server, userID int64, method, path string, body interface{}, want int, out interface{}) {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("userID", strconv.FormatInt(userID, 10))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	var raw bytes.Buffer
+	raw.ReadFrom(resp.Body)
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s = %d, want %d: %s", method, path, resp.StatusCode, want, raw.String())
+	}
+	if out == nil {
+		return
+	}
+	if err := json.Unmarshal(raw.Bytes(), &envelope); err != nil {
+		t.Fatalf("%s %s: %v: %s", method, path, err, raw.String())
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		t.Fatalf("%s %s: %v: %s", method, path, err, raw.String())
+	}
+}
+
+// solutionKeys are the fields that give a question's answer away.
+var solutionKeys = []string{"correctAnswer", "correctAnswers", "description", "source_excerpt", "solution", "repaired"}
+
+// checkNoSolutions fails the test if the JSON document has any of the
+// solutionKeys, at any depth.
+func checkNoSolutions(t *testing.T, what string, raw []byte) {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("%s: %v: %s", what, err, raw)
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				for _, key := range solutionKeys {
+					if k == key {
+						t.Errorf("%s has %q: %s", what, k, raw)
+					}
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+// TestGenerateAndSaveQuizReplay runs a quiz through generation, saving and
+// question insertion with the model's answer replayed from fixturesDir.
+func TestGenerateAndSaveQuizReplay(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+
+	cfg := &config.Config{}
+	cfg.QuizGenerator = "gemini"
+	cfg.GeminiModel = "gemini-2.5-flash"
+	cfg.RecordMode = replay.Replay
+	cfg.FixturesDir = fixturesDir
+	chain, err := generateQuiz.New(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := jobs.NewRunner(db, generateQuiz.NewChecker(chain, 1), 1, 50*time.Millisecond)
+	runner.Start()
+	defer runner.Shutdown(context.Background())
+
+	limiter := quota.New(db, quota.Limits{})
+	screener := moderation.New(db, moderation.Rules{MaxLength: 100, MaxWords: 15})
+	tracker := skill.New(db, time.Minute)
+	resolver := topics.New(db, 0.8, time.Minute)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/quiz/generate", handlers.GenerateQuiz(db, runner, limiter, screener, tracker, resolver)).Methods("POST")
+	router.HandleFunc("/api/quiz/jobs/{id:[0-9]+}", handlers.GetQuizJob(db)).Methods("GET")
+	router.HandleFunc("/api/quiz/new", handlers.CreateQuizInDatabase(db, resolver, tracker)).Methods("POST")
+	router.HandleFunc("/api/quiz/questions/new", handlers.InsertQuestions(db)).Methods("POST")
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	var queued struct {
+		JobID int64 `json:"job_id"`
+	}
+	call(t, srv, userID, "POST", "/api/quiz/generate", map[string]interface{}{
+		"topic": "Photosynthesis", "num_questions": 5, "difficulty": "easy",
+	}, http.StatusAccepted, &queued)
+
+	var job types.QuizJobView
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		var raw json.RawMessage
+		call(t, srv, userID, "GET", fmt.Sprintf("/api/quiz/jobs/%d", queued.JobID), nil, http.StatusOK, &raw)
+		checkNoSolutions(t, "job", raw)
+		if err := json.Unmarshal(raw, &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == types.JobSucceeded {
+			break
+		}
+		if job.Status == types.JobFailed || time.Now().After(deadline) {
+			t.Fatalf("job %d is %s: %s", job.ID, job.Status, job.Error)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if len(job.Questions) != 5 {
+		t.Fatalf("job generated %d questions, want 5", len(job.Questions))
+	}
+
+	var quiz types.Quiz
+	call(t, srv, userID, "POST", "/api/quiz/new", map[string]interface{}{
+		"quiz_name": "Photosynthesis", "level": "easy", "totalQuestions": 5, "job_id": job.ID,
+	}, http.StatusCreated, &quiz)
+
+	// The client's copy of a question carries no answer key; the saved one
+	// must be the job's.
+	var questions []map[string]interface{}
+	for _, q := range job.Questions {
+		questions = append(questions, map[string]interface{}{
+			"quiz_id": quiz.ID, "serial_number": q.Prompt.SerialNumber, "question": q.Prompt.Question, "options": q.Prompt.Options, "correctAnswer": "forged",
+		})
+	}
+	call(t, srv, userID, "POST", "/api/quiz/questions/new", questions, http.StatusCreated, nil)
+
+	stored, err := database.FetchQuizJob(db, job.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(`SELECT serial_number, correct_answer FROM questions WHERE quiz_id = $1 ORDER BY serial_number`, quiz.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	saved := 0
+	for rows.Next() {
+		var serial int
+		var answer string
+		if err := rows.Scan(&serial, &answer); err != nil {
+			t.Fatal(err)
+		}
+		if want := stored.Questions[serial-1].CorrectAnswer; answer != want {
+			t.Errorf("question %d saved with answer %q, want %q", serial, answer, want)
+		}
+		saved++
+	}
+	if saved != 5 {
+		t.Errorf("saved %d questions, want 5", saved)
+	}
+}
+
+// TestGenerateQuizStreamSendsPromptsOnly checks that no streamed event
+// carries a question's solution.
+func TestGenerateQuizStreamSendsPromptsOnly(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+
+	chain := generateQuiz.NewChain(generateQuiz.ChainConfig{}, generateQuiz.Provider{Name: "fake", Generator: generateQuiz.FakeGenerator{}})
+	checker := generateQuiz.NewChecker(chain, 1)
+	limiter := quota.New(db, quota.Limits{})
+	screener := moderation.New(db, moderation.Rules{MaxLength: 100, MaxWords: 15})
+	tracker := skill.New(db, time.Minute)
+	resolver := topics.New(db, 0.8, time.Minute)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/quiz/generate/stream", handlers.GenerateQuizStream(db, checker, limiter, screener, tracker, resolver)).Methods("GET")
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/api/quiz/generate/stream?topic=Photosynthesis&num_questions=5&difficulty=easy", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("userID", strconv.FormatInt(userID, 10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stream = %d: %s", resp.StatusCode, body)
+	}
+
+	questions := 0
+	event := ""
+	for _, line := range strings.Split(string(body), "\n") {
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			checkNoSolutions(t, event+" event", []byte(strings.TrimPrefix(line, "data: ")))
+			if event == "question" {
+				questions++
+			}
+		}
+	}
+	if questions != 5 {
+		t.Errorf("streamed %d questions, want 5:\n%s", questions, body)
+	}
+}
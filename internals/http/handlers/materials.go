@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/materials"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+	"github.com/gorilla/mux"
+)
+
+// maxMaterialSize bounds an uploaded file.
+const maxMaterialSize = 10 << 20
+
+// UploadMaterial stores study material for later quizzes. It accepts either a
+// multipart form with a "file" field (and an optional "title"), or the file
+// itself as the request body with its Content-Type and a ?title= parameter.
+func UploadMaterial(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.New(log.Writer(), "[UploadMaterial] ", log.LstdFlags)
+
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid userID", http.StatusBadRequest)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxMaterialSize)
+
+		var data []byte
+		var filename, contentType, title string
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			var header *multipart.FileHeader
+			var file multipart.File
+			if file, header, err = r.FormFile("file"); err == nil {
+				defer file.Close()
+				data, err = io.ReadAll(file)
+				filename, contentType = header.Filename, header.Header.Get("Content-Type")
+				title = r.FormValue("title")
+			}
+		} else {
+			data, err = io.ReadAll(r.Body)
+			contentType = r.Header.Get("Content-Type")
+			title = r.URL.Query().Get("title")
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, fmt.Sprintf("Material must be at most %d MB", maxMaterialSize>>20), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to read upload: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(data) == 0 {
+			http.Error(w, "No data provided", http.StatusBadRequest)
+			return
+		}
+
+		kind, err := materials.DetectKind(filename, contentType, data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		text, err := materials.Extract(kind, data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Could not read the material: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+
+		title = strings.TrimSpace(title)
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		}
+		if title == "" || title == "." {
+			title = firstLine(text, 80)
+		}
+
+		material := types.Material{
+			UserID:     int64(userID),
+			Title:      title,
+			Kind:       kind,
+			Characters: utf8.RuneCountInString(text),
+		}
+		if err := database.InsertMaterial(r.Context(), db, &material, materials.Chunk(text)); err != nil {
+			logger.Printf("Failed to store material: %v", err)
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		logger.Printf("Stored %s material %d for user %d: %d characters in %d chunks", kind, material.ID, userID, material.Characters, material.Chunks)
+		response.WriteResponse(w, response.CreateResponse(material, http.StatusCreated, "Material uploaded successfully"))
+	}
+}
+
+// GetMaterials lists the user's uploaded materials.
+func GetMaterials(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid userID", http.StatusBadRequest)
+			return
+		}
+
+		list, err := database.FetchMaterialsByUser(db, int64(userID))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching materials : %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		response.WriteResponse(w, response.CreateResponse(list, http.StatusOK, "Materials retrieved successfully"))
+	}
+}
+
+// DeleteMaterial removes one of the user's materials. Quizzes already
+// generated from it are kept.
+func DeleteMaterial(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid userID", http.StatusBadRequest)
+			return
+		}
+
+		materialID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid material Id : %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		if err := database.DeleteMaterial(db, materialID, int64(userID)); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Material not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Error deleting material : %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		response.WriteResponse(w, response.CreateResponse(nil, http.StatusOK, "Material deleted successfully"))
+	}
+}
+
+// applyMaterial checks that a requested material belongs to the user and,
+// when no topic was given, uses the material's title as the topic. It writes
+// the error response and returns false if the request can't proceed.
+func applyMaterial(w http.ResponseWriter, db *sql.DB, quizRequest *types.QuizRequest) bool {
+	quizRequest.Material = nil // chosen by the server
+	if quizRequest.MaterialID == nil {
+		return true
+	}
+
+	material, err := database.FetchMaterial(db, *quizRequest.MaterialID, quizRequest.UserID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Material not found", http.StatusNotFound)
+			return false
+		}
+		http.Error(w, fmt.Sprintf("Error fetching material : %v", err.Error()), http.StatusInternalServerError)
+		return false
+	}
+	if strings.TrimSpace(quizRequest.Topic) == "" {
+		quizRequest.Topic = material.Title
+	}
+	return true
+}
+
+func firstLine(text string, n int) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	r := []rune(line)
+	if len(r) > n {
+		r = r[:n]
+	}
+	return strings.TrimSpace(string(r))
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/moderation"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// screenTopic writes a 422 response with the reason and returns false if the
+// topic was rejected by moderation. It must run on the topic as the user
+// wrote it, before normalizeTopic drops words.
+func screenTopic(w http.ResponseWriter, r *http.Request, screener *moderation.Screener, userID int64, topic string) bool {
+	// A missing topic is reported by ValidateRequest.
+	if strings.TrimSpace(topic) == "" {
+		return true
+	}
+	err := screener.Screen(r.Context(), userID, topic)
+	if err == nil {
+		return true
+	}
+
+	var rejection *moderation.Rejection
+	if !errors.As(err, &rejection) {
+		http.Error(w, fmt.Sprintf("Failed to check topic: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	log.Printf("[Moderation] Rejected topic of user %d: %v", userID, rejection)
+	response.WriteResponse(w, response.CreateResponse(rejection, http.StatusUnprocessableEntity, rejection.Reason, true, rejection.Reason))
+	return false
+}
+
+// GetModerationEvents lists rejected topics for admins, newest first.
+// Query parameters: status (pending, upheld or overturned; all if empty)
+// and limit (default 50).
+func GetModerationEvents(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "", types.ModerationPending, types.ModerationUpheld, types.ModerationOverturned:
+		default:
+			http.Error(w, "status must be pending, upheld or overturned", http.StatusBadRequest)
+			return
+		}
+
+		limit := 50
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 || n > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		events, err := database.FetchModerationEvents(db, status, limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(events, http.StatusOK, "Moderation events retrieved successfully"))
+	}
+}
+
+// ReviewModerationEvent records whether an admin upheld or overturned a
+// rejection.
+func ReviewModerationEvent(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid moderation event Id : %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		var review struct {
+			Status string `json:"status"`
+			Note   string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, "Failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		if review.Status != types.ModerationUpheld && review.Status != types.ModerationOverturned {
+			http.Error(w, "status must be upheld or overturned", http.StatusBadRequest)
+			return
+		}
+
+		if err := database.ReviewModerationEvent(db, id, review.Status, review.Note); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Moderation event not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(nil, http.StatusOK, "Moderation event reviewed successfully"))
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// GetPromptTemplates lists every prompt template version with the quality
+// metrics of what it generated, so variants can be compared.
+func GetPromptTemplates(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		metrics, err := database.FetchPromptMetrics(db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(metrics, http.StatusOK, "Prompt templates retrieved successfully"))
+	}
+}
+
+// CreatePromptTemplate saves a new version of a prompt template. The body is
+// a Go text/template; it is rejected if it doesn't render.
+func CreatePromptTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		var tmpl types.PromptTemplate
+		if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+			http.Error(w, "Failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		tmpl.Name = strings.TrimSpace(tmpl.Name)
+		if tmpl.Name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		if tmpl.Weight < 0 {
+			http.Error(w, "weight must not be negative", http.StatusBadRequest)
+			return
+		}
+		if _, err := generateQuiz.ParsePromptTemplate(tmpl.Body); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid template: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := database.InsertPromptTemplate(db, &tmpl); err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(tmpl, http.StatusCreated, "Prompt template created successfully"))
+	}
+}
+
+// UpdatePromptTemplate activates or deactivates a template version and sets
+// its weight in the A/B assignment.
+func UpdatePromptTemplate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid prompt template Id : %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		var update struct {
+			Active bool `json:"active"`
+			Weight int  `json:"weight"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		if update.Weight < 0 {
+			http.Error(w, "weight must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		if err := database.UpdatePromptTemplate(db, id, update.Active, update.Weight); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Prompt template not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(nil, http.StatusOK, "Prompt template updated successfully"))
+	}
+}
@@ -0,0 +1,150 @@
+package handlers_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database/dbtest"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/http/handlers"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/topics"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// generatedQuiz saves a succeeded job with questions for the user and a quiz
+// from it through the handlers, and returns the quiz's ID.
+func generatedQuiz(t *testing.T, db *sql.DB, srv *httptest.Server, userID int64, questions []types.Question) int {
+	t.Helper()
+	request := &types.QuizRequest{UserID: userID, Topic: "Binary", NumQuestions: len(questions), Difficulty: "easy"}
+	jobID, err := database.InsertCompletedQuizJob(db, request, questions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var quiz types.Quiz
+	call(t, srv, userID, "POST", "/api/quiz/new", map[string]interface{}{
+		"quiz_name": "Binary", "level": "easy", "totalQuestions": len(questions), "job_id": jobID,
+	}, http.StatusCreated, &quiz)
+	return quiz.ID
+}
+
+func questionsServer(t *testing.T, db *sql.DB) *httptest.Server {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/quiz/new", handlers.CreateQuizInDatabase(db, topics.New(db, 0.8, time.Minute), skill.New(db, time.Minute))).Methods("POST")
+	router.HandleFunc("/api/quiz/questions/new", handlers.InsertQuestions(db)).Methods("POST")
+	router.HandleFunc("/api/quiz/{quizID:[0-9]+}/questions/{serial:[0-9]+}/revisions", handlers.GetQuestionRevisions(db)).Methods("GET")
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var binaryQuestions = []types.Question{
+	{
+		QuestionPrompt:   types.QuestionPrompt{SerialNumber: 1, Type: types.QuestionMCQ, Question: "How many bits are in a byte?", Options: []string{"4", "8", "16", "32"}},
+		QuestionSolution: types.QuestionSolution{CorrectAnswer: "8", Description: "A byte is eight bits."},
+	},
+	{
+		QuestionPrompt:   types.QuestionPrompt{SerialNumber: 2, Type: types.QuestionMCQ, Question: "What is 1 + 1 in binary?", Options: []string{"2", "10", "11", "01"}},
+		QuestionSolution: types.QuestionSolution{CorrectAnswer: "10", Description: "One plus one is two, written 10."},
+	},
+}
+
+func TestInsertQuestionsKeepsTimeLimitsAndJobAnswers(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	srv := questionsServer(t, db)
+	quizID := generatedQuiz(t, db, srv, userID, binaryQuestions)
+
+	call(t, srv, userID, "POST", "/api/quiz/questions/new", []map[string]interface{}{
+		{"quiz_id": quizID, "serial_number": 1, "question": binaryQuestions[0].Question, "options": []string{"4", "8", "16", "32"}, "correctAnswer": "4", "time_limit_seconds": 30},
+		{"quiz_id": quizID, "serial_number": 2, "question": binaryQuestions[1].Question, "options": []string{"2", "10", "11", "01"}, "correctAnswer": "2", "description": "forged"},
+	}, http.StatusCreated, nil)
+
+	rows, err := db.Query(`SELECT serial_number, correct_answer, COALESCE(description, ''), time_limit_seconds FROM questions WHERE quiz_id = $1 ORDER BY serial_number`, quizID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	saved := 0
+	for rows.Next() {
+		var serial int
+		var answer, description string
+		var limit *int
+		if err := rows.Scan(&serial, &answer, &description, &limit); err != nil {
+			t.Fatal(err)
+		}
+		want := binaryQuestions[serial-1]
+		if answer != want.CorrectAnswer || description != want.Description {
+			t.Errorf("question %d saved with %q (%q), want the job's %q (%q)", serial, answer, description, want.CorrectAnswer, want.Description)
+		}
+		if serial == 1 && (limit == nil || *limit != 30) {
+			t.Errorf("question 1 saved with time limit %v, want 30", limit)
+		}
+		if serial == 2 && limit != nil {
+			t.Errorf("question 2 saved with time limit %d, want none", *limit)
+		}
+		saved++
+	}
+	if saved != 2 {
+		t.Errorf("saved %d questions, want 2", saved)
+	}
+}
+
+func TestInsertQuestionsOnlyOnce(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	srv := questionsServer(t, db)
+	quizID := generatedQuiz(t, db, srv, userID, binaryQuestions)
+
+	first := map[string]interface{}{"quiz_id": quizID, "serial_number": 1, "question": binaryQuestions[0].Question, "options": binaryQuestions[0].Options, "correctAnswer": "x"}
+	second := map[string]interface{}{"quiz_id": quizID, "serial_number": 2, "question": binaryQuestions[1].Question, "options": binaryQuestions[1].Options, "correctAnswer": "x"}
+	call(t, srv, userID, "POST", "/api/quiz/questions/new", []map[string]interface{}{first, first}, http.StatusBadRequest, nil)
+	call(t, srv, userID, "POST", "/api/quiz/questions/new", []map[string]interface{}{first, second}, http.StatusCreated, nil)
+	call(t, srv, userID, "POST", "/api/quiz/questions/new", []map[string]interface{}{first, second}, http.StatusConflict, nil)
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM questions WHERE quiz_id = $1`, quizID).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("quiz has %d questions, want 2", count)
+	}
+}
+
+func TestQuestionRevisionsHideSolutionsBeforeSubmission(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	srv := questionsServer(t, db)
+	quizID := generatedQuiz(t, db, srv, userID, binaryQuestions)
+	call(t, srv, userID, "POST", "/api/quiz/questions/new", []map[string]interface{}{
+		{"quiz_id": quizID, "serial_number": 1, "question": binaryQuestions[0].Question, "options": binaryQuestions[0].Options, "correctAnswer": "x"},
+		{"quiz_id": quizID, "serial_number": 2, "question": binaryQuestions[1].Question, "options": binaryQuestions[1].Options, "correctAnswer": "x"},
+	}, http.StatusCreated, nil)
+
+	replacement := types.Question{
+		QuestionPrompt:   types.QuestionPrompt{Type: types.QuestionMCQ, Question: "How many bits are in a nibble?", Options: []string{"2", "4", "8", "16"}},
+		QuestionSolution: types.QuestionSolution{CorrectAnswer: "4", Description: "A nibble is half a byte."},
+	}
+	if _, err := database.ReplaceQuestion(db, quizID, 1, &replacement); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw json.RawMessage
+	call(t, srv, userID, "GET", fmt.Sprintf("/api/quiz/%d/questions/1/revisions", quizID), nil, http.StatusOK, &raw)
+	var revisions []types.QuestionRevisionView
+	if err := json.Unmarshal(raw, &revisions); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Question.Prompt.Question != binaryQuestions[0].Question {
+		t.Fatalf("revisions = %s, want the original question", raw)
+	}
+	checkNoSolutions(t, "revisions", raw)
+}
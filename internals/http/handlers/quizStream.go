@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/moderation"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/quota"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/topics"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// writeSSE writes one Server-Sent Event and flushes it to the client.
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, event string, id string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	if id != "" {
+		fmt.Fprintf(&sb, "id: %s\n", id)
+	}
+	fmt.Fprintf(&sb, "event: %s\ndata: %s\n\n", event, payload)
+
+	if _, err := w.Write([]byte(sb.String())); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// GenerateQuizStream generates a quiz synchronously and streams each
+// question's prompt as a "question" event as soon as it passes validation, with "heartbeat" events
+// while the model is thinking and a closing "summary" (or "error") event. The
+// quiz is recorded as a succeeded job, whose job_id the summary carries for
+// saving it.
+//
+// Query parameters: topic, num_questions, difficulty (easy, medium, hard or
+// adaptive) and optionally question_types (comma-separated), material_id and
+// language (defaults to the user's preferred language).
+func GenerateQuizStream(db *sql.DB, checker *generateQuiz.Checker, limiter *quota.Limiter, screener *moderation.Screener, tracker *skill.Tracker, resolver *topics.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.New(log.Writer(), "[GenerateQuizStream] ", log.LstdFlags)
+
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid userID", http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		numQuestions, err := strconv.Atoi(query.Get("num_questions"))
+		if err != nil {
+			http.Error(w, "num_questions must be a number", http.StatusBadRequest)
+			return
+		}
+
+		quizRequest := types.QuizRequest{
+			UserID:       int64(userID),
+			Topic:        query.Get("topic"),
+			NumQuestions: numQuestions,
+			Difficulty:   strings.ToLower(query.Get("difficulty")),
+			Language:     query.Get("language"),
+		}
+		applyPreferredLanguage(db, &quizRequest)
+		if kinds := query.Get("question_types"); kinds != "" {
+			quizRequest.QuestionTypes = strings.Split(kinds, ",")
+		}
+		if id := query.Get("material_id"); id != "" {
+			materialID, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				http.Error(w, "material_id must be a number", http.StatusBadRequest)
+				return
+			}
+			quizRequest.MaterialID = &materialID
+			if !applyMaterial(w, db, &quizRequest) {
+				return
+			}
+		}
+		if !screenTopic(w, r, screener, quizRequest.UserID, quizRequest.Topic) {
+			return
+		}
+		quizRequest.Topic = normalizeTopic(quizRequest.Topic)
+		applyTopic(r, resolver, &quizRequest)
+		applyAdaptiveDifficulty(tracker, &quizRequest)
+		if err := generateQuiz.ValidateRequest(&quizRequest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		usageID, ok := reserveUsage(w, limiter, quizRequest.UserID)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		// Cancelled when the client disconnects or this handler returns, which
+		// stops the upstream generation.
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		start := time.Now()
+		questions := make(chan types.Question)
+		done := make(chan error, 1)
+		var report *types.GenerationReport
+
+		go func() {
+			usage := &generateQuiz.Usage{}
+			var err error
+			report, err = checker.Stream(generateQuiz.WithUsage(ctx, usage), &quizRequest, func(q types.Question) error {
+				select {
+				case questions <- q:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			// Recorded here so a client that disconnects is still charged.
+			if err := database.FinishUsageRecord(db, usageID, usage.Record(quizRequest.UserID, nil, time.Since(start), err)); err != nil {
+				logger.Printf("%v", err)
+			}
+			done <- err
+		}()
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		var streamed []types.Question
+		for {
+			select {
+			case q := <-questions:
+				streamed = append(streamed, q)
+				if err := writeSSE(w, flusher, "question", strconv.Itoa(q.SerialNumber), q.View(false, nil)); err != nil {
+					logger.Printf("Client write failed: %v", err)
+					return
+				}
+
+			case <-heartbeat.C:
+				if err := writeSSE(w, flusher, "heartbeat", "", map[string]interface{}{"elapsed_ms": time.Since(start).Milliseconds()}); err != nil {
+					logger.Printf("Client write failed: %v", err)
+					return
+				}
+
+			case err := <-done:
+				if err != nil {
+					logger.Printf("Quiz generation failed: %v", err)
+					message := "Quiz generation failed"
+					var refused *generateQuiz.RefusedError
+					if errors.As(err, &refused) {
+						message = refused.Message
+					} else if errors.Is(err, generateQuiz.ErrUnavailable) {
+						message = "Quiz generation is temporarily unavailable, please try again shortly"
+					}
+					writeSSE(w, flusher, "error", "", map[string]interface{}{"message": message})
+					return
+				}
+
+				jobID, err := database.InsertCompletedQuizJob(db, &quizRequest, streamed, report)
+				if err != nil {
+					logger.Printf("%v", err)
+					writeSSE(w, flusher, "error", "", map[string]interface{}{"message": "Failed to save the generated quiz"})
+					return
+				}
+
+				logger.Printf("Streamed %d questions to user %d in %v", len(streamed), userID, time.Since(start))
+				writeSSE(w, flusher, "summary", "", map[string]interface{}{
+					"job_id":     jobID,
+					"count":      len(streamed),
+					"requested":  quizRequest.NumQuestions,
+					"topic":      quizRequest.Topic,
+					"topic_id":   quizRequest.TopicID,
+					"difficulty": quizRequest.Difficulty,
+					"level":      quizRequest.Level,
+					"language":   quizRequest.Language,
+					"elapsed_ms": time.Since(start).Milliseconds(),
+					"report":     report.Public(),
+				})
+				return
+
+			case <-ctx.Done():
+				logger.Printf("Client disconnected after %d questions", len(streamed))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/moderation"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/quota"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// userQuiz parses the quizID route variable and fetches the quiz if it
+// belongs to the user. It writes the error response and returns nil if not.
+func userQuiz(w http.ResponseWriter, r *http.Request, db *sql.DB) *types.Quiz {
+	return fetchQuiz(w, r, db, mux.Vars(r)["quizID"], database.FetchUserQuiz)
+}
+
+// viewableQuiz is userQuiz for quizzes the user may view: their own and
+// those shared with them.
+func viewableQuiz(w http.ResponseWriter, r *http.Request, db *sql.DB) *types.Quiz {
+	return fetchQuiz(w, r, db, mux.Vars(r)["quizID"], database.FetchViewableQuiz)
+}
+
+func fetchQuiz(w http.ResponseWriter, r *http.Request, db *sql.DB, quizIDStr string, fetch func(*sql.DB, int, int) (*types.Quiz, error)) *types.Quiz {
+	userID, err := strconv.Atoi(r.Header.Get("userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return nil
+	}
+	quizID, err := strconv.Atoi(quizIDStr)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid Quiz Id : %v", err.Error()), http.StatusBadRequest)
+		return nil
+	}
+
+	quiz, err := fetch(db, quizID, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Quiz not found", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return nil
+	}
+	return quiz
+}
+
+// revealSolutions reports whether the quiz's solutions may be shown: once it
+// has been submitted, and not while an attempt at it is open. It writes the
+// error response and returns ok false if it can't tell.
+func revealSolutions(w http.ResponseWriter, db *sql.DB, quiz *types.Quiz) (reveal, ok bool) {
+	if quiz.SubmittedAt == nil {
+		return false, true
+	}
+	_, err := database.FetchOpenAttempt(db, quiz.ID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Error fetching attempt : %v", err.Error()), http.StatusInternalServerError)
+		return false, false
+	}
+	return err != nil, true
+}
+
+// quizQuestionVars is userQuiz for routes that also name a question by its
+// serial route variable.
+func quizQuestionVars(w http.ResponseWriter, r *http.Request, db *sql.DB) (*types.Quiz, int) {
+	serial, err := strconv.Atoi(mux.Vars(r)["serial"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid serial number : %v", err.Error()), http.StatusBadRequest)
+		return nil, 0
+	}
+	quiz := userQuiz(w, r, db)
+	if quiz == nil {
+		return nil, 0
+	}
+	return quiz, serial
+}
+
+// RegenerateQuestion replaces one question of a saved quiz with a freshly
+// generated one on the same topic, difficulty, language and question type.
+// The replacement must not duplicate the quiz's other questions. The old
+// version is kept in the question's revision history, where earlier attempts
+// still find it. Both versions are returned without their solutions unless
+// the quiz's solutions may be shown.
+func RegenerateQuestion(db *sql.DB, checker *generateQuiz.Checker, limiter *quota.Limiter, screener *moderation.Screener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz, serial := quizQuestionVars(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		questions, err := database.FetchQuestionsByQuiz(db, quiz.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching questions : %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		var current *types.Question
+		for i := range questions {
+			if questions[i].SerialNumber == serial {
+				current = &questions[i]
+				break
+			}
+		}
+		if current == nil {
+			http.Error(w, "Question not found", http.StatusNotFound)
+			return
+		}
+
+		// The quiz name is whatever the client saved, so it is screened like
+		// any topic before reaching the model; a canonical topic needn't be.
+		topic := quiz.Topic
+		if topic == "" {
+			if !screenTopic(w, r, screener, int64(quiz.UserID), quiz.QuizName) {
+				return
+			}
+			topic = normalizeTopic(quiz.QuizName)
+		}
+
+		questionType := current.Type
+		if questionType == "" {
+			questionType = types.QuestionMCQ
+		}
+		quizRequest := types.QuizRequest{
+			UserID:        int64(quiz.UserID),
+			Topic:         topic,
+			NumQuestions:  1,
+			Difficulty:    strings.ToLower(quiz.Level),
+			Language:      quiz.Language,
+			QuestionTypes: []string{questionType},
+		}
+		if _, ok := skill.DifficultyLevel(quizRequest.Difficulty); !ok {
+			quizRequest.Difficulty = "medium"
+		}
+		if quiz.DifficultyLevel != nil {
+			quizRequest.Level = *quiz.DifficultyLevel
+		}
+		quizRequest.TopicID, quizRequest.CanonicalTopic = quiz.TopicID, quiz.Topic
+		usageID, ok := reserveUsage(w, limiter, quizRequest.UserID)
+		if !ok {
+			return
+		}
+
+		start := time.Now()
+		usage := &generateQuiz.Usage{}
+		result, err := checker.Replace(generateQuiz.WithUsage(r.Context(), usage), &quizRequest, questions)
+		if err := database.FinishUsageRecord(db, usageID, usage.Record(quizRequest.UserID, nil, time.Since(start), err)); err != nil {
+			log.Printf("[RegenerateQuestion] %v", err)
+		}
+		if err != nil {
+			log.Printf("[RegenerateQuestion] Generating a replacement for question %d of quiz %d failed: %v", serial, quiz.ID, err)
+			var refused *generateQuiz.RefusedError
+			switch {
+			case errors.As(err, &refused):
+				http.Error(w, refused.Message, http.StatusUnprocessableEntity)
+			case errors.Is(err, generateQuiz.ErrUnavailable):
+				http.Error(w, "Quiz generation is temporarily unavailable, please try again shortly", http.StatusServiceUnavailable)
+			case errors.Is(err, generateQuiz.ErrNoQuestions):
+				http.Error(w, "Could not generate a new question that isn't already in the quiz, please try again", http.StatusBadGateway)
+			default:
+				http.Error(w, "Question generation failed", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		replacement := result.Questions[0]
+		replaced, err := database.ReplaceQuestion(db, quiz.ID, serial, &replacement)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Question not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		reveal, ok := revealSolutions(w, db, quiz)
+		if !ok {
+			return
+		}
+		data := map[string]interface{}{
+			"question": replacement.View(reveal, nil),
+			"replaced": replaced.View(reveal, nil),
+			"report":   result.Report,
+		}
+		response.WriteResponse(w, response.CreateResponse(data, http.StatusOK, "Question regenerated successfully"))
+	}
+}
+
+// GetQuestionRevisions lists the earlier versions of a question in one of the
+// user's quizzes, oldest first, with their solutions only once the quiz's
+// solutions may be shown.
+func GetQuestionRevisions(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz, serial := quizQuestionVars(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		revisions, err := database.FetchQuestionRevisions(db, quiz.ID, serial)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		reveal, ok := revealSolutions(w, db, quiz)
+		if !ok {
+			return
+		}
+		views := make([]types.QuestionRevisionView, len(revisions))
+		for i, revision := range revisions {
+			views[i] = revision.View(reveal)
+		}
+		response.WriteResponse(w, response.CreateResponse(views, http.StatusOK, "Question revisions retrieved successfully"))
+	}
+}
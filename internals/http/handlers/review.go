@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/review"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// GetDueReviews returns the user's review cards that are due, most overdue
+// first: questions they missed in submitted quizzes, with their solutions.
+func GetDueReviews(queue *review.Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		limit := 20
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 || n > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		cards, err := queue.Due(int64(userID), limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(cards, http.StatusOK, "Due reviews retrieved successfully"))
+	}
+}
+
+// RecordReview records how well the user recalled one of their review cards
+// and returns it with its next due date.
+func RecordReview(queue *review.Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		cardID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid review card ID: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		var body types.ReviewGrade
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			if errors.Is(err, io.EOF) {
+				http.Error(w, "No data provided", http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("Failed to decode JSON: %v", err), http.StatusBadRequest)
+			}
+			return
+		}
+		if err := validator.New().Struct(&body); err != nil {
+			response.ValidateResponse(w, err)
+			return
+		}
+
+		card, err := queue.Grade(int64(userID), cardID, *body.Grade)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Review card not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(card, http.StatusOK, "Review recorded successfully"))
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+)
+
+// ShareQuiz lets the owner of a quiz share it with another user, by
+// username or email. Users a quiz is shared with can view it and its
+// attempts, solutions included once submitted.
+func ShareQuiz(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz := userQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		var body struct {
+			User string `json:"user"` // username or email
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			if errors.Is(err, io.EOF) {
+				http.Error(w, "No data provided", http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("Failed to decode JSON: %v", err), http.StatusBadRequest)
+			}
+			return
+		}
+		body.User = strings.TrimSpace(body.User)
+		if body.User == "" {
+			http.Error(w, "user is required", http.StatusBadRequest)
+			return
+		}
+
+		share, err := database.ShareQuiz(db, quiz.ID, body.User)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "No other user with that username or email", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(share, http.StatusCreated, "Quiz shared successfully"))
+	}
+}
+
+// GetQuizShares lists the users the owner has shared a quiz with.
+func GetQuizShares(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz := userQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		shares, err := database.FetchQuizShares(db, quiz.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(shares, http.StatusOK, "Quiz shares retrieved successfully"))
+	}
+}
+
+// UnshareQuiz lets the owner of a quiz stop sharing it with a user.
+func UnshareQuiz(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		sharedWith, err := strconv.ParseInt(mux.Vars(r)["userID"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid user ID: %v", err), http.StatusBadRequest)
+			return
+		}
+		quiz := userQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		if err := database.DeleteQuizShare(db, quiz.ID, sharedWith); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Quiz is not shared with that user", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(nil, http.StatusOK, "Quiz unshared successfully"))
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// GetUserStats returns the user's quiz totals and their skill estimate on
+// every topic they have taken a quiz on, with the recommended difficulty for
+// the next one, and their accuracy by topic and level and over time. The
+// bucket query parameter sets the trend's granularity: day, week (default)
+// or month.
+func GetUserStats(tracker *skill.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		bucket := r.URL.Query().Get("bucket")
+		switch bucket {
+		case "":
+			bucket = types.BucketWeek
+		case types.BucketDay, types.BucketWeek, types.BucketMonth:
+		default:
+			http.Error(w, "bucket must be day, week or month", http.StatusBadRequest)
+			return
+		}
+
+		stats, err := tracker.Stats(int64(userID), bucket)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(stats, http.StatusOK, "Stats retrieved successfully"))
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/scoring"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// SubmitQuiz grades the user's answers to one of their quizzes against the
+// stored correct answers and saves them as a finished attempt. The score is
+// computed here; the client only sends answers. Timed quizzes must be
+// submitted to an attempt started with StartAttempt, before its deadline.
+func SubmitQuiz(db *sql.DB, tracker *skill.Tracker, late database.LateSubmission) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		quiz := userQuiz(w, r, db)
+		if quiz == nil {
+			return
+		}
+
+		var submission types.Submission
+		if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+			if errors.Is(err, io.EOF) {
+				http.Error(w, "No data provided", http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("Failed to decode JSON: %v", err), http.StatusBadRequest)
+			}
+			return
+		}
+		if err := validator.New().Struct(&submission); err != nil {
+			response.ValidateResponse(w, err)
+			return
+		}
+
+		graded, err := database.SubmitQuiz(db, quiz.ID, quiz.UserID, submission.Answers, scoring.IsCorrect, late)
+		if graded != nil {
+			recordAttempt(tracker, quiz, graded)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, database.ErrNotFound):
+				http.Error(w, "Quiz not found", http.StatusNotFound)
+			case errors.Is(err, database.ErrInvalidAnswer):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, database.ErrNoOpenAttempt):
+				http.Error(w, "Start an attempt before submitting a timed quiz", http.StatusConflict)
+			case errors.Is(err, database.ErrDeadlinePassed):
+				http.Error(w, "The attempt's deadline has passed; it was graded with the answers saved before it", http.StatusConflict)
+			default:
+				http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		msg := "Quiz graded successfully"
+		if graded.Late {
+			msg = "Deadline passed; graded with the answers saved before it"
+		} else if len(graded.Voided) > 0 {
+			msg = "Quiz graded; answers given after their question's time limit were not counted"
+		}
+		response.WriteResponse(w, response.CreateResponse(graded, http.StatusOK, msg))
+	}
+}
+
+// recordAttempt updates the user's skill rating with a finished attempt. A
+// retake only refreshes the cached stats: the user had seen the answers.
+func recordAttempt(tracker *skill.Tracker, quiz *types.Quiz, graded *types.GradedQuiz) {
+	if graded.Retake {
+		tracker.Forget(int64(quiz.UserID))
+		return
+	}
+	finished := *quiz
+	finished.Score, finished.TotalQuestions, finished.SubmittedAt = graded.Score, graded.TotalQuestions, &graded.SubmittedAt
+	if err := tracker.Record(&finished); err != nil {
+		log.Printf("[Attempts] Failed to update skill rating of user %d: %v", quiz.UserID, err)
+	}
+}
+
+// closeExpiredAttempt finishes the quiz's open attempt if its time ran out
+// without a submission, so that a new one can start, and reports whether it
+// did. Failures are logged; the attempt is then closed on a later request
+// or sweep.
+func closeExpiredAttempt(db *sql.DB, tracker *skill.Tracker, quiz *types.Quiz, late database.LateSubmission) bool {
+	graded, err := database.CloseExpiredAttempt(db, quiz.ID, late.Grace, scoring.IsCorrect)
+	if err != nil {
+		log.Printf("[Attempts] Failed to close expired attempt at quiz %d: %v", quiz.ID, err)
+		return false
+	}
+	if graded == nil {
+		return false
+	}
+	recordAttempt(tracker, quiz, graded)
+	return true
+}
+
+// CloseExpiredAttempts finishes every open attempt more than late.Grace past
+// its deadline and returns how many, so that an attempt left to run out is
+// graded and rated even if its user never comes back.
+func CloseExpiredAttempts(db *sql.DB, tracker *skill.Tracker, late database.LateSubmission) (int, error) {
+	quizzes, err := database.FetchExpiredAttemptQuizzes(db, late.Grace)
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	for _, quiz := range quizzes {
+		if closeExpiredAttempt(db, tracker, quiz, late) {
+			closed++
+		}
+	}
+	return closed, nil
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/topics"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// backfillLimit is how many distinct unresolved quiz names are re-resolved
+// after the taxonomy changes.
+const backfillLimit = 500
+
+// GetTopics lists the canonical topics with their aliases. Each names its
+// parent, so clients can build the tree.
+func GetTopics(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		all, err := database.FetchTopics(r.Context(), db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(all, http.StatusOK, "Topics retrieved successfully"))
+	}
+}
+
+// ResolveTopic shows which canonical topic the topic query parameter
+// resolves to, with its ancestors, root first. The topic is null if it
+// resolves to none.
+func ResolveTopic(resolver *topics.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		text := normalizeTopic(r.URL.Query().Get("topic"))
+		if text == "" {
+			http.Error(w, "topic is required", http.StatusBadRequest)
+			return
+		}
+		topic, err := resolver.Resolve(r.Context(), text)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		data := map[string]interface{}{
+			"topic": topic,
+			"path":  []types.Topic{},
+		}
+		if topic != nil {
+			data["path"] = resolver.Path(topic.ID)
+		}
+		response.WriteResponse(w, response.CreateResponse(data, http.StatusOK, "Topic resolved successfully"))
+	}
+}
+
+// GetUnresolvedTopics lists the most common quiz names that resolve to no
+// topic, as candidates for new topics or aliases.
+func GetUnresolvedTopics(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := 50
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, backfillLimit)
+		}
+
+		unresolved, err := database.FetchUnresolvedTopics(r.Context(), db, limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.WriteResponse(w, response.CreateResponse(unresolved, http.StatusOK, "Unresolved topics retrieved successfully"))
+	}
+}
+
+// topicChanged reloads the resolver after an admin edit and links saved
+// quizzes that now resolve to a topic. Failures are logged; the edit itself
+// has been saved.
+func topicChanged(ctx context.Context, resolver *topics.Resolver) {
+	if err := resolver.Reload(ctx); err != nil {
+		log.Printf("[Topics] Reloading the taxonomy failed: %v", err)
+		return
+	}
+	linked, err := resolver.Backfill(ctx, backfillLimit)
+	if err != nil {
+		log.Printf("[Topics] Linking quizzes to topics failed: %v", err)
+	}
+	if linked > 0 {
+		log.Printf("[Topics] Linked %d quizzes to topics", linked)
+	}
+}
+
+// topicConflict returns the topic other than id that name already names or
+// is an alias of, if any.
+func topicConflict(all []types.Topic, name string, id int64) *types.Topic {
+	key := topics.Key(name)
+	for i, topic := range all {
+		if topic.ID == id {
+			continue
+		}
+		for _, other := range append([]string{topic.Name}, topic.Aliases...) {
+			if topics.Key(other) == key {
+				return &all[i]
+			}
+		}
+	}
+	return nil
+}
+
+// CreateTopic adds a canonical topic, optionally under a parent and with
+// aliases.
+func CreateTopic(db *sql.DB, resolver *topics.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		var topic types.Topic
+		if err := json.NewDecoder(r.Body).Decode(&topic); err != nil {
+			http.Error(w, "Failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		topic.Name = strings.TrimSpace(topic.Name)
+		if topics.Key(topic.Name) == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		all, err := database.FetchTopics(r.Context(), db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for _, name := range append([]string{topic.Name}, topic.Aliases...) {
+			if other := topicConflict(all, name, 0); other != nil {
+				http.Error(w, fmt.Sprintf("%q already resolves to the topic %q", name, other.Name), http.StatusConflict)
+				return
+			}
+		}
+		if topic.ParentID != nil && !slices.ContainsFunc(all, func(t types.Topic) bool { return t.ID == *topic.ParentID }) {
+			http.Error(w, "Parent topic not found", http.StatusNotFound)
+			return
+		}
+
+		if err := database.InsertTopic(db, &topic); err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		aliases := []string{}
+		for _, alias := range topic.Aliases {
+			alias = strings.TrimSpace(alias)
+			key := topics.Key(alias)
+			if key == "" || key == topics.Key(topic.Name) {
+				continue
+			}
+			if err := database.InsertTopicAlias(db, topic.ID, alias, key); err != nil {
+				http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+				return
+			}
+			aliases = append(aliases, alias)
+		}
+		topic.Aliases = aliases
+
+		topicChanged(r.Context(), resolver)
+		response.WriteResponse(w, response.CreateResponse(topic, http.StatusCreated, "Topic created successfully"))
+	}
+}
+
+// UpdateTopic renames a topic or moves it under another parent; a null
+// parent_id makes it a root topic. Skill ratings follow the rename.
+func UpdateTopic(db *sql.DB, resolver *topics.Resolver, tracker *skill.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid topic Id : %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		var update struct {
+			Name     string `json:"name"`
+			ParentID *int64 `json:"parent_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		update.Name = strings.TrimSpace(update.Name)
+		if topics.Key(update.Name) == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		all, err := database.FetchTopics(r.Context(), db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if other := topicConflict(all, update.Name, id); other != nil {
+			http.Error(w, fmt.Sprintf("%q already resolves to the topic %q", update.Name, other.Name), http.StatusConflict)
+			return
+		}
+		var oldName string
+		for _, topic := range all {
+			if topic.ID == id {
+				oldName = topic.Name
+			}
+		}
+
+		if err := database.UpdateTopic(db, id, update.Name, update.ParentID); err != nil {
+			switch {
+			case errors.Is(err, database.ErrNotFound):
+				http.Error(w, "Topic not found", http.StatusNotFound)
+			case errors.Is(err, database.ErrTopicCycle):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		if oldName != "" {
+			if err := tracker.RenameTopic(oldName, update.Name); err != nil {
+				log.Printf("[UpdateTopic] Failed to move skill ratings from %q to %q: %v", oldName, update.Name, err)
+			}
+		}
+
+		topicChanged(r.Context(), resolver)
+		response.WriteResponse(w, response.CreateResponse(nil, http.StatusOK, "Topic updated successfully"))
+	}
+}
+
+// AddTopicAlias makes another name resolve to a topic. An alias of another
+// topic is moved to this one.
+func AddTopicAlias(db *sql.DB, resolver *topics.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid topic Id : %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		var body struct {
+			Alias string `json:"alias"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		alias := strings.TrimSpace(body.Alias)
+		key := topics.Key(alias)
+		if key == "" {
+			http.Error(w, "alias is required", http.StatusBadRequest)
+			return
+		}
+
+		all, err := database.FetchTopics(r.Context(), db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		found := false
+		for _, topic := range all {
+			if topic.ID == id {
+				found = true
+			} else if topics.Key(topic.Name) == key {
+				http.Error(w, fmt.Sprintf("%q is the name of the topic %q", alias, topic.Name), http.StatusConflict)
+				return
+			}
+		}
+		if !found {
+			http.Error(w, "Topic not found", http.StatusNotFound)
+			return
+		}
+
+		if err := database.InsertTopicAlias(db, id, alias, key); err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		topicChanged(r.Context(), resolver)
+		response.WriteResponse(w, response.CreateResponse(nil, http.StatusCreated, "Topic alias added successfully"))
+	}
+}
+
+// DeleteTopicAlias removes the alias query parameter from a topic's aliases.
+// Quizzes already linked through it keep their topic.
+func DeleteTopicAlias(db *sql.DB, resolver *topics.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid topic Id : %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		if err := database.DeleteTopicAlias(db, id, topics.Key(r.URL.Query().Get("alias"))); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, "Topic alias not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if err := resolver.Reload(r.Context()); err != nil {
+			log.Printf("[Topics] Reloading the taxonomy failed: %v", err)
+		}
+		response.WriteResponse(w, response.CreateResponse(nil, http.StatusOK, "Topic alias deleted successfully"))
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/quota"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
+)
+
+// recentUsageLimit is how many ledger entries GetUsage returns.
+const recentUsageLimit = 20
+
+// reserveQuota counts a new generation of the user with insert, which adds
+// its job or pending usage record, if the user's quotas allow it (see
+// quota.Limiter.Reserve). Otherwise it writes a 429 response, or a 500 if
+// the reservation failed, and returns false.
+func reserveQuota(w http.ResponseWriter, limiter *quota.Limiter, userID int64, insert func(tx *sql.Tx) error) bool {
+	err := limiter.Reserve(userID, insert)
+	if err == nil {
+		return true
+	}
+
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		log.Printf("[Quota] Failed to reserve a generation for user %d: %v", userID, err)
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return false
+	}
+
+	retryAfter := int(time.Until(exceeded.ResetsAt).Seconds()) + 1
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	message := fmt.Sprintf("You have used your %s quota of %d %s, it resets at %s",
+		map[string]string{"day": "daily", "month": "monthly"}[exceeded.Period], exceeded.Limit, exceeded.Kind, exceeded.ResetsAt.Format(time.RFC3339))
+	response.WriteResponse(w, response.CreateResponse(exceeded, http.StatusTooManyRequests, message, true, message))
+	return false
+}
+
+// reserveUsage reserves a pending usage record for a generation the handler
+// runs itself and returns its ID, for database.FinishUsageRecord.
+func reserveUsage(w http.ResponseWriter, limiter *quota.Limiter, userID int64) (int64, bool) {
+	var usageID int64
+	ok := reserveQuota(w, limiter, userID, func(tx *sql.Tx) (err error) {
+		usageID, err = database.InsertPendingUsage(tx, userID)
+		return err
+	})
+	return usageID, ok
+}
+
+// GetUsage returns the user's generation usage in the current day and month
+// against their quotas, and their most recent generations.
+func GetUsage(db *sql.DB, limiter *quota.Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("%v HTTP method is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := strconv.Atoi(r.Header.Get("userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		summary, err := limiter.Summary(int64(userID))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		recent, err := database.FetchRecentUsage(db, int64(userID), recentUsageLimit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		data := map[string]interface{}{
+			"day":    summary.Day,
+			"month":  summary.Month,
+			"recent": recent,
+		}
+		response.WriteResponse(w, response.CreateResponse(data, http.StatusOK, "Usage retrieved successfully"))
+	}
+}
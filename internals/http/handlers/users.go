@@ -12,12 +12,14 @@ import (
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"firebase.google.com/go/v4/auth"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/locale"
 
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/password"
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/response"
@@ -635,6 +637,22 @@ func UpdateUserDetails(db *sql.DB) http.HandlerFunc {
 			}
 		}
 
+		if request.IsLanguageChanged {
+			language := ""
+			if strings.TrimSpace(request.Language) != "" {
+				tag, err := locale.Canonical(request.Language)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				language = tag
+			}
+			if err := database.UpdatePreferredLanguage(db, userId, language); err != nil {
+				http.Error(w, "Failed to update preferred language", http.StatusInternalServerError)
+				return
+			}
+		}
+
 		if request.IsUsernameChanged {
 
 			u, _ := database.RetrieveUser(db, request.Username)
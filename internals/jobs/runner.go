@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// maxAttempts bounds how often a job is retried after being requeued, so a
+// job that keeps crashing the server doesn't loop forever.
+const maxAttempts = 3
+
+// Runner drains the quiz_jobs table in the background with a fixed number of
+// workers. Jobs are claimed from the database, so several server instances
+// can share one queue.
+type Runner struct {
+	db           *sql.DB
+	checker      *generateQuiz.Checker
+	workers      int
+	pollInterval time.Duration
+
+	wake     chan struct{}
+	stopping chan struct{}
+	// jobCtx is cancelled when Shutdown runs out of time; in-flight jobs are
+	// then requeued instead of failed.
+	jobCtx    context.Context
+	cancelJob context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewRunner(db *sql.DB, checker *generateQuiz.Checker, workers int, pollInterval time.Duration) *Runner {
+	if workers <= 0 {
+		workers = 1
+	}
+	jobCtx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		db:           db,
+		checker:      checker,
+		workers:      workers,
+		pollInterval: pollInterval,
+		wake:         make(chan struct{}, 1),
+		stopping:     make(chan struct{}),
+		jobCtx:       jobCtx,
+		cancelJob:    cancel,
+	}
+}
+
+// Start requeues jobs orphaned by a previous crash and launches the workers.
+func (r *Runner) Start() {
+	// A job that hasn't been touched for far longer than any generation can
+	// take belongs to an instance that is gone.
+	if n, err := database.RequeueStaleQuizJobs(r.db, 15*60); err != nil {
+		log.Printf("[Jobs] %v", err)
+	} else if n > 0 {
+		log.Printf("[Jobs] Requeued %d stale jobs", n)
+	}
+
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work(i)
+	}
+}
+
+// Notify wakes an idle worker after a job has been enqueued.
+func (r *Runner) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Shutdown stops claiming new jobs and waits for in-flight jobs to finish.
+// If ctx expires first, in-flight jobs are cancelled and put back in the
+// queue for the next start.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	close(r.stopping)
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancelJob()
+		return nil
+	case <-ctx.Done():
+		r.cancelJob()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work(id int) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that's queued before going back to sleep.
+		for {
+			select {
+			case <-r.stopping:
+				return
+			default:
+			}
+
+			job, err := database.ClaimNextQuizJob(r.jobCtx, r.db)
+			if err != nil {
+				log.Printf("[Jobs] Worker %d: %v", id, err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			r.run(job)
+		}
+
+		select {
+		case <-r.stopping:
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) run(job *types.QuizJob) {
+	start := time.Now()
+	log.Printf("[Jobs] Running job %d for user %d (attempt %d)", job.ID, job.UserID, job.Attempts)
+
+	if job.Attempts > maxAttempts {
+		r.fail(job, "Quiz generation was interrupted too many times")
+		return
+	}
+
+	usage := &generateQuiz.Usage{}
+	result, err := r.checker.Generate(generateQuiz.WithUsage(r.jobCtx, usage), &job.Request)
+	// A job interrupted by shutdown is charged once it reruns.
+	if r.jobCtx.Err() == nil {
+		jobID := job.ID
+		if err := database.InsertUsageRecord(r.db, usage.Record(job.UserID, &jobID, time.Since(start), err)); err != nil {
+			log.Printf("[Jobs] %v", err)
+		}
+	}
+	if err != nil {
+		if r.jobCtx.Err() != nil {
+			log.Printf("[Jobs] Job %d interrupted by shutdown, requeueing", job.ID)
+			if err := database.RequeueQuizJob(r.db, job.ID); err != nil {
+				log.Printf("[Jobs] %v", err)
+			}
+			return
+		}
+
+		log.Printf("[Jobs] Job %d failed: %v", job.ID, err)
+		var refused *generateQuiz.RefusedError
+		if errors.As(err, &refused) {
+			r.fail(job, refused.Message)
+		} else if errors.Is(err, generateQuiz.ErrPoolBusy) {
+			r.fail(job, "Quiz generation is busy, please try again shortly")
+		} else if errors.Is(err, generateQuiz.ErrUnavailable) {
+			r.fail(job, "Quiz generation is temporarily unavailable, please try again shortly")
+		} else {
+			r.fail(job, "Quiz generation failed")
+		}
+		return
+	}
+
+	if err := database.CompleteQuizJob(r.db, job.ID, result.Questions, &result.Report); err != nil {
+		log.Printf("[Jobs] %v", err)
+		return
+	}
+	log.Printf("[Jobs] Job %d generated %d questions in %d rounds (%d repaired, %d dropped) in %v",
+		job.ID, len(result.Questions), result.Report.Rounds, len(result.Report.Repaired), len(result.Report.Dropped), time.Since(start))
+}
+
+func (r *Runner) fail(job *types.QuizJob, message string) {
+	if err := database.FailQuizJob(r.db, job.ID, message); err != nil {
+		log.Printf("[Jobs] %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package locale handles the language quizzes are generated in: parsing
+// BCP-47 tags, naming them for prompts and checking that generated text is
+// written in the language's script.
+package locale
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// Default is the language of quizzes when neither the request nor the
+// user's profile names one.
+const Default = "en"
+
+// Canonical parses a BCP-47 tag such as "bn", "hi-IN" or "pt_BR" and returns
+// its canonical form.
+func Canonical(tag string) (string, error) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return "", fmt.Errorf("language is empty")
+	}
+	t, err := language.Parse(tag)
+	if err != nil {
+		return "", fmt.Errorf("invalid language %q: %w", tag, err)
+	}
+	if base, conf := t.Base(); conf != language.Exact || base.String() == "und" {
+		return "", fmt.Errorf("invalid language %q", tag)
+	}
+	return t.String(), nil
+}
+
+// Name describes a canonical tag for the model, e.g. "Bangla / বাংলা" for
+// "bn". Unknown tags are returned as they are.
+func Name(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	english := display.English.Tags().Name(t)
+	if english == "" {
+		return tag
+	}
+	if self := display.Self.Name(t); self != "" && self != english {
+		return english + " / " + self
+	}
+	return english
+}
+
+// IsEnglish reports whether tag is some variant of English.
+func IsEnglish(tag string) bool {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return false
+	}
+	base, _ := t.Base()
+	return base.String() == "en"
+}
+
+// scripts maps ISO 15924 script codes to the Unicode ranges text in that
+// script is written with. Japanese and Korean mix several scripts.
+var scripts = map[string][]*unicode.RangeTable{
+	"Latn": {unicode.Latin},
+	"Arab": {unicode.Arabic},
+	"Armn": {unicode.Armenian},
+	"Beng": {unicode.Bengali},
+	"Cyrl": {unicode.Cyrillic},
+	"Deva": {unicode.Devanagari},
+	"Ethi": {unicode.Ethiopic},
+	"Geor": {unicode.Georgian},
+	"Grek": {unicode.Greek},
+	"Gujr": {unicode.Gujarati},
+	"Guru": {unicode.Gurmukhi},
+	"Hang": {unicode.Hangul},
+	"Hans": {unicode.Han},
+	"Hant": {unicode.Han},
+	"Hebr": {unicode.Hebrew},
+	"Jpan": {unicode.Han, unicode.Hiragana, unicode.Katakana},
+	"Khmr": {unicode.Khmer},
+	"Knda": {unicode.Kannada},
+	"Kore": {unicode.Hangul, unicode.Han},
+	"Laoo": {unicode.Lao},
+	"Mlym": {unicode.Malayalam},
+	"Mymr": {unicode.Myanmar},
+	"Orya": {unicode.Oriya},
+	"Sinh": {unicode.Sinhala},
+	"Taml": {unicode.Tamil},
+	"Telu": {unicode.Telugu},
+	"Thai": {unicode.Thai},
+}
+
+// Script returns the ISO 15924 code of the script tag is usually written in,
+// or "" if it can't be determined.
+func Script(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return ""
+	}
+	script, conf := t.Script()
+	if conf == language.No {
+		return ""
+	}
+	return script.String()
+}
+
+// ScriptShare returns the share of the letters in text that belong to
+// script. ok is false if the script is unknown or text has no letters, so
+// that there is nothing to judge by.
+func ScriptShare(text, script string) (share float64, ok bool) {
+	tables, known := scripts[script]
+	if !known {
+		return 0, false
+	}
+	letters, matching := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.In(r, tables...) {
+			matching++
+		}
+	}
+	if letters == 0 {
+		return 0, false
+	}
+	return float64(matching) / float64(letters), true
+}
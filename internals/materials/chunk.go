@@ -0,0 +1,160 @@
+package materials
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/similarity"
+)
+
+// ChunkSize is the target length of a chunk in characters. Paragraphs are
+// kept together where they fit.
+const ChunkSize = 1500
+
+// Chunk splits text into chunks of about ChunkSize characters along
+// paragraph and, for long paragraphs, sentence boundaries.
+func Chunk(text string) []string {
+	var chunks []string
+	var current strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+	}
+	add := func(piece, sep string) {
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(piece) > ChunkSize {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
+		}
+		current.WriteString(piece)
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if utf8.RuneCountInString(paragraph) <= ChunkSize {
+			add(paragraph, "\n\n")
+			continue
+		}
+		for _, sentence := range sentences(paragraph) {
+			for _, piece := range hardWrap(sentence, ChunkSize) {
+				add(piece, " ")
+			}
+		}
+		flush()
+	}
+	flush()
+	return chunks
+}
+
+// sentences splits on ". ", "? ", "! " and line breaks.
+func sentences(paragraph string) []string {
+	var out []string
+	start := 0
+	runes := []rune(paragraph)
+	for i, r := range runes {
+		end := r == '\n' || ((r == '.' || r == '?' || r == '!') && i+1 < len(runes) && runes[i+1] == ' ')
+		if end {
+			if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+				out = append(out, s)
+			}
+			start = i + 1
+		}
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// hardWrap cuts s into pieces of at most n characters at word boundaries.
+func hardWrap(s string, n int) []string {
+	if utf8.RuneCountInString(s) <= n {
+		return []string{s}
+	}
+	var out []string
+	var line strings.Builder
+	for _, word := range strings.Fields(s) {
+		if line.Len() > 0 && utf8.RuneCountInString(line.String())+1+utf8.RuneCountInString(word) > n {
+			out = append(out, line.String())
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(word)
+	}
+	if line.Len() > 0 {
+		out = append(out, line.String())
+	}
+	return out
+}
+
+// Select picks the chunks most relevant to query that fit in budget
+// characters, and returns them in document order. When nothing matches the
+// query (say, the topic is just the document's title), chunks are spread
+// evenly across the document instead.
+func Select(chunks []string, query string, budget int) []string {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	order := make([]int, len(chunks))
+	scores := make([]float64, len(chunks))
+	matched := false
+	for i, chunk := range chunks {
+		order[i] = i
+		scores[i] = similarity.Relevance(query, chunk)
+		matched = matched || scores[i] > 0
+	}
+
+	if matched {
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	} else {
+		order = spread(len(chunks))
+	}
+
+	var picked []int
+	used := 0
+	for _, i := range order {
+		size := utf8.RuneCountInString(chunks[i])
+		if used+size > budget && len(picked) > 0 {
+			continue
+		}
+		picked = append(picked, i)
+		used += size
+	}
+	sort.Ints(picked)
+
+	selected := make([]string, len(picked))
+	for i, index := range picked {
+		selected[i] = chunks[index]
+	}
+	return selected
+}
+
+// spread orders 0..n-1 so that any prefix covers the range evenly: first,
+// middle, then the quarters and so on.
+func spread(n int) []int {
+	order := make([]int, 0, n)
+	seen := make([]bool, n)
+	for step := n; len(order) < n; step = max(1, step/2) {
+		for i := 0; i < n; i += step {
+			if !seen[i] {
+				seen[i] = true
+				order = append(order, i)
+			}
+		}
+		if step == 1 {
+			break
+		}
+	}
+	return order
+}
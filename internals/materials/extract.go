@@ -0,0 +1,149 @@
+// Package materials turns uploaded study material (plain text, Markdown,
+// HTML or PDF) into plain text, splits it into chunks and picks the chunks
+// relevant to a quiz. Everything runs locally; nothing is sent anywhere
+// until the selected chunks go into the generation prompt.
+package materials
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Material kinds
+const (
+	KindText     = "text"
+	KindMarkdown = "markdown"
+	KindHTML     = "html"
+	KindPDF      = "pdf"
+)
+
+// ErrUnsupported is returned for files that are none of the supported kinds.
+var ErrUnsupported = errors.New("unsupported material type: upload plain text, Markdown, HTML or PDF")
+
+// ErrNoText is returned when a file contains no readable text, e.g. a
+// scanned PDF.
+var ErrNoText = errors.New("no readable text found in material")
+
+// DetectKind works out the kind of an upload from its file name, declared
+// content type and, failing those, its content.
+func DetectKind(filename, contentType string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".text":
+		return KindText, nil
+	case ".md", ".markdown":
+		return KindMarkdown, nil
+	case ".html", ".htm":
+		return KindHTML, nil
+	case ".pdf":
+		return KindPDF, nil
+	}
+
+	if contentType == "" || strings.HasPrefix(contentType, "application/octet-stream") {
+		contentType = http.DetectContentType(data)
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch mediaType {
+	case "text/plain":
+		return KindText, nil
+	case "text/markdown", "text/x-markdown":
+		return KindMarkdown, nil
+	case "text/html", "application/xhtml+xml":
+		return KindHTML, nil
+	case "application/pdf":
+		return KindPDF, nil
+	}
+	return "", ErrUnsupported
+}
+
+// Extract returns the plain text of data, which is of the given kind.
+func Extract(kind string, data []byte) (string, error) {
+	var text string
+	switch kind {
+	case KindText:
+		text = decodeText(data)
+	case KindMarkdown:
+		text = markdownText(decodeText(data))
+	case KindHTML:
+		var err error
+		if text, err = htmlText(data); err != nil {
+			return "", err
+		}
+	case KindPDF:
+		var err error
+		if text, err = pdfText(data); err != nil {
+			return "", err
+		}
+	default:
+		return "", ErrUnsupported
+	}
+
+	text = cleanText(text)
+	if !readable(text) {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+// decodeText reads UTF-8, dropping a byte order mark; invalid bytes are
+// treated as Latin-1, which is what legacy text files usually are.
+func decodeText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// cleanText normalizes line endings, trims every line and collapses runs of
+// blank lines, keeping paragraph breaks for chunking.
+func cleanText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var sb strings.Builder
+	blank := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.FieldsFunc(line, func(r rune) bool {
+			return unicode.IsSpace(r) || (unicode.IsControl(r) && r != '\t')
+		}), " ")
+		if line == "" {
+			blank++
+			continue
+		}
+		if sb.Len() > 0 {
+			if blank > 0 {
+				sb.WriteString("\n\n")
+			} else {
+				sb.WriteString("\n")
+			}
+		}
+		blank = 0
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// readable reports whether text is mostly letters, digits, spaces and
+// punctuation rather than decoding garbage.
+func readable(text string) bool {
+	if strings.TrimSpace(text) == "" {
+		return false
+	}
+	good, total := 0, 0
+	for _, r := range text {
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) {
+			good++
+		}
+	}
+	return float64(good)/float64(total) >= 0.85
+}
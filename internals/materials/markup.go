@@ -0,0 +1,87 @@
+package materials
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	mdLink       = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	mdHeading    = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	mdListItem   = regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+[.)])\s+`)
+	mdQuote      = regexp.MustCompile(`(?m)^\s*>\s?`)
+	mdEmphasis   = regexp.MustCompile(`(\*\*|__|\*|_|~~)([^*_~\n]+)(\*\*|__|\*|_|~~)`)
+	mdFence      = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	mdInlineCode = regexp.MustCompile("`([^`]*)`")
+	mdRule       = regexp.MustCompile(`(?m)^\s*([-*_]\s*){3,}$`)
+	mdHTMLTag    = regexp.MustCompile(`<[^>\n]+>`)
+)
+
+// markdownText strips Markdown syntax, keeping the text of links, headings,
+// lists and code blocks.
+func markdownText(text string) string {
+	text = mdFence.ReplaceAllString(text, "")
+	text = mdRule.ReplaceAllString(text, "")
+	text = mdLink.ReplaceAllString(text, "$1")
+	text = mdHeading.ReplaceAllString(text, "")
+	text = mdListItem.ReplaceAllString(text, "")
+	text = mdQuote.ReplaceAllString(text, "")
+	text = mdEmphasis.ReplaceAllString(text, "$2")
+	text = mdInlineCode.ReplaceAllString(text, "$1")
+	text = mdHTMLTag.ReplaceAllString(text, "")
+	return text
+}
+
+// skippedElements hold no readable content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Head: true, atom.Iframe: true, atom.Object: true,
+}
+
+// blockElements end a line of text.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Section: true, atom.Article: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Table: true, atom.Ul: true, atom.Ol: true, atom.Dt: true, atom.Dd: true,
+	atom.Header: true, atom.Footer: true, atom.Figcaption: true, atom.Hr: true,
+}
+
+// htmlText returns the visible text of an HTML document with block elements
+// on their own lines.
+func htmlText(data []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && skippedElements[n.DataAtom] {
+			return
+		}
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Td || n.DataAtom == atom.Th) {
+			sb.WriteString(" ")
+		}
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			sb.WriteString("\n\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			sb.WriteString("\n\n")
+		}
+	}
+	walk(doc)
+	return sb.String(), nil
+}
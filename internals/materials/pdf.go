@@ -0,0 +1,388 @@
+package materials
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxStreamSize bounds a single decompressed PDF stream.
+const maxStreamSize = 16 << 20
+
+// maxArrayDepth bounds the nesting of arrays in a content stream. Real text
+// arrays are flat; deeper nesting is only ever an attempt to exhaust the
+// stack.
+const maxArrayDepth = 32
+
+var (
+	pdfStream     = regexp.MustCompile(`stream\r?\n`)
+	pdfDictStart  = regexp.MustCompile(`\d+\s+\d+\s+obj\b`)
+	pdfFlate      = regexp.MustCompile(`/FlateDecode\b`)
+	pdfOtherCodec = regexp.MustCompile(`/(DCTDecode|JPXDecode|CCITTFaxDecode|JBIG2Decode|LZWDecode|RunLengthDecode|ASCII85Decode|ASCIIHexDecode)\b`)
+	pdfNonContent = regexp.MustCompile(`/Subtype\s*/(Image|XML)|/Type\s*/(Metadata|XRef|ObjStm|EmbeddedFile)|/Length[123]\b|/FontFile`)
+)
+
+var (
+	// errEncryptedPDF is returned for password-protected PDFs.
+	errEncryptedPDF = errors.New("encrypted PDFs are not supported")
+	// errInvalidPDF is returned for PDFs too malformed to read safely.
+	errInvalidPDF = errors.New("invalid PDF")
+)
+
+// pdfText extracts the text shown by the content streams of a PDF. It reads
+// uncompressed and Flate-compressed streams and the text operators Tj, TJ, '
+// and ". Fonts with custom encodings (common for CID fonts) yield no usable
+// text, which Extract reports as ErrNoText; scanned documents have no text
+// to extract at all.
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return "", ErrUnsupported
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", errEncryptedPDF
+	}
+
+	var sb strings.Builder
+	for _, loc := range pdfStream.FindAllIndex(data, -1) {
+		// The stream's dictionary lies between the object header and the
+		// "stream" keyword.
+		dictStart := 0
+		if headers := pdfDictStart.FindAllIndex(data[max(0, loc[0]-4096):loc[0]], -1); len(headers) > 0 {
+			dictStart = max(0, loc[0]-4096) + headers[len(headers)-1][0]
+		}
+		dict := data[dictStart:loc[0]]
+		if pdfNonContent.Match(dict) || pdfOtherCodec.Match(dict) {
+			continue
+		}
+
+		end := bytes.Index(data[loc[1]:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		raw := data[loc[1] : loc[1]+end]
+
+		content := raw
+		if pdfFlate.Match(dict) {
+			r, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			content, err = io.ReadAll(io.LimitReader(r, maxStreamSize))
+			r.Close()
+			if err != nil && len(content) == 0 {
+				continue
+			}
+		}
+
+		text, err := contentStreamText(content)
+		if err != nil {
+			return "", err
+		}
+		if text != "" {
+			sb.WriteString(text)
+			sb.WriteString("\n\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+// contentStreamText interprets the text operators of one content stream.
+func contentStreamText(content []byte) (string, error) {
+	var sb strings.Builder
+	var operands []pdfToken
+	inText := false
+
+	lex := pdfLexer{data: content}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		if tok.kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch tok.text {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			sb.WriteString("\n")
+		case "Tj", "'", "\"":
+			if tok.text != "Tj" {
+				sb.WriteString("\n")
+			}
+			if n := len(operands); inText && n > 0 && operands[n-1].kind == pdfString {
+				sb.WriteString(operands[n-1].text)
+			}
+		case "TJ":
+			if n := len(operands); inText && n > 0 && operands[n-1].kind == pdfArray {
+				for _, part := range operands[n-1].items {
+					switch part.kind {
+					case pdfString:
+						sb.WriteString(part.text)
+					case pdfNumber:
+						// A large negative adjustment is a word gap.
+						if v, err := strconv.ParseFloat(part.text, 64); err == nil && v < -200 {
+							sb.WriteString(" ")
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if n := len(operands); n >= 2 {
+				if ty, err := strconv.ParseFloat(operands[n-1].text, 64); err == nil && ty != 0 {
+					sb.WriteString("\n")
+				} else {
+					sb.WriteString(" ")
+				}
+			}
+		case "T*", "Tm":
+			sb.WriteString("\n")
+		}
+		operands = operands[:0]
+	}
+
+	if lex.err != nil {
+		return "", lex.err
+	}
+	if !readable(sb.String()) {
+		return "", nil
+	}
+	return sb.String(), nil
+}
+
+type pdfTokenKind int
+
+const (
+	pdfOperator pdfTokenKind = iota
+	pdfString
+	pdfNumber
+	pdfName
+	pdfArray
+	pdfOther
+)
+
+type pdfToken struct {
+	kind  pdfTokenKind
+	text  string
+	items []pdfToken
+}
+
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int // of the array being read
+	// err stops the lexer: next returns false from then on.
+	err error
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// next returns the next token. Arrays are returned whole; dictionaries
+// (inline image parameters, marked-content properties) are skipped. Arrays
+// nested deeper than maxArrayDepth stop the lexer with errInvalidPDF.
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.err == nil && l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: pdfString, text: decodePDFString(l.literal())}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.skipDict()
+			return pdfToken{kind: pdfOther}, true
+		case c == '<':
+			return pdfToken{kind: pdfString, text: decodePDFString(l.hex())}, true
+		case c == '[':
+			if l.depth >= maxArrayDepth {
+				l.err = errInvalidPDF
+				return pdfToken{}, false
+			}
+			l.pos++
+			l.depth++
+			var items []pdfToken
+			for {
+				tok, ok := l.next()
+				if !ok || (tok.kind == pdfOther && tok.text == "]") {
+					break
+				}
+				items = append(items, tok)
+			}
+			l.depth--
+			if l.err != nil {
+				return pdfToken{}, false
+			}
+			return pdfToken{kind: pdfArray, items: items}, true
+		case c == ']':
+			l.pos++
+			return pdfToken{kind: pdfOther, text: "]"}, true
+		case c == '/':
+			start := l.pos
+			l.pos++
+			for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			return pdfToken{kind: pdfName, text: string(l.data[start:l.pos])}, true
+		case isPDFDelimiter(c):
+			l.pos++
+		default:
+			start := l.pos
+			for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			word := string(l.data[start:l.pos])
+			if word == "ID" {
+				l.skipInlineImage()
+				return pdfToken{kind: pdfOther}, true
+			}
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				return pdfToken{kind: pdfNumber, text: word}, true
+			}
+			return pdfToken{kind: pdfOperator, text: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// literal reads a (...) string, handling escapes and balanced parentheses.
+func (l *pdfLexer) literal() []byte {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// hex reads a <...> string.
+func (l *pdfLexer) hex() []byte {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
+
+func (l *pdfLexer) skipDict() {
+	depth := 0
+	for l.pos < len(l.data) {
+		switch {
+		case bytes.HasPrefix(l.data[l.pos:], []byte("<<")):
+			depth++
+			l.pos += 2
+		case bytes.HasPrefix(l.data[l.pos:], []byte(">>")):
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+		case l.data[l.pos] == '(':
+			l.literal()
+		default:
+			l.pos++
+		}
+	}
+}
+
+// skipInlineImage skips binary inline image data up to the EI operator.
+func (l *pdfLexer) skipInlineImage() {
+	if i := bytes.Index(l.data[l.pos:], []byte("EI")); i >= 0 {
+		l.pos += i + 2
+	} else {
+		l.pos = len(l.data)
+	}
+}
+
+// decodePDFString decodes UTF-16BE strings (marked by a byte order mark) and
+// treats anything else as PDFDocEncoding, approximated by Latin-1.
+func decodePDFString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -0,0 +1,57 @@
+package materials
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// flatePDF wraps a content stream in a minimal PDF, Flate-compressed.
+func flatePDF(t *testing.T, content string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return []byte(fmt.Sprintf("%%PDF-1.4\n1 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n%sendstream\nendobj\n%%%%EOF\n",
+		compressed.Len(), compressed.String()))
+}
+
+func TestPDFTextReadsTextOperators(t *testing.T) {
+	data := flatePDF(t, "BT /F1 12 Tf (Photosynthesis converts light) Tj 0 -14 Td [(into chemical) -250 (energy.)] TJ ET")
+	text, err := Extract(KindPDF, data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	for _, want := range []string{"Photosynthesis converts light", "into chemical energy."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q does not contain %q", text, want)
+		}
+	}
+}
+
+func TestPDFTextRejectsDeeplyNestedArrays(t *testing.T) {
+	data := flatePDF(t, "BT "+strings.Repeat("[", 8<<20)+" ET")
+	if _, err := Extract(KindPDF, data); !errors.Is(err, errInvalidPDF) {
+		t.Fatalf("Extract error = %v, want %v", err, errInvalidPDF)
+	}
+}
+
+func TestPDFTextAcceptsNestingUpToLimit(t *testing.T) {
+	nested := strings.Repeat("[", maxArrayDepth) + strings.Repeat("]", maxArrayDepth)
+	data := flatePDF(t, "BT "+nested+" (Nested arrays are tolerated here) Tj ET")
+	text, err := Extract(KindPDF, data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if !strings.Contains(text, "Nested arrays are tolerated here") {
+		t.Errorf("text %q lost the string after the arrays", text)
+	}
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+)
+
+// AdminMiddleware only lets requests through whose X-Admin-Token header
+// matches the ADMIN_TOKEN environment variable. Admin endpoints are disabled
+// while ADMIN_TOKEN is unset.
+func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		adminToken := os.Getenv("ADMIN_TOKEN")
+		if adminToken == "" {
+			http.Error(w, "Admin endpoints are disabled", http.StatusForbidden)
+			return
+		}
+
+		token := r.Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			http.Error(w, "Invalid admin token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
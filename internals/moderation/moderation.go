@@ -0,0 +1,101 @@
+// Package moderation screens user text before it reaches a quiz prompt.
+//
+// A Screener first applies fixed rules: length limits, allowed character
+// classes, detection of text that reads like instructions to the model
+// rather than a quiz topic, and configurable blocklists. Text that passes is
+// then given to each Classifier in turn, the extension point for a
+// model-based check. Rejections are logged to the moderation_events table for
+// admins to review.
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Rejection codes
+const (
+	CodeTooLong           = "too_long"
+	CodeInvalidCharacters = "invalid_characters"
+	CodePromptInjection   = "prompt_injection"
+	CodeBlocked           = "blocked"
+	CodeUnsafe            = "unsafe" // for classifiers
+)
+
+// Rejection is returned by Screen when text is not allowed. It is also the
+// body of the 422 response.
+type Rejection struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+	// Check names the rule or classifier that rejected the text.
+	Check string `json:"check"`
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("rejected by %s (%s): %s", r.Check, r.Code, r.Reason)
+}
+
+// Classifier is an additional check run on text that passed the rules, e.g.
+// a call to a moderation model. It returns a non-nil Rejection to reject the
+// text; Check is filled in with Name if left empty.
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, text string) (*Rejection, error)
+}
+
+type Screener struct {
+	db          *sql.DB
+	rules       Rules
+	classifiers []Classifier
+}
+
+// New returns a Screener that logs its rejections to db. classifiers run in
+// order after the rules.
+func New(db *sql.DB, rules Rules, classifiers ...Classifier) *Screener {
+	return &Screener{db: db, rules: rules, classifiers: classifiers}
+}
+
+// Screen returns a *Rejection if the user's text may not be used in a
+// prompt. A classifier that fails is logged and skipped, so an outage of a
+// moderation model doesn't block quiz generation; the rules still apply.
+func (s *Screener) Screen(ctx context.Context, userID int64, text string) error {
+	rejection := s.rules.check(text)
+	for _, c := range s.classifiers {
+		if rejection != nil {
+			break
+		}
+		var err error
+		rejection, err = c.Classify(ctx, text)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			log.Printf("[Moderation] Classifier %s failed: %v", c.Name(), err)
+			rejection = nil
+			continue
+		}
+		if rejection != nil && rejection.Check == "" {
+			rejection.Check = c.Name()
+		}
+	}
+	if rejection == nil {
+		return nil
+	}
+
+	event := &types.ModerationEvent{
+		UserID: userID,
+		Text:   text,
+		Code:   rejection.Code,
+		Reason: rejection.Reason,
+		Check:  rejection.Check,
+	}
+	if err := database.InsertModerationEvent(s.db, event); err != nil {
+		log.Printf("[Moderation] %v", err)
+	}
+	return rejection
+}
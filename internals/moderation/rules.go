@@ -0,0 +1,154 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rules are the fixed checks of a Screener. Zero limits are unlimited.
+type Rules struct {
+	// MaxLength and MaxWords bound the text in characters and words.
+	MaxLength int
+	MaxWords  int
+	// Blocklist terms are matched case-insensitively as whole words, so
+	// "war" blocks "the war" but not "software".
+	Blocklist []string
+}
+
+// LoadBlocklist reads blocklist terms from files with one term per line.
+// Blank lines and lines starting with # are skipped.
+func LoadBlocklist(paths ...string) ([]string, error) {
+	var terms []string
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open blocklist: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			terms = append(terms, line)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read blocklist %s: %w", path, err)
+		}
+	}
+	return terms, nil
+}
+
+// deniedRunes are characters that have no business in a quiz topic but are
+// how markup, code and the JSON the model is asked to answer with are
+// written.
+const deniedRunes = "{}[]<>`\\|$^~=;@*"
+
+// injectionPatterns match text that addresses the model instead of naming a
+// topic. They run on lower-cased text with whitespace collapsed. Words like
+// "system prompt" or "new instructions" only count in an imperative, since
+// they also name real topics.
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(ignore|disregard|forget|override|bypass|reveal|repeat|leak)\s+(all\s+|any\s+|the\s+|your\s+|these\s+|those\s+|my\s+)?(previous\s+|prior\s+|above\s+|earlier\s+|preceding\s+|system\s+|developer\s+|hidden\s+|original\s+)?(instructions?|prompts?|messages?|rules?|directions?|guidelines?|context|constraints?)\b`),
+	regexp.MustCompile(`\b(ignore|disregard|forget)\s+(everything|anything|all)\s+(above|before|previous|prior|else)\b`),
+	regexp.MustCompile(`\b(follow|obey|apply|use|here\s+are)\s+(the\s+|these\s+|my\s+|your\s+)?new\s+(instructions?|rules?)\b`),
+	regexp.MustCompile(`\byou\s+are\s+(now|no\s+longer)\b`),
+	regexp.MustCompile(`\b(act|behave)\s+as\s+(an?\s+)?(ai|assistant|model|llm|chatbot|jailbroken|dan)\b`),
+	regexp.MustCompile(`\bpretend\s+(to\s+be|you\s+are)\b`),
+	regexp.MustCompile(`\b(respond|reply|output|return|print)\s+(only\s+)?(with|the\s+following|in\s+json|as\s+json)\b`),
+	regexp.MustCompile(`<\|[a-z_]*\|?>|\[/?inst\]|<</?sys>>|###`),
+	// JSON fragments, in particular of the envelope the model answers with
+	regexp.MustCompile(`[{\[]\s*"|"\s*:\s*["\[{\d]|"\s*:\s*(true|false|null)\b|\b(correctanswer|serial_number)\b|"ok"`),
+}
+
+// rolePattern matches a chat role marker at the start of a line. Only text
+// of several lines is checked for it, a transcript for the model to
+// continue; a single line like "User: interface design" is a topic.
+var rolePattern = regexp.MustCompile(`(?m)^\s*(system|assistant|user)\s*:`)
+
+// prepare removes invisible format characters, which could otherwise hide
+// a word from the checks, and applies NFC.
+func prepare(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) && r != '\u200c' && r != '\u200d' {
+			return -1
+		}
+		return r
+	}, norm.NFC.String(text))
+}
+
+// words splits lower-cased text into runs of letters, marks and digits.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsNumber(r)
+	})
+}
+
+func looksLikeInstructions(lower string) bool {
+	if strings.ContainsAny(lower, "\r\n") && rolePattern.MatchString(lower) {
+		return true
+	}
+	flat := strings.Join(strings.Fields(lower), " ")
+	for _, pattern := range injectionPatterns {
+		if pattern.MatchString(flat) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rules Rules) check(text string) *Rejection {
+	clean := prepare(text)
+
+	if n := len([]rune(clean)); rules.MaxLength > 0 && n > rules.MaxLength {
+		return &Rejection{Code: CodeTooLong, Check: "length", Reason: fmt.Sprintf("The topic is %d characters long, the limit is %d", n, rules.MaxLength)}
+	}
+	if n := len(strings.Fields(clean)); rules.MaxWords > 0 && n > rules.MaxWords {
+		return &Rejection{Code: CodeTooLong, Check: "length", Reason: fmt.Sprintf("The topic has %d words, the limit is %d", n, rules.MaxWords)}
+	}
+
+	if looksLikeInstructions(strings.ToLower(clean)) {
+		return &Rejection{Code: CodePromptInjection, Check: "instructions", Reason: "The topic looks like instructions to the quiz generator rather than a subject"}
+	}
+
+	hasWord := false
+	for _, r := range clean {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return &Rejection{Code: CodeInvalidCharacters, Check: "characters", Reason: "The topic must be a single line"}
+		case unicode.IsControl(r) || unicode.Is(unicode.Co, r) || unicode.Is(unicode.Cs, r) || r == unicode.ReplacementChar ||
+			!unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Zs, unicode.Cf):
+			return &Rejection{Code: CodeInvalidCharacters, Check: "characters", Reason: "The topic contains invisible or unassigned characters"}
+		case strings.ContainsRune(deniedRunes, r):
+			return &Rejection{Code: CodeInvalidCharacters, Check: "characters", Reason: fmt.Sprintf("The topic may not contain %q", r)}
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			hasWord = true
+		}
+	}
+	if !hasWord {
+		return &Rejection{Code: CodeInvalidCharacters, Check: "characters", Reason: "The topic must contain letters or digits"}
+	}
+
+	textWords := words(clean)
+	for _, term := range rules.Blocklist {
+		termWords := words(prepare(term))
+		if len(termWords) == 0 {
+			continue
+		}
+		for i := 0; i+len(termWords) <= len(textWords); i++ {
+			if slices.Equal(textWords[i:i+len(termWords)], termWords) {
+				return &Rejection{Code: CodeBlocked, Check: "blocklist", Reason: "The topic is not allowed"}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Package quizbank connects quiz generation to the database: the shared
+// question bank, the user's question history and their uploaded material.
+//
+// Bank questions are deduplicated by normalized text and indexed by topic and
+// difficulty; a question counts as seen by a user once it is part of one of
+// their quizzes.
+package quizbank
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/locale"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Bank implements generateQuiz.QuestionSource on top of the bank_questions
+// table.
+type Bank struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Bank {
+	return &Bank{db: db}
+}
+
+func (b *Bank) Draw(ctx context.Context, quizRequest *types.QuizRequest, n int) ([]types.Question, error) {
+	kinds := quizRequest.QuestionTypes
+	if len(kinds) == 0 {
+		kinds = []string{types.QuestionMCQ}
+	}
+	questions, err := database.FetchUnseenBankQuestions(ctx, b.db, quizRequest.UserID, quizRequest.Topic, quizRequest.Difficulty, language(quizRequest), kinds, n)
+	if err != nil {
+		return nil, err
+	}
+	// Bank IDs aren't question IDs; the link is re-established by text when
+	// the quiz is saved.
+	for i := range questions {
+		questions[i].ID = 0
+	}
+	return questions, nil
+}
+
+func (b *Bank) Store(ctx context.Context, quizRequest *types.QuizRequest, questions []types.Question) error {
+	return database.InsertBankQuestions(ctx, b.db, quizRequest.Topic, quizRequest.Difficulty, language(quizRequest), questions)
+}
+
+// language returns the request's language, which is English for requests
+// queued before languages were supported.
+func language(quizRequest *types.QuizRequest) string {
+	if quizRequest.Language == "" {
+		return locale.Default
+	}
+	return quizRequest.Language
+}
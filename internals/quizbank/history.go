@@ -0,0 +1,26 @@
+package quizbank
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// History implements generateQuiz.QuestionHistory with the user's most
+// recent saved questions.
+type History struct {
+	db    *sql.DB
+	limit int
+}
+
+// NewHistory returns a History that looks at the user's last limit
+// questions.
+func NewHistory(db *sql.DB, limit int) *History {
+	return &History{db: db, limit: limit}
+}
+
+func (h *History) PastQuestions(ctx context.Context, quizRequest *types.QuizRequest) ([]types.PastQuestion, error) {
+	return database.FetchRecentPastQuestions(ctx, h.db, quizRequest.UserID, h.limit)
+}
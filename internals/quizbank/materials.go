@@ -0,0 +1,22 @@
+package quizbank
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+)
+
+// Materials implements generateQuiz.MaterialSource with the chunks stored
+// when a user uploads study material.
+type Materials struct {
+	db *sql.DB
+}
+
+func NewMaterials(db *sql.DB) *Materials {
+	return &Materials{db: db}
+}
+
+func (m *Materials) MaterialChunks(ctx context.Context, userID, materialID int64) ([]string, error) {
+	return database.FetchMaterialChunks(ctx, m.db, materialID, userID)
+}
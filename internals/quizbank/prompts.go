@@ -0,0 +1,54 @@
+package quizbank
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Prompts implements generateQuiz.PromptSource on top of the
+// prompt_templates table.
+type Prompts struct {
+	db *sql.DB
+}
+
+func NewPrompts(db *sql.DB) *Prompts {
+	return &Prompts{db: db}
+}
+
+// AssignPrompt picks one of the active templates for the user, each with a
+// probability proportional to its weight. A user keeps getting the same
+// template as long as the set of active templates and their weights don't
+// change.
+func (p *Prompts) AssignPrompt(ctx context.Context, userID int64) (*types.PromptTemplate, error) {
+	templates, err := database.FetchActivePromptTemplates(ctx, p.db)
+	if err != nil {
+		return nil, err
+	}
+	return assign(templates, userID), nil
+}
+
+func assign(templates []types.PromptTemplate, userID int64) *types.PromptTemplate {
+	total := 0
+	for _, tmpl := range templates {
+		total += tmpl.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatInt(userID, 10)))
+	point := int(h.Sum64() % uint64(total))
+	for i := range templates {
+		point -= templates[i].Weight
+		if point < 0 {
+			return &templates[i]
+		}
+	}
+	return nil
+}
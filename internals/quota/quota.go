@@ -0,0 +1,150 @@
+// Package quota enforces per-user limits on quiz generation, by number of
+// generations and by model tokens, per UTC day and calendar month.
+//
+// Generations are counted from the usage ledger; jobs that are still queued
+// or running and generations under way count as well, so a user can't get
+// around the limit by starting many at once. Reserve checks the quota and
+// adds the row that counts the new generation under a per-user lock, so
+// concurrent requests can't all pass the check first. Tokens are only known
+// once a generation has finished, so the token limit stops the next
+// generation rather than the one that crosses it.
+package quota
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Limits are the quotas of every user; 0 means unlimited.
+type Limits struct {
+	DailyGenerations   int
+	MonthlyGenerations int
+	DailyTokens        int
+	MonthlyTokens      int
+}
+
+// ExceededError is returned by Check when a quota is used up. It is also the
+// body of the 429 response.
+type ExceededError struct {
+	Period   string    `json:"period"` // "day" or "month"
+	Kind     string    `json:"kind"`   // "generations" or "tokens"
+	Limit    int       `json:"limit"`
+	Used     int       `json:"used"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s quota of %d %s used up, resets at %s", e.Period, e.Limit, e.Kind, e.ResetsAt.Format(time.RFC3339))
+}
+
+type Limiter struct {
+	db     *sql.DB
+	limits Limits
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+func New(db *sql.DB, limits Limits) *Limiter {
+	return &Limiter{db: db, limits: limits, now: time.Now}
+}
+
+// Summary returns the user's usage in the current day and month.
+func (l *Limiter) Summary(userID int64) (*types.UsageSummary, error) {
+	now := l.now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	pending, err := database.CountPendingGenerations(l.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &types.UsageSummary{}
+	summary.Day, err = l.period(userID, dayStart, dayStart.AddDate(0, 0, 1), l.limits.DailyGenerations, l.limits.DailyTokens)
+	if err != nil {
+		return nil, err
+	}
+	summary.Month, err = l.period(userID, monthStart, monthStart.AddDate(0, 1, 0), l.limits.MonthlyGenerations, l.limits.MonthlyTokens)
+	if err != nil {
+		return nil, err
+	}
+	summary.Day.Pending = pending
+	summary.Month.Pending = pending
+	return summary, nil
+}
+
+func (l *Limiter) period(userID int64, start, end time.Time, generationLimit, tokenLimit int) (types.UsagePeriod, error) {
+	generations, tokens, err := database.FetchUsageSince(l.db, userID, start)
+	if err != nil {
+		return types.UsagePeriod{}, err
+	}
+	return types.UsagePeriod{
+		Generations:     generations,
+		GenerationLimit: generationLimit,
+		Tokens:          tokens,
+		TokenLimit:      tokenLimit,
+		ResetsAt:        end,
+	}, nil
+}
+
+// Check returns an *ExceededError if the user may not start another
+// generation now. The monthly quota is reported before the daily one, since
+// waiting for the day to end wouldn't help.
+func (l *Limiter) Check(userID int64) error {
+	if l.limits == (Limits{}) {
+		return nil
+	}
+	summary, err := l.Summary(userID)
+	if err != nil {
+		return err
+	}
+	if err := exceeded("month", summary.Month); err != nil {
+		return err
+	}
+	return exceeded("day", summary.Day)
+}
+
+// Reserve runs insert in a transaction if the user may start another
+// generation, and returns Check's error otherwise. insert must add the row
+// the generation is counted by, a queued job or a pending usage record; the
+// check and insert hold a per-user lock, so a concurrent Reserve sees that
+// row. Errors from insert are returned as is.
+func (l *Limiter) Reserve(userID int64, insert func(tx *sql.Tx) error) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if l.limits != (Limits{}) {
+		if err := database.LockUsage(tx, userID); err != nil {
+			return err
+		}
+		// Earlier reservations are committed before the lock is released, so
+		// the check sees them.
+		if err := l.Check(userID); err != nil {
+			return err
+		}
+	}
+	if err := insert(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	return nil
+}
+
+func exceeded(name string, p types.UsagePeriod) error {
+	if used := p.Generations + p.Pending; p.GenerationLimit > 0 && used >= p.GenerationLimit {
+		return &ExceededError{Period: name, Kind: "generations", Limit: p.GenerationLimit, Used: used, ResetsAt: p.ResetsAt}
+	}
+	if p.TokenLimit > 0 && p.Tokens >= p.TokenLimit {
+		return &ExceededError{Period: name, Kind: "tokens", Limit: p.TokenLimit, Used: p.Tokens, ResetsAt: p.ResetsAt}
+	}
+	return nil
+}
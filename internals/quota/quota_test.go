@@ -0,0 +1,122 @@
+package quota
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database/dbtest"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+func TestExceeded(t *testing.T) {
+	resets := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		period   types.UsagePeriod
+		wantKind string
+	}{
+		{types.UsagePeriod{Generations: 2, GenerationLimit: 3}, ""},
+		{types.UsagePeriod{Generations: 2, Pending: 1, GenerationLimit: 3}, "generations"},
+		{types.UsagePeriod{Generations: 100}, ""},
+		{types.UsagePeriod{Tokens: 999, TokenLimit: 1000}, ""},
+		{types.UsagePeriod{Tokens: 1000, TokenLimit: 1000}, "tokens"},
+	} {
+		tc.period.ResetsAt = resets
+		err := exceeded("month", tc.period)
+		var e *ExceededError
+		switch {
+		case tc.wantKind == "" && err != nil:
+			t.Errorf("exceeded(%+v) = %v, want nil", tc.period, err)
+		case tc.wantKind != "" && (!errors.As(err, &e) || e.Kind != tc.wantKind || !e.ResetsAt.Equal(resets)):
+			t.Errorf("exceeded(%+v) = %v, want %s used up", tc.period, err, tc.wantKind)
+		}
+	}
+}
+
+func reservePending(l *Limiter, userID int64) error {
+	return l.Reserve(userID, func(tx *sql.Tx) error {
+		_, err := database.InsertPendingUsage(tx, userID)
+		return err
+	})
+}
+
+func TestReserveIsAtomic(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	limiter := New(db, Limits{DailyGenerations: 3})
+
+	const requests = 10
+	errs := make(chan error, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- reservePending(limiter, userID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	reserved, refused := 0, 0
+	for err := range errs {
+		var e *ExceededError
+		switch {
+		case err == nil:
+			reserved++
+		case errors.As(err, &e):
+			refused++
+		default:
+			t.Fatal(err)
+		}
+	}
+	if reserved != 3 || refused != requests-3 {
+		t.Errorf("%d reserved and %d refused, want 3 and %d", reserved, refused, requests-3)
+	}
+}
+
+func TestSummaryCountsFinishedAndPending(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.NewUser(t, db)
+	limiter := New(db, Limits{DailyGenerations: 5, MonthlyTokens: 1000})
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := database.InsertPendingUsage(tx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reservePending(limiter, userID); err != nil {
+		t.Fatal(err)
+	}
+	record := &types.UsageRecord{UserID: userID, Provider: "fake", PromptTokens: 300, ResponseTokens: 200, Outcome: types.UsageSucceeded}
+	if err := database.FinishUsageRecord(db, id, record); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := limiter.Summary(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if day := summary.Day; day.Generations != 1 || day.Pending != 1 || day.Tokens != 500 || day.GenerationLimit != 5 {
+		t.Errorf("day = %+v, want 1 generation, 1 pending, 500 tokens", day)
+	}
+
+	// Next month the finished generation no longer counts.
+	limiter.now = func() time.Time { return time.Now().AddDate(0, 1, 0) }
+	summary, err = limiter.Summary(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if month := summary.Month; month.Generations != 0 || month.Tokens != 0 || month.TokenLimit != 1000 {
+		t.Errorf("next month = %+v, want nothing used", month)
+	}
+}
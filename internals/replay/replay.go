@@ -0,0 +1,296 @@
+// Package replay records the HTTP exchanges of the generation client to
+// fixture files and serves them back, so quiz generation can be exercised
+// offline and deterministically, e.g. with httptest against the real
+// handlers.
+//
+// A fixture is keyed by the request's method, URL path and query, and body,
+// so replays don't depend on the host a stub server happens to listen on.
+// Credentials (the key query parameter and all request headers) are left
+// out of both the key and the file, so fixtures recorded with a real API key
+// can be committed and replayed without one.
+//
+// Clients that take an http.Client use Transport directly; others, like the
+// Python quiz workers, are pointed at the local proxy started by Serve.
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Modes of a Transport.
+const (
+	// Off passes requests through untouched.
+	Off = ""
+	// Record passes requests through and saves every exchange.
+	Record = "record"
+	// Replay serves saved exchanges and never touches the network.
+	Replay = "replay"
+)
+
+// ErrNoFixture is returned in replay mode for a request that was never
+// recorded.
+var ErrNoFixture = errors.New("no recorded response for request")
+
+// secretParams are query parameters that carry credentials.
+var secretParams = []string{"key", "access_token"}
+
+// Fixture is one recorded exchange, as stored on disk.
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+type FixtureRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type FixtureResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	// Body is kept as text; streamed responses are server-sent events, not
+	// JSON.
+	Body string `json:"body"`
+}
+
+// Transport is an http.RoundTripper that records to or replays from Dir.
+// Recording reads each response in full before returning it, so streamed
+// answers arrive at once.
+type Transport struct {
+	Mode string
+	Dir  string
+	// Base makes the real requests in Off and Record mode;
+	// http.DefaultTransport if nil.
+	Base http.RoundTripper
+}
+
+// New returns a Transport, checking the mode and, for replay, that dir
+// exists. In record mode dir is created.
+func New(mode, dir string, base http.RoundTripper) (*Transport, error) {
+	switch mode {
+	case Off:
+	case Record:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+		}
+	case Replay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("fixture directory: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown record mode %q, want %q or %q", mode, Record, Replay)
+	}
+	return &Transport{Mode: mode, Dir: dir, Base: base}, nil
+}
+
+// Client returns an http.Client that uses t.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Proxy returns a handler that forwards every request to target through t.
+func (t *Transport) Proxy(target *url.URL) http.Handler {
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			// Recorded bodies are stored as text, so ask for them
+			// uncompressed.
+			r.Out.Header.Del("Accept-Encoding")
+		},
+		Transport: t,
+	}
+}
+
+// Serve starts Proxy(target) on a loopback port for the life of the process
+// and returns its base URL.
+func (t *Transport) Serve(target string) (string, error) {
+	upstream, err := url.Parse(target)
+	if err != nil || upstream.Host == "" {
+		return "", fmt.Errorf("invalid upstream URL %q", target)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to start replay proxy: %w", err)
+	}
+	go http.Serve(listener, t.Proxy(upstream))
+	return "http://" + listener.Addr().String(), nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Mode == Off {
+		return t.base().RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	fixtureReq := FixtureRequest{Method: req.Method, URL: redactURL(req.URL), Body: canonicalJSON(body)}
+	path := filepath.Join(t.Dir, key(fixtureReq)+".json")
+
+	if t.Mode == Replay {
+		fixture, err := load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s %s (%s)", ErrNoFixture, req.Method, fixtureReq.URL, filepath.Base(path))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return fixture.Response.toHTTP(req), nil
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fixture := Fixture{
+		Request:  fixtureReq,
+		Response: FixtureResponse{Status: resp.StatusCode, Header: keepHeaders(resp.Header), Body: string(respBody)},
+	}
+	if err := save(path, &fixture); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r FixtureResponse) toHTTP(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// redactURL returns u without credential parameters, with the remaining
+// ones in a stable order.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, name := range secretParams {
+		query.Del(name)
+	}
+	redacted.RawQuery = query.Encode()
+	redacted.User = nil
+	return redacted.String()
+}
+
+// canonicalJSON re-encodes a JSON body with sorted keys so that field order
+// doesn't change the fixture key. Non-JSON bodies are stored as a JSON
+// string.
+func canonicalJSON(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		if out, err := json.Marshal(v); err == nil {
+			return out
+		}
+	}
+	out, _ := json.Marshal(string(body))
+	return out
+}
+
+// keepHeaders drops response headers that vary between calls or identify
+// the session.
+func keepHeaders(header http.Header) http.Header {
+	kept := http.Header{}
+	for _, name := range []string{"Content-Type", "Content-Encoding"} {
+		if v := header.Values(name); len(v) > 0 {
+			kept[name] = v
+		}
+	}
+	return kept
+}
+
+func key(req FixtureRequest) string {
+	target := req.URL
+	if u, err := url.Parse(req.URL); err == nil {
+		target = u.RequestURI()
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, target)
+	h.Write(req.Body)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// save writes the fixture atomically, so a replay never reads a partial
+// file.
+func save(path string, fixture *Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return fmt.Errorf("failed to save fixture: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save fixture: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save fixture: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save fixture: %w", err)
+	}
+	return nil
+}
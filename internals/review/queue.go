@@ -0,0 +1,31 @@
+package review
+
+import (
+	"database/sql"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Queue keeps users' review cards in the review_cards table. Cards are added
+// by the database when an attempt is finished.
+type Queue struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Queue {
+	return &Queue{db: db}
+}
+
+// Due returns up to limit of the user's cards that are due for review.
+func (q *Queue) Due(userID int64, limit int) ([]types.ReviewCard, error) {
+	return database.FetchDueReviewCards(q.db, userID, limit)
+}
+
+// Grade records a recall of one of the user's cards and schedules its next
+// review.
+func (q *Queue) Grade(userID, cardID int64, grade int) (*types.ReviewCard, error) {
+	return database.RecordReview(q.db, userID, cardID, func(card *types.ReviewCard) {
+		Schedule(card, grade)
+	})
+}
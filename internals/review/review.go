@@ -0,0 +1,48 @@
+// Package review schedules the questions users missed for spaced-repetition
+// review with the SM-2 algorithm.
+//
+// Every recall is graded from 0 to 5. A grade of at least 3 is a success:
+// the card comes back after 1 day, then 6, then each interval times the
+// card's ease factor. A failure starts the card over at 1 day. Either way
+// the ease factor moves with the grade, and never drops below 1.3, so cards
+// that are hard to recall come back more often.
+package review
+
+import (
+	"math"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+const (
+	// MinGrade and MaxGrade bound recall grades.
+	MinGrade = 0
+	MaxGrade = 5
+	// passGrade is the lowest grade that counts as recalled.
+	passGrade = 3
+	// minEase keeps hard cards from coming back every day forever.
+	minEase = 1.3
+)
+
+// Schedule updates the card's ease factor, interval and repetitions after a
+// recall with the given grade.
+func Schedule(card *types.ReviewCard, grade int) {
+	grade = max(MinGrade, min(MaxGrade, grade))
+
+	if grade < passGrade {
+		card.Repetitions, card.IntervalDays = 0, 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.IntervalDays = 1
+		case 1:
+			card.IntervalDays = 6
+		default:
+			card.IntervalDays = int(math.Round(float64(card.IntervalDays) * card.EaseFactor))
+		}
+		card.Repetitions++
+	}
+
+	miss := float64(MaxGrade - grade)
+	card.EaseFactor = math.Max(minEase, card.EaseFactor+0.1-miss*(0.08+miss*0.02))
+}
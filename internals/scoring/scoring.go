@@ -0,0 +1,100 @@
+// Package scoring decides whether a user's answer to a question is correct,
+// according to the question's type.
+package scoring
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// IsCorrect reports whether the user's answer on q is correct. Single-answer
+// types read UserAnswer, multi_select and ordering read UserAnswers. Every
+// type is all-or-nothing: a multi_select answer must pick exactly the correct
+// options, and an ordering answer must list every item in place.
+func IsCorrect(q types.Question) bool {
+	switch q.Type {
+	case types.QuestionMultiSelect:
+		return sameSet(q.UserAnswers, q.CorrectAnswers)
+	case types.QuestionOrdering:
+		return sameSequence(q.UserAnswers, q.CorrectAnswers)
+	case types.QuestionFillIn:
+		variants := q.CorrectAnswers
+		if len(variants) == 0 {
+			variants = []string{q.CorrectAnswer}
+		}
+		given := FillInKey(q.UserAnswer, q.CaseSensitive, q.IgnoreSpaces)
+		if given == "" {
+			return false
+		}
+		for _, variant := range variants {
+			if FillInKey(variant, q.CaseSensitive, q.IgnoreSpaces) == given {
+				return true
+			}
+		}
+		return false
+	default: // mcq, true_false
+		return q.UserAnswer != "" && optionKey(q.UserAnswer) == optionKey(q.CorrectAnswer)
+	}
+}
+
+// Score counts the correctly answered questions.
+func Score(questions []types.Question) int {
+	score := 0
+	for _, q := range questions {
+		if IsCorrect(q) {
+			score++
+		}
+	}
+	return score
+}
+
+// FillInKey is the form in which fill-in answers are compared: trimmed,
+// inner whitespace collapsed (or removed with ignoreSpaces), and lower-cased
+// unless caseSensitive.
+func FillInKey(answer string, caseSensitive, ignoreSpaces bool) string {
+	sep := " "
+	if ignoreSpaces {
+		sep = ""
+	}
+	answer = strings.Join(strings.Fields(norm.NFC.String(answer)), sep)
+	if !caseSensitive {
+		answer = strings.ToLower(answer)
+	}
+	return answer
+}
+
+// optionKey compares options the way the validation stage deduplicates them.
+func optionKey(option string) string {
+	return strings.ToLower(strings.Join(strings.Fields(norm.NFC.String(option)), " "))
+}
+
+func sameSet(given, correct []string) bool {
+	want := make(map[string]bool, len(correct))
+	for _, c := range correct {
+		want[optionKey(c)] = true
+	}
+	got := make(map[string]bool, len(given))
+	for _, g := range given {
+		key := optionKey(g)
+		if !want[key] {
+			return false
+		}
+		got[key] = true
+	}
+	return len(got) == len(want) && len(want) > 0
+}
+
+func sameSequence(given, correct []string) bool {
+	if len(given) != len(correct) || len(correct) == 0 {
+		return false
+	}
+	for i := range correct {
+		if optionKey(given[i]) != optionKey(correct[i]) {
+			return false
+		}
+	}
+	return true
+}
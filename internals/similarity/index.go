@@ -0,0 +1,43 @@
+package similarity
+
+// Index holds fingerprints of known texts and reports whether a new text is
+// a near-duplicate of any of them. Lookups are linear, which is fine for the
+// few hundred past questions a user has.
+type Index struct {
+	threshold float64
+	entries   []entry
+}
+
+type entry struct {
+	text string
+	fp   Fingerprint
+}
+
+// NewIndex returns an index that treats texts with an estimated similarity
+// of at least threshold as near-duplicates.
+func NewIndex(threshold float64) *Index {
+	return &Index{threshold: threshold}
+}
+
+func (idx *Index) Add(text string) {
+	idx.entries = append(idx.entries, entry{text: text, fp: NewFingerprint(text)})
+}
+
+// Match returns the most similar known text if it reaches the threshold.
+func (idx *Index) Match(text string) (string, float64, bool) {
+	fp := NewFingerprint(text)
+	best, bestScore := "", 0.0
+	for _, e := range idx.entries {
+		if score := fp.Similarity(e.fp); score > bestScore {
+			best, bestScore = e.text, score
+		}
+	}
+	if bestScore == 0 || bestScore < idx.threshold {
+		return "", bestScore, false
+	}
+	return best, bestScore, true
+}
+
+func (idx *Index) Len() int {
+	return len(idx.entries)
+}
@@ -0,0 +1,148 @@
+// Package similarity detects near-duplicate questions. Texts are reduced to
+// word shingles and summarized by a MinHash signature, whose agreement rate
+// estimates the Jaccard similarity of the shingle sets.
+package similarity
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// signatureSize is the number of MinHash functions. 64 keeps the standard
+// error of the Jaccard estimate around 0.06.
+const signatureSize = 64
+
+// stopWords are dropped before shingling so that rephrasings like "What is"
+// and "Which of these is" don't hide or fake a match.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "in": true, "on": true,
+	"at": true, "to": true, "for": true, "by": true, "with": true, "from": true,
+	"and": true, "or": true, "is": true, "are": true, "was": true, "were": true,
+	"be": true, "been": true, "which": true, "what": true, "who": true,
+	"whom": true, "when": true, "where": true, "why": true, "how": true,
+	"does": true, "do": true, "did": true, "this": true, "that": true,
+	"these": true, "those": true, "following": true, "it": true, "its": true,
+	"as": true,
+}
+
+// Fingerprint is the MinHash signature of a text.
+type Fingerprint struct {
+	sig   [signatureSize]uint64
+	empty bool
+}
+
+// NewFingerprint fingerprints text from its word unigrams and bigrams.
+func NewFingerprint(text string) Fingerprint {
+	shingles := Shingles(text)
+	fp := Fingerprint{empty: len(shingles) == 0}
+	for i := range fp.sig {
+		fp.sig[i] = ^uint64(0)
+	}
+	for _, shingle := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		x := h.Sum64()
+		for i := range fp.sig {
+			if v := mix(x ^ seeds[i]); v < fp.sig[i] {
+				fp.sig[i] = v
+			}
+		}
+	}
+	return fp
+}
+
+// Similarity estimates the Jaccard similarity (0-1) of the two texts'
+// shingle sets.
+func (f Fingerprint) Similarity(other Fingerprint) float64 {
+	if f.empty || other.empty {
+		return 0
+	}
+	same := 0
+	for i := range f.sig {
+		if f.sig[i] == other.sig[i] {
+			same++
+		}
+	}
+	return float64(same) / signatureSize
+}
+
+// Tokens lower-cases text, splits it on anything that isn't a letter or
+// digit and drops stop words.
+func Tokens(text string) []string {
+	// Combining marks (e.g. Indic vowel signs) are part of the word they
+	// follow, and NFC makes composed and decomposed spellings compare equal.
+	words := strings.FieldsFunc(strings.ToLower(norm.NFC.String(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+	tokens := words[:0]
+	for _, word := range words {
+		if !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// Shingles returns the distinct unigrams and bigrams of text's tokens.
+func Shingles(text string) []string {
+	tokens := Tokens(text)
+	seen := make(map[string]bool, 2*len(tokens))
+	var shingles []string
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			shingles = append(shingles, s)
+		}
+	}
+	for i, token := range tokens {
+		add(token)
+		if i > 0 {
+			add(tokens[i-1] + " " + token)
+		}
+	}
+	return shingles
+}
+
+// Relevance is the share of query's tokens that occur in text, 0 if query
+// has none.
+func Relevance(query, text string) float64 {
+	want := Tokens(query)
+	if len(want) == 0 {
+		return 0
+	}
+	have := make(map[string]bool)
+	for _, token := range Tokens(text) {
+		have[token] = true
+	}
+	found := 0
+	for _, token := range want {
+		if have[token] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(want))
+}
+
+// seeds derives one independent hash function per signature slot.
+var seeds = func() [signatureSize]uint64 {
+	var s [signatureSize]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range s {
+		x = mix(x + uint64(i))
+		s[i] = x
+	}
+	return s
+}()
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
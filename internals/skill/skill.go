@@ -0,0 +1,116 @@
+// Package skill estimates how well a user knows a topic and recommends the
+// difficulty of their next quiz on it.
+//
+// Every (user, topic) pair has an Elo rating. A quiz is treated as a match
+// between the user and the quiz's difficulty: difficulty levels 1-10 have
+// fixed ratings, the expected score follows from the rating difference, and
+// the user's rating moves by how much better or worse they did. Adaptive
+// quizzes are pitched so that the user is expected to answer about 70% of
+// the questions correctly.
+package skill
+
+import (
+	"math"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Adaptive is the QuizRequest difficulty that asks the server to choose.
+const Adaptive = "adaptive"
+
+// InitialRating is the rating of a user without any scored quizzes. It is the
+// rating of a medium quiz.
+const InitialRating = 1000
+
+// targetScore is the share of correct answers adaptive quizzes aim for: hard
+// enough to learn from, easy enough not to discourage.
+const targetScore = 0.7
+
+const (
+	minLevel = 1
+	maxLevel = 10
+	// levelStep is the rating difference between neighbouring levels;
+	// level 5 is InitialRating.
+	levelStep = 80
+)
+
+// levels maps the named difficulties to the level they stand for.
+var levels = map[string]int{
+	"easy":   3,
+	"medium": 5,
+	"hard":   8,
+}
+
+// LevelRating returns the Elo rating of a quiz of the given level.
+func LevelRating(level int) float64 {
+	return InitialRating + float64(level-levels["medium"])*levelStep
+}
+
+// DifficultyLevel returns the level a named difficulty stands for.
+func DifficultyLevel(difficulty string) (int, bool) {
+	level, ok := levels[strings.ToLower(strings.TrimSpace(difficulty))]
+	return level, ok
+}
+
+// LevelDifficulty returns the named difficulty a level falls under.
+func LevelDifficulty(level int) string {
+	switch {
+	case level <= 3:
+		return "easy"
+	case level <= 6:
+		return "medium"
+	default:
+		return "hard"
+	}
+}
+
+// Expected returns the share of questions a user rated rating is expected
+// to answer correctly on a quiz rated against.
+func Expected(rating, against float64) float64 {
+	return 1 / (1 + math.Pow(10, (against-rating)/400))
+}
+
+// Update returns the rating after a quiz of the given level on which score of
+// total questions were answered correctly. quizzes is the number of quizzes
+// the current rating is based on: early ratings move faster. Short quizzes
+// say less about the user and move the rating less.
+func Update(rating float64, quizzes, level, score, total int) float64 {
+	if total <= 0 {
+		return rating
+	}
+	actual := math.Max(0, math.Min(1, float64(score)/float64(total)))
+	k := 32 + 48/float64(1+quizzes)
+	k *= math.Min(float64(total), 10) / 10
+	return rating + k*(actual-Expected(rating, LevelRating(level)))
+}
+
+// Recommend returns the level, and the named difficulty it falls under, at
+// which a user rated rating is expected to score targetScore.
+func Recommend(rating float64) (string, int) {
+	// Solve Expected(rating, against) = targetScore for against.
+	against := rating + 400*math.Log10(1/targetScore-1)
+	level := int(math.Round((against-InitialRating)/levelStep)) + levels["medium"]
+	level = max(minLevel, min(maxLevel, level))
+	return LevelDifficulty(level), level
+}
+
+// TopicKey is the form topics are rated under, so that "Solar System" and
+// "solar  system" share a rating.
+func TopicKey(topic string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFC.String(topic))), " ")
+}
+
+// Estimate returns the estimate for a topic the user has a rating on.
+func Estimate(topic string, rating float64, quizzes int) types.SkillEstimate {
+	difficulty, level := Recommend(rating)
+	return types.SkillEstimate{
+		Topic:                 topic,
+		Rating:                math.Round(rating*10) / 10,
+		Quizzes:               quizzes,
+		RecommendedDifficulty: difficulty,
+		RecommendedLevel:      level,
+	}
+}
@@ -0,0 +1,205 @@
+package skill
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+const (
+	// minTopicQuestions is how many questions on a topic it takes to count
+	// as one of the user's strongest or weakest.
+	minTopicQuestions = 10
+	// extremeTopics is how many strongest and weakest topics are listed.
+	extremeTopics = 3
+)
+
+type cachedStats struct {
+	stats    *types.UserStats
+	computed time.Time
+}
+
+// Stats returns the user's quiz totals, skill estimates, accuracy by topic
+// and level, and score trend by bucket, from the cache if fresh.
+func (t *Tracker) Stats(userID int64, bucket string) (*types.UserStats, error) {
+	t.mu.Lock()
+	cached, ok := t.stats[userID][bucket]
+	generation := t.generation
+	t.mu.Unlock()
+	if ok && time.Since(cached.computed) < t.statsTTL {
+		return cached.stats, nil
+	}
+
+	stats, err := t.computeStats(userID, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.generation == generation && t.statsTTL > 0 {
+		t.pruneStats(time.Now())
+		if t.stats[userID] == nil {
+			t.stats[userID] = make(map[string]cachedStats)
+		}
+		t.stats[userID][bucket] = cachedStats{stats: stats, computed: time.Now()}
+	}
+	return stats, nil
+}
+
+// Forget drops the user's cached stats, after they saved or took a quiz.
+func (t *Tracker) Forget(userID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.stats, userID)
+	t.generation++
+}
+
+// pruneStats drops the cached stats older than statsTTL, at most once per
+// statsTTL so that caching stays cheap. The caller holds t.mu.
+func (t *Tracker) pruneStats(now time.Time) {
+	if now.Sub(t.pruned) < t.statsTTL {
+		return
+	}
+	t.pruned = now
+	for userID, buckets := range t.stats {
+		for bucket, cached := range buckets {
+			if now.Sub(cached.computed) >= t.statsTTL {
+				delete(buckets, bucket)
+			}
+		}
+		if len(buckets) == 0 {
+			delete(t.stats, userID)
+		}
+	}
+}
+
+func (t *Tracker) forgetAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.stats)
+	t.generation++
+}
+
+func (t *Tracker) computeStats(userID int64, bucket string) (*types.UserStats, error) {
+	quizzes, questions, avgScoreRate, err := database.FetchQuizTotals(t.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := database.FetchSkillRatings(t.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	byTopic, err := database.FetchAccuracyByTopic(t.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	byLevel, err := database.FetchAccuracyByLevel(t.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	trend, err := database.FetchScoreTrend(t.db, userID, bucket)
+	if err != nil {
+		return nil, err
+	}
+	answered, avgSeconds, err := database.FetchAnswerTotals(t.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &types.UserStats{
+		Quizzes:           quizzes,
+		Questions:         questions,
+		AvgScoreRate:      avgScoreRate,
+		Rating:            InitialRating,
+		Skills:            make([]types.SkillEstimate, len(ratings)),
+		AnsweredQuestions: answered,
+		ByTopic:           mergeTopics(byTopic),
+		ByLevel:           byLevel,
+		TrendBucket:       bucket,
+		Trend:             trend,
+	}
+	var weighted float64
+	var rated int
+	for i, r := range ratings {
+		stats.Skills[i] = Estimate(r.Topic, r.Rating, r.Quizzes)
+		stats.Skills[i].UpdatedAt = r.UpdatedAt
+		weighted += r.Rating * float64(r.Quizzes)
+		rated += r.Quizzes
+	}
+	if rated > 0 {
+		stats.Rating = math.Round(weighted/float64(rated)*10) / 10
+	}
+
+	for i := range stats.ByLevel {
+		stats.ByLevel[i].Accuracy = accuracy(stats.ByLevel[i].Correct, stats.ByLevel[i].Questions)
+	}
+	for i := range stats.Trend {
+		stats.Trend[i].Accuracy = accuracy(stats.Trend[i].Correct, stats.Trend[i].Questions)
+	}
+	if avgSeconds != nil {
+		rounded := math.Round(*avgSeconds*10) / 10
+		stats.AvgSecondsPerQuestion = &rounded
+	}
+	stats.Strongest, stats.Weakest = extremes(stats.ByTopic)
+	return stats, nil
+}
+
+// mergeTopics merges the stats of topics with the same TopicKey, as skill
+// ratings are, under the first name seen, and sets their accuracy.
+func mergeTopics(byName []types.AccuracyStat) []types.AccuracyStat {
+	merged := []types.AccuracyStat{}
+	index := make(map[string]int)
+	for _, s := range byName {
+		key := TopicKey(s.Name)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, s)
+			continue
+		}
+		merged[i].Attempts += s.Attempts
+		merged[i].Questions += s.Questions
+		merged[i].Correct += s.Correct
+	}
+	for i := range merged {
+		merged[i].Accuracy = accuracy(merged[i].Correct, merged[i].Questions)
+	}
+	return merged
+}
+
+// extremes returns up to extremeTopics of the topics with at least
+// minTopicQuestions questions with the highest and the lowest accuracy. With
+// few such topics they are split between the two, strongest first.
+func extremes(topics []types.AccuracyStat) (strongest, weakest []types.AccuracyStat) {
+	var ranked []types.AccuracyStat
+	for _, s := range topics {
+		if s.Questions >= minTopicQuestions {
+			ranked = append(ranked, s)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Accuracy != ranked[j].Accuracy {
+			return ranked[i].Accuracy > ranked[j].Accuracy
+		}
+		return ranked[i].Questions > ranked[j].Questions
+	})
+
+	n := min(extremeTopics, (len(ranked)+1)/2)
+	strongest = append([]types.AccuracyStat{}, ranked[:n]...)
+	weakest = []types.AccuracyStat{}
+	for i := len(ranked) - 1; i >= n && len(weakest) < extremeTopics; i-- {
+		weakest = append(weakest, ranked[i])
+	}
+	return strongest, weakest
+}
+
+func accuracy(correct, questions int) float64 {
+	if questions == 0 {
+		return 0
+	}
+	return math.Round(float64(correct)/float64(questions)*1000) / 1000
+}
@@ -0,0 +1,42 @@
+package skill
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+func TestPruneStatsDropsExpiredEntries(t *testing.T) {
+	tracker := New(nil, time.Minute)
+	now := time.Now()
+	tracker.stats[1] = map[string]cachedStats{
+		"week":  {stats: &types.UserStats{}, computed: now.Add(-2 * time.Minute)},
+		"month": {stats: &types.UserStats{}, computed: now.Add(-10 * time.Second)},
+	}
+	tracker.stats[2] = map[string]cachedStats{
+		"week": {stats: &types.UserStats{}, computed: now.Add(-time.Hour)},
+	}
+
+	tracker.pruneStats(now)
+	if _, ok := tracker.stats[2]; ok {
+		t.Error("user with only expired stats is still cached")
+	}
+	if _, ok := tracker.stats[1]["week"]; ok {
+		t.Error("expired bucket is still cached")
+	}
+	if _, ok := tracker.stats[1]["month"]; !ok {
+		t.Error("fresh bucket was dropped")
+	}
+
+	// Pruning again within the TTL is skipped.
+	tracker.stats[3] = map[string]cachedStats{"week": {stats: &types.UserStats{}, computed: now.Add(-time.Hour)}}
+	tracker.pruneStats(now.Add(30 * time.Second))
+	if _, ok := tracker.stats[3]; !ok {
+		t.Error("pruned twice within the TTL")
+	}
+	tracker.pruneStats(now.Add(time.Minute))
+	if _, ok := tracker.stats[3]; ok {
+		t.Error("expired stats weren't pruned after the TTL")
+	}
+}
@@ -0,0 +1,97 @@
+package skill
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// Tracker keeps users' ratings in the skill_ratings table, and caches their
+// stats for statsTTL or until they take or save a quiz. Expired stats are
+// dropped as new ones are cached.
+type Tracker struct {
+	db       *sql.DB
+	statsTTL time.Duration
+
+	mu    sync.Mutex
+	stats map[int64]map[string]cachedStats // by user and trend bucket
+	// generation counts invalidations, so that stats computed across one
+	// are not cached.
+	generation uint64
+	// pruned is when expired stats were last dropped.
+	pruned time.Time
+}
+
+func New(db *sql.DB, statsTTL time.Duration) *Tracker {
+	return &Tracker{db: db, statsTTL: statsTTL, stats: make(map[int64]map[string]cachedStats)}
+}
+
+// Record updates the user's rating on the quiz's topic with its score: its
+// canonical topic if it has one, otherwise its name. Quizzes without
+// questions or with an unknown difficulty are ignored.
+func (t *Tracker) Record(quiz *types.Quiz) error {
+	t.Forget(int64(quiz.UserID))
+	if quiz.TotalQuestions <= 0 {
+		return nil
+	}
+	level, ok := DifficultyLevel(quiz.Level)
+	if quiz.DifficultyLevel != nil {
+		level, ok = *quiz.DifficultyLevel, true
+	}
+	if !ok || level < minLevel || level > maxLevel {
+		return nil
+	}
+
+	topic := quiz.QuizName
+	if quiz.Topic != "" {
+		topic = quiz.Topic
+	}
+	return database.UpdateSkillRating(t.db, int64(quiz.UserID), TopicKey(topic), InitialRating, func(rating float64, quizzes int) float64 {
+		return Update(rating, quizzes, level, quiz.Score, quiz.TotalQuestions)
+	})
+}
+
+// Recommend returns the difficulty and level for the user's next quiz on
+// topic.
+func (t *Tracker) Recommend(userID int64, topic string) (string, int, error) {
+	rating, _, err := database.FetchSkillRating(t.db, userID, TopicKey(topic), InitialRating)
+	if err != nil {
+		return "", 0, err
+	}
+	difficulty, level := Recommend(rating)
+	return difficulty, level, nil
+}
+
+// ApplyAdaptive replaces an "adaptive" difficulty in the request with the one
+// recommended for the user on its canonical topic, or its topic if it has
+// none.
+func (t *Tracker) ApplyAdaptive(quizRequest *types.QuizRequest) error {
+	quizRequest.Level = 0
+	if quizRequest.Difficulty != Adaptive {
+		return nil
+	}
+	topic := quizRequest.Topic
+	if quizRequest.CanonicalTopic != "" {
+		topic = quizRequest.CanonicalTopic
+	}
+	difficulty, level, err := t.Recommend(quizRequest.UserID, topic)
+	if err != nil {
+		return fmt.Errorf("failed to recommend a difficulty: %w", err)
+	}
+	quizRequest.Difficulty, quizRequest.Level = difficulty, level
+	return nil
+}
+
+// RenameTopic moves the ratings on a renamed canonical topic to its new
+// name. Users who already have a rating under the new name keep both.
+func (t *Tracker) RenameTopic(oldName, newName string) error {
+	if TopicKey(oldName) == TopicKey(newName) {
+		return nil
+	}
+	defer t.forgetAll()
+	return database.RenameSkillTopic(t.db, TopicKey(oldName), TopicKey(newName))
+}
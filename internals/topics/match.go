@@ -0,0 +1,138 @@
+// Package topics resolves the free-text topics users type to canonical
+// topics, so that "DBMS", "Database Management Systems" and "databases" can
+// share history and stats.
+//
+// A topic resolves to the canonical topic whose name or admin-curated alias
+// has the same key (see Key). Failing that it is matched fuzzily: every
+// significant word must be close to a distinct word of the name or alias,
+// which forgives typos and word order but not extra or missing words.
+package topics
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// minFuzzyWord is the length below which words must match exactly; "c" and
+// "r" are different languages.
+const minFuzzyWord = 4
+
+// stopWords are left out when matching fuzzily.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "for": true, "in": true, "of": true, "on": true, "the": true, "to": true,
+}
+
+// Key is the form topics and aliases are compared in: Unicode NFC, lower
+// case, punctuation removed, whitespace collapsed and English plurals made
+// singular. '+' and '#' are kept so that C, C++ and C# stay apart.
+func Key(topic string) string {
+	topic = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u200c' || r == '\u200d' || r == '+' || r == '#':
+			return r
+		case unicode.Is(unicode.Cf, r):
+			return -1
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			return ' '
+		}
+		return r
+	}, norm.NFC.String(strings.ToLower(topic)))
+
+	words := strings.Fields(topic)
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// singular strips the common English plural endings from words long enough
+// not to be mangled by it; "dbms" and "gas" are left alone.
+func singular(word string) string {
+	if len(word) <= 4 || !strings.HasSuffix(word, "s") {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	}
+	return strings.TrimSuffix(word, "s")
+}
+
+// significant returns the words of a key without stop words.
+func significant(key string) []string {
+	var words []string
+	for _, word := range strings.Fields(key) {
+		if !stopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// fuzzy scores how well the words of query match the words of candidate,
+// both keys. Every word must be paired with a distinct word of the other
+// with a similarity of at least threshold; the score is the mean similarity
+// of the pairs.
+func fuzzy(query, candidate []string, threshold float64) (float64, bool) {
+	if len(query) == 0 || len(query) != len(candidate) {
+		return 0, false
+	}
+
+	used := make([]bool, len(candidate))
+	var total float64
+	for _, word := range query {
+		best, bestScore := -1, 0.0
+		for i, other := range candidate {
+			if used[i] {
+				continue
+			}
+			if score := wordSimilarity(word, other); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 || bestScore < threshold {
+			return 0, false
+		}
+		used[best] = true
+		total += bestScore
+	}
+	return total / float64(len(query)), true
+}
+
+// wordSimilarity is 1 minus the edit distance between a and b relative to
+// the longer of them. Short words only match themselves.
+func wordSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < minFuzzyWord || len(rb) < minFuzzyWord {
+		return 0
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(max(len(ra), len(rb)))
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,186 @@
+package topics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/types"
+)
+
+// maxDepth bounds walks up the topic tree.
+const maxDepth = 32
+
+// entry is one name or alias a topic can be matched by.
+type entry struct {
+	words   []string
+	topicID int64
+}
+
+// Resolver maps topic strings to canonical topics. It keeps the whole
+// taxonomy in memory and reloads it when it is older than ttl, or after
+// Reload.
+type Resolver struct {
+	db        *sql.DB
+	threshold float64
+	ttl       time.Duration
+
+	mu      sync.RWMutex
+	topics  map[int64]types.Topic
+	exact   map[string]int64
+	entries []entry
+	loaded  time.Time
+}
+
+func New(db *sql.DB, threshold float64, ttl time.Duration) *Resolver {
+	return &Resolver{db: db, threshold: threshold, ttl: ttl}
+}
+
+// Reload reads the taxonomy from the database.
+func (r *Resolver) Reload(ctx context.Context) error {
+	all, err := database.FetchTopics(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	topics := make(map[int64]types.Topic, len(all))
+	exact := make(map[string]int64)
+	var entries []entry
+	add := func(name string, id int64) {
+		key := Key(name)
+		if key == "" {
+			return
+		}
+		if _, taken := exact[key]; !taken {
+			exact[key] = id
+		}
+		entries = append(entries, entry{words: significant(key), topicID: id})
+	}
+	// Names take precedence over aliases; all is ordered by name.
+	for _, topic := range all {
+		topics[topic.ID] = topic
+		add(topic.Name, topic.ID)
+	}
+	for _, topic := range all {
+		for _, alias := range topic.Aliases {
+			add(alias, topic.ID)
+		}
+	}
+
+	r.mu.Lock()
+	r.topics, r.exact, r.entries, r.loaded = topics, exact, entries, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// ensureLoaded reloads a stale taxonomy. If reloading fails the stale one is
+// kept; only a resolver that never loaded returns the error.
+func (r *Resolver) ensureLoaded(ctx context.Context) error {
+	r.mu.RLock()
+	loaded := r.loaded
+	r.mu.RUnlock()
+	if !loaded.IsZero() && time.Since(loaded) < r.ttl {
+		return nil
+	}
+
+	err := r.Reload(ctx)
+	if err != nil && !loaded.IsZero() {
+		log.Printf("[Topics] Reloading the taxonomy failed, using the one from %s: %v", loaded.Format(time.RFC3339), err)
+		return nil
+	}
+	return err
+}
+
+// Resolve returns the canonical topic text resolves to, or nil if it
+// resolves to none.
+func (r *Resolver) Resolve(ctx context.Context, text string) (*types.Topic, error) {
+	if err := r.ensureLoaded(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load topics: %w", err)
+	}
+	key := Key(text)
+	if key == "" {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id, ok := r.exact[key]; ok {
+		topic := r.topics[id]
+		return &topic, nil
+	}
+
+	words := significant(key)
+	var best int64
+	var bestScore float64
+	for _, e := range r.entries {
+		score, ok := fuzzy(words, e.words, r.threshold)
+		if ok && (score > bestScore || score == bestScore && e.topicID < best) {
+			best, bestScore = e.topicID, score
+		}
+	}
+	if bestScore == 0 {
+		return nil, nil
+	}
+	topic := r.topics[best]
+	return &topic, nil
+}
+
+// Path returns the topic and its ancestors, root first.
+func (r *Resolver) Path(topicID int64) []types.Topic {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var path []types.Topic
+	for id := &topicID; id != nil && len(path) < maxDepth; {
+		topic, ok := r.topics[*id]
+		if !ok {
+			break
+		}
+		path = append([]types.Topic{topic}, path...)
+		id = topic.ParentID
+	}
+	return path
+}
+
+// Apply fills in the request's TopicID and CanonicalTopic from its Topic.
+func (r *Resolver) Apply(ctx context.Context, quizRequest *types.QuizRequest) error {
+	quizRequest.TopicID, quizRequest.CanonicalTopic = nil, ""
+	topic, err := r.Resolve(ctx, quizRequest.Topic)
+	if err != nil || topic == nil {
+		return err
+	}
+	quizRequest.TopicID, quizRequest.CanonicalTopic = &topic.ID, topic.Name
+	return nil
+}
+
+// Backfill links saved quizzes without a topic to the topic their name now
+// resolves to, looking at the limit most frequent unresolved names. It
+// returns how many quizzes were linked.
+func (r *Resolver) Backfill(ctx context.Context, limit int) (int64, error) {
+	unresolved, err := database.FetchUnresolvedTopics(ctx, r.db, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var linked int64
+	for _, u := range unresolved {
+		topic, err := r.Resolve(ctx, u.QuizName)
+		if err != nil {
+			return linked, err
+		}
+		if topic == nil {
+			continue
+		}
+		n, err := database.LinkQuizzesToTopic(ctx, r.db, u.QuizName, topic.ID)
+		if err != nil {
+			return linked, err
+		}
+		linked += n
+	}
+	return linked, nil
+}
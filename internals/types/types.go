@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type User struct {
 	Id         int64       `json:"id"`
@@ -10,6 +13,9 @@ type User struct {
 	IsVarified bool        `json:"isVarified"`
 	ProfileImg string      `json:"profileImg"`
 	Bio        *string     `json:"bio"`
+	// PreferredLanguage is the BCP-47 tag quizzes are generated in when a
+	// request doesn't name a language; empty means English.
+	PreferredLanguage string `json:"preferredLanguage"`
 }
 
 type QuizRequest struct {
@@ -17,6 +23,107 @@ type QuizRequest struct {
 	Topic        string `json:"topic"`
 	NumQuestions int    `json:"num_questions"`
 	Difficulty   string `json:"difficulty"`
+	// Level is a finer difficulty from 1 to 10 within Difficulty, set when
+	// Difficulty was "adaptive". Filled in by the server, never by the
+	// client.
+	Level int `json:"level,omitempty"`
+	// Language is the BCP-47 tag of the language to write the quiz in. Empty
+	// means the user's preferred language, or English.
+	Language string `json:"language,omitempty"`
+	// BankRatio is the share (0-1) of questions to reuse from the question
+	// bank; nil means the server default.
+	BankRatio *float64 `json:"bank_ratio,omitempty"`
+	// PastQuestions are questions the user has already seen that the model is
+	// told not to repeat. Filled in by the server, never by the client.
+	PastQuestions []string `json:"past_questions,omitempty"`
+	// QuestionTypes restricts the quiz to these question types; empty means
+	// single-answer MCQ only.
+	QuestionTypes []string `json:"question_types,omitempty"`
+	// MaterialID bases the quiz on one of the user's uploaded materials.
+	MaterialID *int64 `json:"material_id,omitempty"`
+	// Material holds the chunks of that material chosen for the prompt.
+	// Filled in by the server, never by the client.
+	Material []string `json:"material,omitempty"`
+	// PromptTemplate is the prompt variant the user is assigned to; nil means
+	// the backend's built-in prompt. Filled in by the server, never by the
+	// client.
+	PromptTemplate *PromptTemplate `json:"prompt_template,omitempty"`
+	// TopicID and CanonicalTopic identify the canonical topic Topic resolves
+	// to; nil and empty if it resolves to none. Filled in by the server,
+	// never by the client.
+	TopicID        *int64 `json:"topic_id,omitempty"`
+	CanonicalTopic string `json:"canonical_topic,omitempty"`
+}
+
+// Topic is a canonical quiz topic. Topics form a tree through ParentID, and
+// Aliases are other names that resolve to them, such as "DBMS" for
+// "database management systems".
+type Topic struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UnresolvedTopic is a quiz name that resolves to no canonical topic, with
+// the number of quizzes that use it.
+type UnresolvedTopic struct {
+	QuizName string `json:"quiz_name"`
+	Quizzes  int    `json:"quizzes"`
+}
+
+// PromptTemplate is one version of a generation prompt, written as a Go
+// text/template. Active versions are A/B tested against each other, each
+// user getting one of them in proportion to its weight.
+type PromptTemplate struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Version   int       `json:"version" db:"version"` // counts up per name
+	Body      string    `json:"body" db:"body"`
+	Weight    int       `json:"weight" db:"weight"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Label identifies the version in reports, e.g. "concise@3".
+func (t *PromptTemplate) Label() string {
+	if t == nil {
+		return "builtin"
+	}
+	return fmt.Sprintf("%s@%d", t.Name, t.Version)
+}
+
+// PromptMetrics compares the quality of the quizzes a prompt version
+// produced.
+type PromptMetrics struct {
+	PromptTemplate
+	Jobs         int     `json:"jobs"`           // generation jobs that succeeded
+	AvgRounds    float64 `json:"avg_rounds"`     // generator calls per job
+	AvgDropped   float64 `json:"avg_dropped"`    // questions discarded per job
+	AvgRepaired  float64 `json:"avg_repaired"`   // repairs per job
+	Quizzes      int     `json:"quizzes"`        // quizzes submitted
+	AvgScoreRate float64 `json:"avg_score_rate"` // mean score / totalQuestions
+}
+
+// Material is a document a user uploaded to generate quizzes from. Its text
+// is stored in chunks alongside it.
+type Material struct {
+	ID         int64     `json:"id" db:"id"`
+	UserID     int64     `json:"user_id" db:"user_id"`
+	Title      string    `json:"title" db:"title"`
+	Kind       string    `json:"kind" db:"kind"` // text, markdown, html or pdf
+	Characters int       `json:"characters" db:"characters"`
+	Chunks     int       `json:"chunks" db:"chunks"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// PastQuestion is a question from one of a user's earlier quizzes.
+type PastQuestion struct {
+	Question  string    `json:"question"`
+	QuizName  string    `json:"quiz_name"`
+	CreatedAt time.Time `json:"created_at"`
+	TopicID   *int64    `json:"topic_id,omitempty"`
 }
 
 type Quiz struct {
@@ -26,18 +133,292 @@ type Quiz struct {
 	Score          int       `json:"score" db:"score"`
 	TotalQuestions int       `json:"totalQuestions" db:"totalQuestions"`
 	UserID         int       `json:"user_id" validate:"required" db:"user_id"` // Foreign key to the users table
+	Language       string    `json:"language" db:"language"`                   // BCP-47 tag; empty means English
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	// JobID is the generation job the quiz was saved from. Its output is
+	// the only source of the quiz's questions and answer keys.
+	JobID *int64 `json:"job_id,omitempty" validate:"required" db:"job_id"`
+	// PromptTemplateID is the prompt version that generated the quiz, from
+	// the job's report; nil for the built-in prompt. Set by the server.
+	PromptTemplateID *int64 `json:"prompt_template_id,omitempty" db:"prompt_template_id"`
+	// DifficultyLevel is the 1-10 level of an adaptive quiz, if any, from
+	// the job's request. Set by the server.
+	DifficultyLevel *int `json:"difficulty_level,omitempty" db:"difficulty_level"`
+	// TopicID is the canonical topic QuizName resolves to, and Topic its
+	// name; QuizName keeps the user's wording. Set by the server.
+	TopicID *int64 `json:"topic_id,omitempty" db:"topic_id"`
+	Topic   string `json:"topic,omitempty"`
+	// SubmittedAt is when the latest attempt was submitted and graded, and
+	// Score that attempt's score; nil and zero until the first submission.
+	SubmittedAt *time.Time `json:"submitted_at,omitempty" db:"submitted_at"`
+	// Attempts counts the finished attempts, and BestScore and LatestScore
+	// are the best and the most recent of their scores; nil if there are
+	// none. Filled in by FetchQuizzesByUser.
+	Attempts    int  `json:"attempts"`
+	BestScore   *int `json:"best_score,omitempty"`
+	LatestScore *int `json:"latest_score,omitempty"`
+	// TimeLimitSeconds bounds how long an attempt may take; nil means
+	// untimed, unless every question has its own limit, in which case their
+	// sum applies.
+	TimeLimitSeconds *int `json:"time_limit_seconds,omitempty" validate:"omitempty,min=1" db:"time_limit_seconds"`
+}
+
+// Attempt is one sitting of a quiz. A quiz can be taken any number of times,
+// one attempt at a time; an attempt is open until it is submitted.
+type Attempt struct {
+	ID             int64      `json:"id"`
+	QuizID         int        `json:"quiz_id"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	Score          *int       `json:"score,omitempty"` // nil while open
+	TotalQuestions int        `json:"totalQuestions"`
+	// Retake is set on attempts finished after the quiz's first.
+	Retake bool `json:"retake,omitempty"`
+	// Position is the serial number of the question the user was last at,
+	// 0 if never saved, and LastActiveAt when the attempt last started or
+	// saved answers.
+	Position     int       `json:"position"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	// Deadline is when a timed attempt runs out, fixed when it starts, and
+	// RemainingSeconds the time left to it as of the response, for open
+	// attempts; both nil if untimed.
+	Deadline         *time.Time `json:"deadline,omitempty"`
+	RemainingSeconds *int       `json:"remaining_seconds,omitempty"`
+}
+
+// AttemptView is an attempt with its questions: their prompts and the
+// answers saved so far while it is open, and the graded answers with the
+// solutions once it is finished.
+type AttemptView struct {
+	Attempt
+	Questions []QuestionView `json:"questions"`
+}
+
+// QuizShare is a user a quiz's owner has shared it with. They may view the
+// quiz and its attempts but not take it.
+type QuizShare struct {
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username"`
+	SharedAt time.Time `json:"shared_at"`
+}
+
+// SkillEstimate is a user's Elo rating on one topic, with the difficulty
+// recommended for their next quiz on it.
+type SkillEstimate struct {
+	Topic                 string    `json:"topic"`
+	Rating                float64   `json:"rating"`
+	Quizzes               int       `json:"quizzes"` // scored quizzes the rating is based on
+	RecommendedDifficulty string    `json:"recommended_difficulty"`
+	RecommendedLevel      int       `json:"recommended_level"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// UserStats summarizes a user's quiz history.
+type UserStats struct {
+	Quizzes      int     `json:"quizzes"`
+	Questions    int     `json:"questions"`
+	AvgScoreRate float64 `json:"avg_score_rate"` // mean score / totalQuestions
+	// Rating is the user's overall rating, the mean of their topic ratings
+	// weighted by quizzes; new topics start from it.
+	Rating float64         `json:"rating"`
+	Skills []SkillEstimate `json:"skills"`
+
+	// The rest is computed over every finished attempt, not only the latest
+	// of each quiz.
+	AnsweredQuestions int            `json:"answered_questions"` // questions given an answer
+	ByTopic           []AccuracyStat `json:"by_topic"`
+	ByLevel           []AccuracyStat `json:"by_level"`
+	// Strongest and Weakest are the topics with the highest and lowest
+	// accuracy among those with enough questions to tell, best and worst
+	// first; a topic is in at most one of them.
+	Strongest []AccuracyStat `json:"strongest_topics"`
+	Weakest   []AccuracyStat `json:"weakest_topics"`
+	// Trend is the accuracy per TrendBucket, oldest first, skipping buckets
+	// without attempts.
+	TrendBucket string       `json:"trend_bucket"`
+	Trend       []TrendPoint `json:"trend"`
+	// AvgSecondsPerQuestion is the mean time taken per question on timed
+	// attempts; nil without any.
+	AvgSecondsPerQuestion *float64 `json:"avg_seconds_per_question,omitempty"`
 }
 
+// Score trend buckets.
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// AccuracyStat is how a user did on a topic or quiz level across attempts.
+type AccuracyStat struct {
+	Name      string  `json:"name"`
+	Attempts  int     `json:"attempts"`
+	Questions int     `json:"questions"`
+	Correct   int     `json:"correct"`
+	Accuracy  float64 `json:"accuracy"` // Correct / Questions
+}
+
+// TrendPoint is how a user did on the attempts they finished in the bucket
+// of time starting at Start.
+type TrendPoint struct {
+	Start     time.Time `json:"start"`
+	Attempts  int       `json:"attempts"`
+	Questions int       `json:"questions"`
+	Correct   int       `json:"correct"`
+	Accuracy  float64   `json:"accuracy"` // Correct / Questions
+}
+
+// Question types
+const (
+	QuestionMCQ         = "mcq"          // one correct option
+	QuestionMultiSelect = "multi_select" // one or more correct options
+	QuestionTrueFalse   = "true_false"   // options are always "True" and "False"
+	QuestionFillIn      = "fill_in"      // free text, no options
+	QuestionOrdering    = "ordering"     // options must be put in order
+)
+
+// Question is a quiz question in two parts: the prompt a quiz taker sees
+// and the solution they may only see once their attempt is submitted. Both
+// are embedded, so a Question still reads and writes flat JSON.
 type Question struct {
-	ID            int      `json:"id" db:"id"`
-	SerialNumber  int      `json:"serial_number" validate:"required" db:"serial_number"`
-	Question      string   `json:"question" validate:"required" db:"question"`
-	Options       []string `json:"options" validate:"required,dive,required" db:"options"` // JSONB field in PostgreSQL
-	CorrectAnswer string   `json:"correctAnswer" validate:"required" db:"correct_answer"`
-	UserAnswer    string   `json:"user_answer"  db:"user_answer"`
-	Description   string   `json:"description" db:"description"`
-	QuizID        int      `json:"quiz_id" validate:"required" db:"quiz_id"` // Foreign key to the quizzes table
+	QuestionPrompt
+	QuestionSolution
+	UserAnswer  string   `json:"user_answer"  db:"user_answer"`
+	UserAnswers []string `json:"user_answers,omitempty" db:"user_answers"` // multi_select and ordering
+	QuizID      int      `json:"quiz_id" validate:"required" db:"quiz_id"` // Foreign key to the quizzes table
+	// IsCorrect is the graded result of the user's answer in the latest
+	// finished attempt; nil if there is none.
+	IsCorrect *bool `json:"is_correct,omitempty" db:"is_correct"`
+}
+
+// QuestionPrompt is what a quiz taker sees of a question.
+type QuestionPrompt struct {
+	ID           int      `json:"id" db:"id"`
+	SerialNumber int      `json:"serial_number" validate:"required" db:"serial_number"`
+	Type         string   `json:"type" validate:"omitempty,oneof=mcq multi_select true_false fill_in ordering" db:"type"` // empty means mcq
+	Question     string   `json:"question" validate:"required" db:"question"`
+	Options      []string `json:"options" validate:"required_unless=Type fill_in,dive,required" db:"options"` // JSONB field in PostgreSQL
+	// Fill-in matching rules. Answers are always trimmed with inner whitespace
+	// collapsed; by default case is ignored.
+	CaseSensitive bool `json:"caseSensitive,omitempty" db:"case_sensitive"`
+	IgnoreSpaces  bool `json:"ignoreSpaces,omitempty" db:"ignore_spaces"` // remove all whitespace, e.g. for formulas
+	// TimeLimitSeconds is the time the user may spend on the question,
+	// counted from when their attempt first moves to it; nil if it has no
+	// limit of its own.
+	TimeLimitSeconds *int `json:"time_limit_seconds,omitempty" validate:"omitempty,min=1" db:"time_limit_seconds"`
+}
+
+// QuestionSolution is the answer key of a question with its explanation.
+type QuestionSolution struct {
+	CorrectAnswer string `json:"correctAnswer" validate:"required" db:"correct_answer"`
+	// CorrectAnswers is set for types with more than one answer: every correct
+	// option for multi_select, the accepted variants for fill_in and the
+	// options in their correct order for ordering. CorrectAnswer then holds a
+	// readable summary.
+	CorrectAnswers []string `json:"correctAnswers,omitempty" db:"correct_answers"`
+	Description    string   `json:"description" db:"description"`
+	// SourceExcerpt quotes the passage of the study material the answer comes
+	// from, for quizzes generated from uploaded material.
+	SourceExcerpt string `json:"source_excerpt,omitempty" db:"source_excerpt"`
+}
+
+// QuestionView is a question as shown to the users who may see a quiz: the
+// prompt always, with the user's answer, and the solution and grading only
+// once the attempt is submitted.
+type QuestionView struct {
+	Prompt      QuestionPrompt    `json:"prompt"`
+	Solution    *QuestionSolution `json:"solution,omitempty"`
+	UserAnswer  string            `json:"user_answer"`
+	UserAnswers []string          `json:"user_answers,omitempty"`
+	Correct     *bool             `json:"correct,omitempty"`
+	// Locked is set on a timed question an open attempt hasn't moved to
+	// yet. Its text and options are withheld until then, so that its clock
+	// can't be dodged by reading it early.
+	Locked bool `json:"locked,omitempty"`
+}
+
+// View returns the question as shown before submission, or with reveal, as
+// shown after it with correct as its grading.
+func (q Question) View(reveal bool, correct *bool) QuestionView {
+	view := QuestionView{Prompt: q.QuestionPrompt, UserAnswer: q.UserAnswer, UserAnswers: q.UserAnswers}
+	if reveal {
+		solution := q.QuestionSolution
+		view.Solution, view.Correct = &solution, correct
+	}
+	return view
+}
+
+// LockedView returns the question as shown before its attempt has moved to
+// it; see QuestionView.Locked.
+func (q Question) LockedView() QuestionView {
+	view := QuestionView{Prompt: q.QuestionPrompt, Locked: true}
+	view.Prompt.Question, view.Prompt.Options = "", []string{}
+	return view
+}
+
+// Answer is the user's answer to one question of a quiz, identified by its
+// serial number. Single-answer types use Answer, multi_select and ordering
+// use Answers.
+type Answer struct {
+	SerialNumber int      `json:"serial_number" validate:"required"`
+	Answer       string   `json:"answer,omitempty"`
+	Answers      []string `json:"answers,omitempty"`
+}
+
+// Submission is the body of a quiz submission. Questions without an answer
+// count as wrong.
+type Submission struct {
+	Answers []Answer `json:"answers" validate:"dive"`
+}
+
+// AttemptProgress is the body of an autosave of an open attempt: answers
+// given so far, graded only on submission, and the question the user is at.
+// Moving to a question with its own time limit starts its clock; it must be
+// moved to before it is answered.
+type AttemptProgress struct {
+	Answers  []Answer `json:"answers" validate:"dive"`
+	Position *int     `json:"position,omitempty" validate:"omitempty,min=1"`
+}
+
+// GradedQuiz is the server's grading of one attempt at a quiz.
+type GradedQuiz struct {
+	QuizID         int            `json:"quiz_id"`
+	AttemptID      int64          `json:"attempt_id"`
+	Score          int            `json:"score"`
+	TotalQuestions int            `json:"totalQuestions"`
+	StartedAt      time.Time      `json:"started_at"`
+	SubmittedAt    time.Time      `json:"submitted_at"`
+	Questions      []QuestionView `json:"questions"`
+	// Late is set when the attempt was past its deadline and was graded with
+	// the answers saved before it instead of the ones submitted.
+	Late bool `json:"late,omitempty"`
+	// Voided lists the questions whose submitted answers were dropped for
+	// coming after the question's own time limit.
+	Voided []int `json:"voided,omitempty"`
+	// Retake is set when the quiz had been graded before, so its answer
+	// keys were known; retakes don't count toward ratings and stats.
+	Retake bool `json:"retake,omitempty"`
+}
+
+// QuestionRevision is an earlier version of a quiz question, kept when the
+// question was regenerated.
+type QuestionRevision struct {
+	ID         int64     `json:"id"`
+	Revision   int       `json:"revision"` // 1 for the originally generated question
+	Question   Question  `json:"question"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// View returns the revision with its question as Question.View shows it.
+func (r QuestionRevision) View(reveal bool) QuestionRevisionView {
+	return QuestionRevisionView{QuestionRevision: r, Question: r.Question.View(reveal, nil)}
+}
+
+// QuestionRevisionView is a QuestionRevision as shown to its user; see
+// QuestionRevision.View.
+type QuestionRevisionView struct {
+	QuestionRevision
+	Question QuestionView `json:"question"`
 }
 
 type GoogleTokenInfo struct {
@@ -55,6 +436,176 @@ type UserUpdateRequest struct {
 	IsUsernameChanged bool   `json:"isUsernameChanged"`
 	IsBioChanged      bool   `json:"isbioChanged"`
 	IsEmailChanged    bool   `json:"isemailChanged"`
+	IsLanguageChanged bool   `json:"isLanguageChanged"`
+	Language          string `json:"language"`
 	NewPassword       string `json:"newPassword"`
 	Username          string `json:"username"`
 }
+
+// ProviderHealth is the circuit breaker state of one quiz generation
+// provider, as shown on the admin health endpoint.
+type ProviderHealth struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"` // closed, open or half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Requests            int64      `json:"requests"`
+	Failures            int64      `json:"failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	// RetryAt is when an open breaker lets the next trial call through.
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// Generation outcomes recorded in the usage ledger
+const (
+	UsageSucceeded   = "succeeded"
+	UsageRefused     = "refused"
+	UsageFailed      = "failed"
+	UsageUnavailable = "unavailable" // every provider failed or was skipped
+	UsageCancelled   = "cancelled"
+	// UsagePending reserves the entry of a generation under way
+	UsagePending = "pending"
+)
+
+// UsageRecord is one quiz generation in the usage ledger. Tokens are summed
+// over every model call the generation made.
+type UsageRecord struct {
+	ID             int64     `json:"id" db:"id"`
+	UserID         int64     `json:"user_id" db:"user_id"`
+	JobID          *int64    `json:"job_id,omitempty" db:"job_id"`
+	Provider       string    `json:"provider" db:"provider"` // providers that answered, comma-separated
+	PromptTokens   int       `json:"prompt_tokens" db:"prompt_tokens"`
+	ResponseTokens int       `json:"response_tokens" db:"response_tokens"`
+	LatencyMs      int64     `json:"latency_ms" db:"latency_ms"`
+	Outcome        string    `json:"outcome" db:"outcome"`
+	Error          string    `json:"error,omitempty" db:"error"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// UsagePeriod is a user's usage in the current day or month against their
+// quota. A limit of 0 means unlimited.
+type UsagePeriod struct {
+	Generations     int       `json:"generations"`
+	Pending         int       `json:"pending"` // under way, counted against the limit
+	GenerationLimit int       `json:"generation_limit"`
+	Tokens          int       `json:"tokens"`
+	TokenLimit      int       `json:"token_limit"`
+	ResetsAt        time.Time `json:"resets_at"`
+}
+
+type UsageSummary struct {
+	Day   UsagePeriod `json:"day"`
+	Month UsagePeriod `json:"month"`
+}
+
+// Review states of a moderation event
+const (
+	ModerationPending    = "pending"
+	ModerationUpheld     = "upheld"     // the rejection was right
+	ModerationOverturned = "overturned" // the text should have been allowed
+)
+
+// ModerationEvent is a topic rejected by the moderation stage, kept for
+// admins to review.
+type ModerationEvent struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Text       string     `json:"text" db:"text"`
+	Code       string     `json:"code" db:"code"`
+	Reason     string     `json:"reason" db:"reason"`
+	Check      string     `json:"check" db:"check_name"` // the rule or classifier that rejected it
+	Status     string     `json:"status" db:"status"`
+	ReviewNote string     `json:"review_note,omitempty" db:"review_note"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Quiz generation job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+type QuizJob struct {
+	ID        int64             `json:"id" db:"id"`
+	UserID    int64             `json:"user_id" db:"user_id"`
+	Status    string            `json:"status" db:"status"`
+	Progress  int               `json:"progress" db:"progress"` // 0-100
+	Attempts  int               `json:"-" db:"attempts"`
+	Request   QuizRequest       `json:"request" db:"request"`
+	Questions []Question        `json:"questions,omitempty" db:"result"`
+	Report    *GenerationReport `json:"report,omitempty" db:"report"`
+	Error     string            `json:"error,omitempty" db:"error"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// View returns the job as its user sees it: the questions' prompts only,
+// without the answer keys, which stay in the job for InsertQuestions.
+func (j QuizJob) View() QuizJobView {
+	view := QuizJobView{QuizJob: j, Report: j.Report.Public()}
+	if len(j.Questions) > 0 {
+		view.Questions = make([]QuestionView, len(j.Questions))
+		for i, q := range j.Questions {
+			view.Questions[i] = q.View(false, nil)
+		}
+	}
+	return view
+}
+
+// QuizJobView is a QuizJob as shown to its user; see QuizJob.View.
+type QuizJobView struct {
+	QuizJob
+	Questions []QuestionView    `json:"questions,omitempty"`
+	Report    *GenerationReport `json:"report,omitempty"`
+}
+
+// GenerationReport describes what the validation stage did to the model's
+// output before it reached the client.
+type GenerationReport struct {
+	Requested int      `json:"requested"`
+	Returned  int      `json:"returned"`
+	Rounds    int      `json:"rounds"`             // generator calls made
+	FromBank  int      `json:"from_bank"`          // reused from the question bank
+	Repaired  []string `json:"repaired,omitempty"` // one note per fix
+	Dropped   []string `json:"dropped,omitempty"`  // one note per discarded question
+	// The prompt version used; PromptTemplateID is nil for the built-in one.
+	PromptTemplateID *int64 `json:"prompt_template_id,omitempty"`
+	PromptVersion    string `json:"prompt_version,omitempty"`
+}
+
+// Public returns the report without its repair notes, which quote answer
+// keys, for the user who will take the quiz.
+func (r *GenerationReport) Public() *GenerationReport {
+	if r == nil {
+		return nil
+	}
+	public := *r
+	public.Repaired = nil
+	return &public
+}
+
+// ReviewCard schedules a question the user missed for spaced-repetition
+// review with SM-2: EaseFactor scales the interval after each successful
+// recall, Repetitions counts the successful recalls in a row and the card is
+// due IntervalDays after it was last reviewed.
+type ReviewCard struct {
+	ID             int64         `json:"id"`
+	QuizID         int           `json:"quiz_id"`
+	Question       *QuestionView `json:"question,omitempty"`
+	EaseFactor     float64       `json:"ease_factor"`
+	IntervalDays   int           `json:"interval_days"`
+	Repetitions    int           `json:"repetitions"`
+	Reviews        int           `json:"reviews"`
+	DueAt          time.Time     `json:"due_at"`
+	LastReviewedAt *time.Time    `json:"last_reviewed_at,omitempty"`
+}
+
+// ReviewGrade is the body of a review: how well the user recalled the
+// answer, from 0 (blackout) to 5 (perfect).
+type ReviewGrade struct {
+	Grade *int `json:"grade" validate:"required,min=0,max=5"`
+}
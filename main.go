@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 	"encoding/json"
@@ -17,8 +18,16 @@ import (
 	"firebase.google.com/go/v4/auth"
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/config"
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/database"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/generateQuiz"
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/http/handlers"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/jobs"
 	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/middlewares"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/moderation"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/quizbank"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/quota"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/review"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/skill"
+	"github.com/SudipSarkar1193/Try-Your-Gyan-v2.git/internals/topics"
 	"github.com/rs/cors"
 
 	"github.com/gorilla/mux"
@@ -33,7 +42,7 @@ type Route struct {
 }
 
 // Function to return all API routes
-func getRoutes(db *sql.DB, client *auth.Client) []Route {
+func getRoutes(db *sql.DB, client *auth.Client, checker *generateQuiz.Checker, runner *jobs.Runner, limiter *quota.Limiter, screener *moderation.Screener, tracker *skill.Tracker, resolver *topics.Resolver, late database.LateSubmission, queue *review.Queue) []Route {
 	return []Route{
 		{"/", "GET", func(w http.ResponseWriter, r *http.Request) {
 			if r.Method != http.MethodGet {
@@ -74,19 +83,52 @@ func getRoutes(db *sql.DB, client *auth.Client) []Route {
 		{"/api/users/update-profile-pic", "PUT", handlers.UpdateProfilePic(db), true},
 		{"/api/users/verify-email", "POST", handlers.VerifyEmailToUpdate(db, client), true},
 		{"/api/users/update-profile", "PUT", handlers.UpdateUserDetails(db), true},
-		{"/api/quiz/generate", "POST", handlers.GenerateQuiz(), true},
-		{"/api/quiz/new", "POST", handlers.CreateQuizInDatabase(db), true},
+		{"/api/users/usage", "GET", handlers.GetUsage(db, limiter), true},
+		{"/api/users/stats", "GET", handlers.GetUserStats(tracker), true},
+		{"/api/quiz/generate", "POST", handlers.GenerateQuiz(db, runner, limiter, screener, tracker, resolver), true},
+		{"/api/quiz/generate/stream", "GET", handlers.GenerateQuizStream(db, checker, limiter, screener, tracker, resolver), true},
+		{"/api/quiz/jobs/{id:[0-9]+}", "GET", handlers.GetQuizJob(db), true},
+		{"/api/materials", "POST", handlers.UploadMaterial(db), true},
+		{"/api/materials", "GET", handlers.GetMaterials(db), true},
+		{"/api/materials/{id:[0-9]+}", "DELETE", handlers.DeleteMaterial(db), true},
+		{"/api/quiz/new", "POST", handlers.CreateQuizInDatabase(db, resolver, tracker), true},
 		{"/api/quiz/questions/new", "POST", handlers.InsertQuestions(db), true},
 		{"/api/quiz/quizzes", "GET", handlers.GetUserQuizzesHandler(db), true},
-		{"/api/quiz/quizzes", "DELETE", handlers.DeleteQuiz(db), true},
-		{"/api/quiz/questions", "GET", handlers.GetQuizQuestionsHandler(db), false},
+		{"/api/quiz/quizzes", "DELETE", handlers.DeleteQuiz(db, tracker), true},
+		{"/api/quiz/questions", "GET", handlers.GetQuizQuestionsHandler(db), true},
+		{"/api/quiz/{quizID:[0-9]+}/submit", "POST", handlers.SubmitQuiz(db, tracker, late), true},
+		{"/api/quiz/{quizID:[0-9]+}/attempts", "POST", handlers.StartAttempt(db, tracker, late), true},
+		{"/api/quiz/{quizID:[0-9]+}/attempts", "GET", handlers.GetAttempts(db), true},
+		{"/api/quiz/{quizID:[0-9]+}/attempts/current", "GET", handlers.ResumeAttempt(db), true},
+		{"/api/quiz/{quizID:[0-9]+}/attempts/current/answers", "PUT", handlers.SaveAttemptAnswers(db, tracker, late), true},
+		{"/api/quiz/{quizID:[0-9]+}/attempts/{attemptID:[0-9]+}", "GET", handlers.GetAttempt(db), true},
+		{"/api/quiz/{quizID:[0-9]+}/shares", "POST", handlers.ShareQuiz(db), true},
+		{"/api/quiz/{quizID:[0-9]+}/shares", "GET", handlers.GetQuizShares(db), true},
+		{"/api/quiz/{quizID:[0-9]+}/shares/{userID:[0-9]+}", "DELETE", handlers.UnshareQuiz(db), true},
+		{"/api/quiz/{quizID:[0-9]+}/questions/{serial:[0-9]+}/regenerate", "POST", handlers.RegenerateQuestion(db, checker, limiter, screener), true},
+		{"/api/quiz/{quizID:[0-9]+}/questions/{serial:[0-9]+}/revisions", "GET", handlers.GetQuestionRevisions(db), true},
+		{"/api/review/due", "GET", handlers.GetDueReviews(queue), true},
+		{"/api/review/{id:[0-9]+}", "POST", handlers.RecordReview(queue), true},
+		{"/api/topics", "GET", handlers.GetTopics(db), true},
+		{"/api/topics/resolve", "GET", handlers.ResolveTopic(resolver), true},
 		{"/api/auth/me", "GET", middlewares.GetUserDetails(db), true},
+		{"/api/admin/providers", "GET", middlewares.AdminMiddleware(handlers.GetProviderHealth(checker)), false},
+		{"/api/admin/moderation", "GET", middlewares.AdminMiddleware(handlers.GetModerationEvents(db)), false},
+		{"/api/admin/moderation/{id:[0-9]+}", "PUT", middlewares.AdminMiddleware(handlers.ReviewModerationEvent(db)), false},
+		{"/api/admin/prompts", "GET", middlewares.AdminMiddleware(handlers.GetPromptTemplates(db)), false},
+		{"/api/admin/prompts", "POST", middlewares.AdminMiddleware(handlers.CreatePromptTemplate(db)), false},
+		{"/api/admin/prompts/{id:[0-9]+}", "PUT", middlewares.AdminMiddleware(handlers.UpdatePromptTemplate(db)), false},
+		{"/api/admin/topics", "POST", middlewares.AdminMiddleware(handlers.CreateTopic(db, resolver)), false},
+		{"/api/admin/topics/unresolved", "GET", middlewares.AdminMiddleware(handlers.GetUnresolvedTopics(db)), false},
+		{"/api/admin/topics/{id:[0-9]+}", "PUT", middlewares.AdminMiddleware(handlers.UpdateTopic(db, resolver, tracker)), false},
+		{"/api/admin/topics/{id:[0-9]+}/aliases", "POST", middlewares.AdminMiddleware(handlers.AddTopicAlias(db, resolver)), false},
+		{"/api/admin/topics/{id:[0-9]+}/aliases", "DELETE", middlewares.AdminMiddleware(handlers.DeleteTopicAlias(db, resolver)), false},
 	}
 }
 
 // Register routes dynamically using Gorilla Mux
-func registerRoutes(router *mux.Router, db *sql.DB, client *auth.Client) {
-	for _, route := range getRoutes(db, client) {
+func registerRoutes(router *mux.Router, db *sql.DB, client *auth.Client, checker *generateQuiz.Checker, runner *jobs.Runner, limiter *quota.Limiter, screener *moderation.Screener, tracker *skill.Tracker, resolver *topics.Resolver, late database.LateSubmission, queue *review.Queue) {
+	for _, route := range getRoutes(db, client, checker, runner, limiter, screener, tracker, resolver, late, queue) {
 		handler := route.Handler
 		if route.Auth {
 			handler = middlewares.AuthMiddleware(handler)
@@ -97,6 +139,30 @@ func registerRoutes(router *mux.Router, db *sql.DB, client *auth.Client) {
 	}
 }
 
+// expireAbandonedAttempts deletes untimed attempts idle for longer than
+// idle and grades timed ones past their deadline, every interval, for as
+// long as the server runs. idle 0 leaves untimed attempts open.
+func expireAbandonedAttempts(db *sql.DB, tracker *skill.Tracker, late database.LateSubmission, idle, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if idle > 0 {
+			n, err := database.ExpireAbandonedAttempts(db, idle)
+			if err != nil {
+				log.Printf("[Attempts] %v", err)
+			} else if n > 0 {
+				log.Printf("[Attempts] Expired %d abandoned attempts", n)
+			}
+		}
+		n, err := handlers.CloseExpiredAttempts(db, tracker, late)
+		if err != nil {
+			log.Printf("[Attempts] %v", err)
+		} else if n > 0 {
+			log.Printf("[Attempts] Closed %d attempts past their deadline", n)
+		}
+	}
+}
+
 func main() {
 	log.Println("Welcome to GO backend")
 	cfg := config.MustLoad()
@@ -105,11 +171,66 @@ func main() {
 		log.Fatal("Database connection failed")
 	}
 
+	if err := database.Migrate(db); err != nil {
+		log.Fatal(err)
+	}
+
 	client := handlers.InitializeFirebaseApp()
 	if client == nil {
 		log.Fatal("Firebase initialization failed")
 	}
 
+	bank := quizbank.New(db)
+	generator, err := generateQuiz.New(context.Background(), cfg, bank)
+	if err != nil {
+		log.Fatalf("Quiz generator initialization failed: %v", err)
+	}
+	log.Printf("Using %q quiz generator with fallbacks %q", cfg.QuizGenerator, cfg.QuizFallbacks)
+
+	checker := generateQuiz.NewChecker(generator, cfg.MaxRounds)
+	checker.UseSource(bank, cfg.BankRatio)
+	checker.UseHistory(quizbank.NewHistory(db, cfg.HistoryLimit), cfg.PromptPastQuestions, cfg.SimilarityThreshold)
+	checker.UseMaterials(quizbank.NewMaterials(db), cfg.MaterialPromptChars)
+	checker.UsePrompts(quizbank.NewPrompts(db))
+	if strings.EqualFold(cfg.QuizGenerator, "fake") && len(cfg.QuizFallbacks) == 0 {
+		// Placeholder questions are English whatever the language and much
+		// alike, so the checks on a model's writing would drop them all.
+		checker.SkipModelChecks()
+	}
+
+	runner := jobs.NewRunner(db, checker, cfg.JobWorkers, cfg.JobPollInterval)
+	runner.Start()
+
+	limiter := quota.New(db, quota.Limits{
+		DailyGenerations:   cfg.DailyGenerations,
+		MonthlyGenerations: cfg.MonthlyGenerations,
+		DailyTokens:        cfg.DailyTokens,
+		MonthlyTokens:      cfg.MonthlyTokens,
+	})
+
+	blocklist, err := moderation.LoadBlocklist(cfg.BlocklistFiles...)
+	if err != nil {
+		log.Fatalf("Moderation initialization failed: %v", err)
+	}
+	tracker := skill.New(db, cfg.StatsCacheTTL)
+	resolver := topics.New(db, cfg.TopicMatchThreshold, cfg.TopicCacheTTL)
+	queue := review.New(db)
+
+	screener := moderation.New(db, moderation.Rules{
+		MaxLength: cfg.MaxTopicLength,
+		MaxWords:  cfg.MaxTopicWords,
+		Blocklist: append(blocklist, cfg.Blocklist...),
+	})
+
+	if cfg.LateSubmission != "reject" && cfg.LateSubmission != "grade_saved" {
+		log.Fatalf("Unknown late submission policy %q, want \"reject\" or \"grade_saved\"", cfg.LateSubmission)
+	}
+	late := database.LateSubmission{Grace: cfg.SubmitGrace, GradeSaved: cfg.LateSubmission == "grade_saved"}
+	if cfg.AbandonCheckInterval <= 0 {
+		log.Fatalf("Attempt abandon check interval must be positive, got %v", cfg.AbandonCheckInterval)
+	}
+	go expireAbandonedAttempts(db, tracker, late, cfg.AbandonAfter, cfg.AbandonCheckInterval)
+
 	origins := []string{"https://try-your-gyan.vercel.app", "http://localhost:5173"}
 	if localOrigin := os.Getenv("CORS_LOCAL_ORIGIN"); localOrigin != "" {
 		origins = append(origins, localOrigin)
@@ -124,7 +245,7 @@ func main() {
 	})
 
 	router := mux.NewRouter()
-	registerRoutes(router, db, client)
+	registerRoutes(router, db, client, checker, runner, limiter, screener, tracker, resolver, late, queue)
 
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		if err := db.Ping(); err != nil {
@@ -165,4 +286,17 @@ func main() {
 	} else {
 		slog.Info("Server shut down successfully")
 	}
+
+	jobsCtx, jobsCancel := context.WithTimeout(context.Background(), cfg.JobShutdownTimeout)
+	defer jobsCancel()
+
+	if err := runner.Shutdown(jobsCtx); err != nil {
+		slog.Warn("In-flight quiz jobs were requeued", slog.String("error", err.Error()))
+	} else {
+		slog.Info("Quiz jobs drained")
+	}
+
+	if err := generator.Close(); err != nil {
+		slog.Error("Failed to close quiz generator", slog.String("error", err.Error()))
+	}
 }
\ No newline at end of file
@@ -2,8 +2,7 @@ import json
 import logging
 import sys
 import psutil
-from quiz_generation import generate_quiz
-from db import get_past_questions
+from quiz_generation import generate_quiz, error_response
 
 # Setup logging
 logging.basicConfig(
@@ -13,6 +12,34 @@ logging.basicConfig(
 )
 logger = logging.getLogger(__name__)
 
+
+def handle_request(data: dict) -> dict:
+    """Validate a quiz request and generate the quiz. Always returns an {"ok", "data"} dict."""
+    logger.info(f"Received quiz request: {data}")
+
+    # Validation
+    user_id = data.get("user_id")
+    topic = data.get("topic")
+    num_questions = data.get("num_questions")
+    difficulty = (data.get("difficulty") or "").lower()
+
+    if not isinstance(num_questions, int) or not (5 <= num_questions <= 20):
+        logger.error(f"Invalid num_questions: {num_questions}")
+        return {"ok": False, "data": ["Number of questions must be between 5 and 20"]}
+
+    if difficulty not in ["easy", "medium", "hard"]:
+        logger.error(f"Invalid difficulty: {difficulty}")
+        return {"ok": False, "data": ["Difficulty must be easy, medium, or hard"]}
+
+    # Past questions are selected by the Go server and arrive with the request
+    past_questions = data.get("past_questions") or []
+    logger.info(f"Received {len(past_questions)} past questions for user {user_id}, topic {topic}")
+
+    result = generate_quiz(data)
+    logger.info(f"Generated quiz: {len(result.get('data', []))} questions")
+    return result
+
+
 def main():
     try:
         logger.info("In Python Program...")
@@ -38,34 +65,7 @@ def main():
             sys.stdout.flush()
             return
 
-        logger.info(f"Received quiz request: {data}")
-
-        # Validation
-        user_id = data.get("user_id")
-        topic = data.get("topic")
-        num_questions = data.get("num_questions")
-        difficulty = data.get("difficulty", "").lower()
-
-        if not (5 <= num_questions <= 20):
-            logger.error(f"Invalid num_questions: {num_questions}")
-            print(json.dumps({"ok": False, "data": ["Number of questions must be between 5 and 20"]}), file=sys.stdout)
-            sys.stdout.flush()
-            return
-
-        if difficulty not in ["easy", "medium", "hard"]:
-            logger.error(f"Invalid difficulty: {difficulty}")
-            print(json.dumps({"ok": False, "data": ["Difficulty must be easy, medium, or hard"]}), file=sys.stdout)
-            sys.stdout.flush()
-            return
-
-        # Fetch past questions to inform quiz generation
-        past_questions = get_past_questions(user_id, topic)
-        logger.info(f"Retrieved {len(past_questions)} past questions for user {user_id}, topic {topic}")
-
-        # Pass past questions to quiz generation (adjust generate_quiz as needed)
-        data["past_questions"] = past_questions
-        result = generate_quiz(data)
-        logger.info(f"Generated quiz: {len(result.get('data', []))} questions")
+        result = handle_request(data)
 
         # Log memory usage after processing
         mem_info = process.memory_info()
@@ -77,8 +77,57 @@ def main():
 
     except Exception as e:
         logger.error(f"Unexpected error: {str(e)}", exc_info=True)
-        print(json.dumps({"ok": False, "data": [f"Internal server error: {str(e)}"]}), file=sys.stdout)
+        print(json.dumps(error_response(e)), file=sys.stdout)
         sys.stdout.flush()
 
+
+def serve():
+    """
+    Long-lived worker mode used by the Go worker pool.
+
+    Each stdin line is {"id": "...", "request": {...}} and each stdout line is
+    the handle_request result with the same "id" added. Anything else that
+    would be printed is redirected to stderr so stdout stays a clean protocol
+    channel.
+    """
+    out = sys.stdout
+    sys.stdout = sys.stderr
+    process = psutil.Process()
+    logger.info("Quiz worker started")
+
+    for line in sys.stdin:
+        line = line.strip()
+        if not line:
+            continue
+
+        request_id = None
+        try:
+            message = json.loads(line)
+            request_id = message.get("id")
+            request = message.get("request") or {}
+            # The Go server renders the prompt, from the user's template or the default one
+            if message.get("prompt"):
+                request["prompt"] = message["prompt"]
+            result = handle_request(request)
+        except json.JSONDecodeError as e:
+            logger.error(f"Invalid JSON input: {str(e)}")
+            result = {"ok": False, "data": [f"Invalid JSON: {str(e)}"]}
+        except Exception as e:
+            logger.error(f"Unexpected error: {str(e)}", exc_info=True)
+            result = error_response(e)
+
+        result["id"] = request_id
+        out.write(json.dumps(result) + "\n")
+        out.flush()
+
+        mem_info = process.memory_info()
+        logger.info(f"Worker memory usage: {mem_info.rss / 1024 / 1024:.2f} MB")
+
+    logger.info("Quiz worker stdin closed, exiting")
+
+
 if __name__ == "__main__":
-    main()
\ No newline at end of file
+    if "--worker" in sys.argv[1:]:
+        serve()
+    else:
+        main()
@@ -49,29 +49,26 @@ class DatabaseConnection:
             self.connection.close()
             logger.info("Database connection closed")
 
-def get_past_questions(user_id: int, topic: str) -> list:
-    """Fetch past questions for a user and topic (synchronous version)"""
-    logger.info(f"Fetching past questions for user {user_id} and topic {topic}")
-    
-    try:
-        with DatabaseConnection() as conn:
-            with conn.cursor() as cur:
-                cur.execute(
-                    """
-                    SELECT q.question 
-                    FROM questions q
-                    JOIN quizzes z ON q.quiz_id = z.id
-                    WHERE z.user_id = %s AND LOWER(z.quiz_name) = LOWER(%s)
-                    """,
-                    (user_id, topic)
-                )
-                past_questions = [row[0] for row in cur.fetchall()]
-                logger.info(f"Found {len(past_questions)} past questions")
-                return past_questions
-                
-    except Exception as e:
-        logger.error(f"Database error for user {user_id}, topic {topic}: {str(e)}")
-        return []  
+# Connection reused across requests when running as a long-lived worker
+_connection = None
+
+def get_connection():
+    """Return the shared connection, reconnecting if it was closed or broken"""
+    global _connection
+    if _connection is None or _connection.closed:
+        _connection = psycopg2.connect(**DB_CONFIG)
+        _connection.autocommit = True
+        logger.info("Database connection established")
+    return _connection
+
+def reset_connection():
+    global _connection
+    if _connection is not None:
+        try:
+            _connection.close()
+        except Exception:
+            pass
+    _connection = None
 
 # Testing
 def test_db_connection():
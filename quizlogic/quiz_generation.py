@@ -1,13 +1,10 @@
 from langchain_google_genai import ChatGoogleGenerativeAI
-from langchain_core.prompts import ChatPromptTemplate
-from langchain.output_parsers import StructuredOutputParser, ResponseSchema
-import random
 import os
-from db import get_past_questions
 import json
 import re
 import logging
 import sys
+import unicodedata
 
 # Configure logging
 logging.basicConfig(
@@ -17,90 +14,74 @@ logging.basicConfig(
 )
 logger = logging.getLogger(__name__)
 
-# Define the expected JSON structure
-response_schemas = [
-    ResponseSchema(name="ok", description="Success indicator", type="boolean"),
-    ResponseSchema(
-        name="data",
-        description="Array of questions or error message",
-        type="array"
-    )
-]
-output_parser = StructuredOutputParser.from_response_schemas(response_schemas)
+# Created once per process so long-lived workers don't rebuild the client per request
+_llm = None
 
-def normalize_topic(raw_topic: str) -> str:
-    """Normalize a raw topic string into a clean version"""
-    topic = raw_topic.lower().strip()
-    phrases_to_remove = [
-        r"generate\s+me\s+a\s+quiz\s+on",
-        r"create\s+a\s+quiz\s+about",
-        r"quiz\s+on",
-        r"a\s+quiz\s+on"
-    ]
-    for phrase in phrases_to_remove:
-        topic = re.sub(phrase, "", topic)
-    return topic.strip().title() or "Unknown"
-
-def generate_quiz(request_data):
-    try:
-        logger.info(f"Starting generate_quiz with request: {request_data}")
-        
-        # Extract parameters from request
-        user_id = request_data.get('user_id')
-        topic = request_data.get('topic')
-        num_questions = request_data.get('num_questions')
-        difficulty = request_data.get('difficulty')
-        
-        # Normalize the topic
-        clean_name = normalize_topic(topic)
-        logger.info(f"Normalized topic: {clean_name}")
-
-        # Initialize Gemini (synchronous version)
-        llm = ChatGoogleGenerativeAI(
+def get_llm():
+    global _llm
+    if _llm is None:
+        options = {}
+        # Set by the Go server, e.g. to its record/replay proxy, which
+        # only speaks REST
+        endpoint = os.getenv("GEMINI_ENDPOINT")
+        if endpoint:
+            options = {"client_options": {"api_endpoint": endpoint}, "transport": "rest"}
+        _llm = ChatGoogleGenerativeAI(
             model="gemini-2.5-flash",
             google_api_key=os.getenv("API_KEY"),
             temperature=0.9,
+            **options,
         )
         logger.info("Gemini LLM initialized")
+    return _llm
 
-        # Fetch past questions (synchronous version)
-        past_questions = get_past_questions(user_id, clean_name)
-        past_questions_str = "; ".join(past_questions) if past_questions else "None"
-        logger.info(f"Past questions fetched: {past_questions}")
+def error_status(e: Exception):
+    """HTTP status of a failed model API call, if the exception carries one"""
+    for attr in ("status_code", "code"):
+        value = getattr(e, attr, None)
+        if isinstance(value, int) and 100 <= value < 600:
+            return value
+    match = re.match(r"\s*(\d{3})\b", str(e))
+    return int(match.group(1)) if match else 0
 
-        # Dynamic prompt with randomization and history
-        random_seed = random.randint(1, 1000)
-        prompt = ChatPromptTemplate.from_messages([
-            ("human", """
-            Generate a quiz with the following details:
-            - **Topic**: "{topic}"
-            - **Number of Questions**: {num}
-            - **Difficulty Level**: "{diff}"
-            
-            ### Instructions:
-            1. Create an array of quiz questions in this JSON format: {format}
-            2. Each question must include: serial_number (string), question, options (4 strings), correctAnswer, description.
-            3. Use a randomization approach with the seed provided: {seed}.
-            4. Do NOT repeat these past questions: {past}.
-            
-            ### Guidelines:
-            - Ensure content is accurate, clear, and matches the topic and difficulty.
-            - If you can't generate the requested number, provide as many as possible.
-            
-            ### Fallback:
-            - If the topic is inappropriate, return: [ {{ "ok": false }}, ["The requested topic is inappropriate or cannot be used."] ]
-            
-            Always return a two-element array: [ {{ "ok": bool }}, [questions or error] ].
-            """)
-        ])
-        formatted_prompt = prompt.format(
-            topic=clean_name,
-            num=num_questions,
-            diff=difficulty,
-            format=output_parser.get_format_instructions(),
-            seed=random_seed,
-            past=past_questions_str
-        )
+def error_response(e: Exception) -> dict:
+    """Report a failure (as opposed to a refusal) so the Go server can retry or fall back"""
+    return {"ok": False, "error": str(e), "status": error_status(e), "data": ["Quiz generation failed"]}
+
+def parse_model_output(content):
+    """Parse the model's answer: the [ { "ok": bool }, [...] ] array the Go server's
+    prompts ask for, or an {"ok", "data"} object"""
+    text = content.strip()
+    fenced = re.search(r"```(?:json)?\s*(.*?)```", text, re.DOTALL)
+    if fenced:
+        text = fenced.group(1).strip()
+    parsed = json.loads(text)
+    if isinstance(parsed, list) and len(parsed) == 2 and isinstance(parsed[0], dict):
+        return {"ok": bool(parsed[0].get("ok")), "data": parsed[1]}
+    if isinstance(parsed, dict) and "ok" in parsed:
+        return parsed
+    raise ValueError("Unexpected quiz format from the model")
+
+def token_usage(response):
+    """Token counts the model reported for a call, for the Go server's usage ledger."""
+    metadata = getattr(response, "usage_metadata", None) or {}
+    return {
+        "prompt_tokens": metadata.get("input_tokens", 0),
+        "response_tokens": metadata.get("output_tokens", 0),
+    }
+
+def generate_quiz(request_data):
+    try:
+        logger.info(f"Starting generate_quiz with request: {request_data}")
+        
+        # The prompt is rendered by the Go server, past questions, material
+        # and language included
+        formatted_prompt = request_data.get('prompt')
+        if not formatted_prompt:
+            raise ValueError("The request has no prompt")
+
+        # Initialize Gemini (synchronous version)
+        llm = get_llm()
         
         logger.info("Invoking LLM with prompt")
         # Synchronous invocation
@@ -108,8 +89,9 @@ def generate_quiz(request_data):
 
         # logger.info(f"LLM response raw content: {response.content}")
 
-        parsed_response = output_parser.parse(response.content)
+        parsed_response = parse_model_output(response.content)
         logger.info(f"Parsed response: {parsed_response}")
+        parsed_response["usage"] = token_usage(response)
 
         if parsed_response["ok"]:
             if isinstance(parsed_response["data"][0], dict):
@@ -127,4 +109,4 @@ def generate_quiz(request_data):
             
     except Exception as e:
         logger.error(f"Exception in generate_quiz 😓: {str(e)}")
-        return {"ok": False, "data": [f"Error generating quiz: {str(e)}"]}
\ No newline at end of file
+        return error_response(e)
\ No newline at end of file